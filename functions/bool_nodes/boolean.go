@@ -5,9 +5,7 @@
 // Package boolNodes defines the Boolean function collections available for the GEP algorithm.
 package boolNodes
 
-import (
-	"log"
-)
+import "github.com/gmlewis/gep/functions"
 
 // BoolNode is a boolean function used for the formation of GEP expressions.
 type BoolNode struct {
@@ -34,6 +32,6 @@ func (n BoolNode) BoolFunction(a, b, c, d bool) bool {
 
 // Float64Function is unused in this package and returns an error.
 func (n BoolNode) Float64Function(a, b, c, d float64) float64 {
-	log.Println("error calling Float64Function on BoolNode model.")
+	functions.Log.Println("error calling Float64Function on BoolNode model.")
 	return 0.0
 }
@@ -5,6 +5,11 @@
 // Package functions provides a map of available functions for the GEP algorithm.
 package functions
 
+import (
+	"fmt"
+	"sort"
+)
+
 // FuncMap is a map from the symbol name of a function to its defining FuncNode.
 type FuncMap map[string]FuncNode
 
@@ -19,3 +24,43 @@ type FuncNode interface {
 	// Float64Function represents a general floating-point function.
 	Float64Function(a, b, c, d float64) float64
 }
+
+// Describer is optionally implemented by a FuncNode to describe its function.
+type Describer interface {
+	// Description is a short, human-readable description of the function.
+	Description() string
+}
+
+// Names returns the sorted symbol names of the functions in fm.
+func Names(fm FuncMap) []string {
+	result := make([]string, 0, len(fm))
+	for k := range fm {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Arity returns the number of input terminals of the named function in fm,
+// and whether fm holds the function at all.
+func Arity(fm FuncMap, name string) (int, bool) {
+	f, ok := fm[name]
+	if !ok {
+		return 0, false
+	}
+	return f.Terminals(), true
+}
+
+// Description returns a short description of the named function in fm,
+// provided by the function itself if it implements Describer, or else giving
+// its symbol and arity. It returns "" if fm does not hold the function.
+func Description(fm FuncMap, name string) string {
+	f, ok := fm[name]
+	if !ok {
+		return ""
+	}
+	if d, ok := f.(Describer); ok {
+		return d.Description()
+	}
+	return fmt.Sprintf("%v: function of %v inputs", f.Symbol(), f.Terminals())
+}
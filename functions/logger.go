@@ -0,0 +1,41 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package functions
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// Logger is the interface through which the GEP packages report diagnostics.
+// A *log.Logger satisfies it. Fatalf must not return.
+type Logger interface {
+	Printf(format string, v ...interface{})
+	Println(v ...interface{})
+	Fatalf(format string, v ...interface{})
+}
+
+// Log receives the diagnostics of all the GEP packages. It defaults to the
+// standard logger of package log. Set it to redirect diagnostics, or to
+// log.New(ioutil.Discard, "", 0) to suppress them.
+var Log Logger = stdLogger{}
+
+// stdLogger writes to the standard logger, so that log.SetOutput and
+// log.SetFlags continue to apply.
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, v ...interface{}) {
+	log.Output(2, fmt.Sprintf(format, v...))
+}
+
+func (stdLogger) Println(v ...interface{}) {
+	log.Output(2, fmt.Sprintln(v...))
+}
+
+func (stdLogger) Fatalf(format string, v ...interface{}) {
+	log.Output(2, fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
@@ -6,7 +6,6 @@
 package mathNodes
 
 import (
-	"log"
 	"math"
 
 	"github.com/gmlewis/gep/functions"
@@ -32,7 +31,7 @@ func (n MathNode) Terminals() int {
 
 // BoolFunction is unused in this package and returns an error.
 func (n MathNode) BoolFunction(a, b, c, d bool) bool {
-	log.Println("error calling BoolFunction on MathNode model.")
+	functions.Log.Println("error calling BoolFunction on MathNode model.")
 	return false
 }
 
@@ -41,11 +40,17 @@ func (n MathNode) Float64Function(a, b, c, d float64) float64 {
 	return n.function(a, b, c, d)
 }
 
+// Symbols of the functions most commonly used to link the genes of a genome.
+const (
+	Sum     = "+" // additive linking
+	Product = "*" // multiplicative linking
+)
+
 // Math lists all the available floating-point functions for this package.
 var Math = functions.FuncMap{
-	"+":     MathNode{0, "+", 2, func(x0, x1, x2, x3 float64) float64 { return (x0 + x1) }},
+	Sum:     MathNode{0, Sum, 2, func(x0, x1, x2, x3 float64) float64 { return (x0 + x1) }},
 	"-":     MathNode{1, "-", 2, func(x0, x1, x2, x3 float64) float64 { return (x0 - x1) }},
-	"*":     MathNode{2, "*", 2, func(x0, x1, x2, x3 float64) float64 { return (x0 * x1) }},
+	Product: MathNode{2, Product, 2, func(x0, x1, x2, x3 float64) float64 { return (x0 * x1) }},
 	"/":     MathNode{3, "/", 2, func(x0, x1, x2, x3 float64) float64 { return (x0 / x1) }},
 	"Mod":   MathNode{4, "Mod", 2, func(x0, x1, x2, x3 float64) float64 { return gepMod(x0, x1) }},
 	"Pow":   MathNode{5, "Pow", 2, func(x0, x1, x2, x3 float64) float64 { return math.Pow(x0, x1) }},
@@ -346,6 +351,9 @@ func gepLog2(x, y float64) float64 {
 }
 
 func gepMod(x, y float64) float64 {
+	if y == 0.0 { // Protected against a zero divisor.
+		return 0.0
+	}
 	// The built-in function is incorrect for cases such as -1.0 and 0.2.
 	return ((x / y) - float64(int(x/y))) * y
 }
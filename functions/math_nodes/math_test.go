@@ -5,7 +5,12 @@
 package mathNodes
 
 import (
+	"math"
+	"reflect"
+	"sort"
 	"testing"
+
+	"github.com/gmlewis/gep/functions"
 )
 
 func TestPlus(t *testing.T) {
@@ -14,6 +19,37 @@ func TestPlus(t *testing.T) {
 	}
 }
 
+func TestModFloorCeil(t *testing.T) {
+	tests := []struct {
+		sym    string
+		x0, x1 float64
+		want   float64
+	}{
+		{"Mod", 7, 3, 1},
+		{"Mod", -7, 3, -1},
+		{"Mod", 7.5, 2, 1.5},
+		{"Mod", -1, 0.2, 0},
+		{"Mod", 7, 0, 0}, // protected zero divisor
+		{"Mod", 0, 0, 0},
+		{"Floor", 2.7, 0, 2},
+		{"Floor", -2.2, 0, -3},
+		{"Floor", 3, 0, 3},
+		{"Ceil", 2.2, 0, 3},
+		{"Ceil", -2.7, 0, -2},
+		{"Ceil", 3, 0, 3},
+	}
+	for _, test := range tests {
+		if g := Math[test.sym].Float64Function(test.x0, test.x1, 0, 0); math.Abs(g-test.want) > 1e-12 {
+			t.Errorf("Math[%v](%v,%v) = %v, want %v", test.sym, test.x0, test.x1, g, test.want)
+		}
+	}
+	for sym, want := range map[string]int{"Mod": 2, "Floor": 1, "Ceil": 1} {
+		if g := Math[sym].Terminals(); g != want {
+			t.Errorf("Math[%v].Terminals() = %v, want %v", sym, g, want)
+		}
+	}
+}
+
 var result float64
 
 func runBenchmark(b *testing.B, sym string) {
@@ -304,3 +340,35 @@ func BenchmarkLOE4L(b *testing.B)    { runBenchmark(b, "LOE4L") }
 func BenchmarkGOE4L(b *testing.B)    { runBenchmark(b, "GOE4L") }
 func BenchmarkET4L(b *testing.B)     { runBenchmark(b, "ET4L") }
 func BenchmarkNET4L(b *testing.B)    { runBenchmark(b, "NET4L") }
+
+func TestNames(t *testing.T) {
+	names := functions.Names(Math)
+	if len(names) != len(Math) || !sort.StringsAreSorted(names) {
+		t.Fatalf("Names(Math) = %v, want %v sorted names", names, len(Math))
+	}
+	arities := map[int]int{}
+	for _, name := range names {
+		n, ok := functions.Arity(Math, name)
+		if !ok || n != Math[name].Terminals() {
+			t.Errorf("Arity(Math, %q) = (%v, %v), want (%v, true)", name, n, ok, Math[name].Terminals())
+		}
+		arities[n]++
+	}
+	if want := map[int]int{1: 49, 2: 68, 3: 81, 4: 81}; !reflect.DeepEqual(arities, want) {
+		t.Errorf("Math arities = %v, want %v", arities, want)
+	}
+	for name, want := range map[string]int{"+": 2, "Sqrt": 1, "Add3": 3, "Avg4": 4} {
+		if got, _ := functions.Arity(Math, name); got != want {
+			t.Errorf("Arity(Math, %q) = %v, want %v", name, got, want)
+		}
+	}
+	if _, ok := functions.Arity(Math, "Bogus"); ok {
+		t.Errorf("Arity(Math, Bogus) = ok, want missing")
+	}
+	if got, want := functions.Description(Math, "Add3"), "Add3: function of 3 inputs"; got != want {
+		t.Errorf("Description(Math, Add3) = %q, want %q", got, want)
+	}
+	if got := functions.Description(Math, "Bogus"); got != "" {
+		t.Errorf("Description(Math, Bogus) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package functions
+
+import "math/rand"
+
+// RNG is the source of randomness for the stochastic operations of the GEP
+// packages (such as random generation, mutation, and selection). A *rand.Rand
+// satisfies it. Driving operations with RNGs of the same seed reproduces
+// their results exactly.
+type RNG interface {
+	// Intn returns a non-negative pseudo-random int in [0,n). It panics if n <= 0.
+	Intn(n int) int
+	// Float64 returns a pseudo-random float64 in [0.0,1.0).
+	Float64() float64
+	// NormFloat64 returns a normally distributed float64 with mean 0 and
+	// standard deviation 1.
+	NormFloat64() float64
+}
+
+// DefaultRNG is the RNG used by the operations that are not given one. It
+// defaults to the global functions of package math/rand, which are safe for
+// concurrent use (unlike most other RNGs, such as those of NewRNG), so
+// rand.Seed continues to apply.
+var DefaultRNG RNG = globalRNG{}
+
+// NewRNG returns a new RNG seeded with seed. It is not safe for concurrent
+// use, so each goroutine should be given its own RNG, which also avoids the
+// contention of the global functions of package math/rand.
+func NewRNG(seed int64) RNG {
+	return rand.New(rand.NewSource(seed))
+}
+
+// Perm returns, as a slice of n ints, a pseudo-random permutation of the
+// integers [0,n) drawn from r.
+func Perm(r RNG, n int) []int {
+	m := make([]int, n)
+	for i := 0; i < n; i++ {
+		j := r.Intn(i + 1)
+		m[i] = m[j]
+		m[j] = i
+	}
+	return m
+}
+
+// globalRNG uses the global functions of package math/rand.
+type globalRNG struct{}
+
+func (globalRNG) Intn(n int) int       { return rand.Intn(n) }
+func (globalRNG) Float64() float64     { return rand.Float64() }
+func (globalRNG) NormFloat64() float64 { return rand.NormFloat64() }
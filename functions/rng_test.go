@@ -0,0 +1,21 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package functions
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestPerm(t *testing.T) {
+	for n := 0; n < 20; n++ {
+		// Perm matches rand.Perm given the same source of randomness.
+		got, want := Perm(NewRNG(int64(n)), n), rand.New(rand.NewSource(int64(n))).Perm(n)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Perm(%v) = %v, want %v", n, got, want)
+		}
+	}
+}
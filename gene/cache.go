@@ -0,0 +1,83 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package gene
+
+import (
+	"sync"
+
+	"github.com/gmlewis/gep/functions"
+)
+
+// cache holds the lazily built functions of a gene. Each is built at most once,
+// guarded by a sync.Once, so that a gene may be evaluated concurrently. The
+// cache itself is created on first use (see getCache), so that a gene made
+// without a constructor is cached too, and the operators that alter a gene
+// replace it (see invalidate).
+type cache struct {
+	mathOnce sync.Once
+	mf       func([]float64) float64 // math generated function
+	boolOnce sync.Once
+	bf       func([]bool) bool // boolean generated function
+	// symbolsOnce guards the SymbolMap of the gene, which is recorded by the
+	// first function built (or by SymbolCount).
+	symbolsOnce sync.Once
+}
+
+// invalidate discards the cached functions and SymbolMap of the gene, which
+// must be done whenever its symbols, head, or functions change.
+func (g *Gene) invalidate() {
+	g.cache.Store(&cache{})
+	g.SymbolMap = nil
+}
+
+// getCache returns the cache of the gene, creating it on first use. Concurrent
+// first uses agree on the same cache.
+func (g *Gene) getCache() *cache {
+	if c, ok := g.cache.Load().(*cache); ok {
+		return c
+	}
+	g.cache.CompareAndSwap(nil, &cache{})
+	return g.cache.Load().(*cache)
+}
+
+// Compile decodes the expression of the gene into its floating-point function
+// now, rather than lazily upon the first EvalMath, so that every evaluation
+// walks the cached function without decoding the symbols. The function is
+// kept until an operator alters the gene.
+func (g *Gene) Compile() {
+	g.mathFunc()
+}
+
+// mathFunc returns the floating-point function of the gene, building it on
+// first use.
+func (g *Gene) mathFunc() func([]float64) float64 {
+	c := g.getCache()
+	c.mathOnce.Do(func() {
+		var symbols map[string]int
+		c.mf, symbols = g.buildMathTree(0, g.getMathArgOrder())
+		c.symbolsOnce.Do(func() { g.SymbolMap = symbols })
+	})
+	return c.mf
+}
+
+// boolFunc is like mathFunc for the boolean function of the gene, which is
+// built with nodes on first use.
+func (g *Gene) boolFunc(nodes functions.FuncMap) func([]bool) bool {
+	c := g.getCache()
+	c.boolOnce.Do(func() {
+		var symbols map[string]int
+		c.bf, symbols = g.buildBoolTree(0, g.getBoolArgOrder(nodes), nodes)
+		c.symbolsOnce.Do(func() { g.SymbolMap = symbols })
+	})
+	return c.bf
+}
+
+// symbols returns the SymbolMap of the gene, building it (from the math
+// expression) on first use.
+func (g *Gene) symbols() map[string]int {
+	c := g.getCache()
+	c.symbolsOnce.Do(func() { _, g.SymbolMap = g.buildMathTree(0, g.getMathArgOrder()) })
+	return g.SymbolMap
+}
@@ -7,13 +7,12 @@ package gene
 
 import (
 	"fmt"
-	"log"
-	"math/rand"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/gmlewis/gep/functions"
-	mn "github.com/gmlewis/gep/functions/math_nodes"
 )
 
 // FuncWeight contains the symbol name and its weight to be used during
@@ -32,9 +31,8 @@ type Gene struct {
 	// Constants is the slice of floats available for use by this gene.
 	Constants []float64
 
-	SymbolMap   map[string]int          // do not use directly.  Use SymbolCount() instead.
-	bf          func([]bool) bool       // boolean generated function
-	mf          func([]float64) float64 // math generated function
+	SymbolMap   map[string]int // do not use directly.  Use SymbolCount() instead.
+	cache       atomic.Value   // of *cache: the lazily built functions of the gene (see getCache)
 	headSize    int
 	choiceSlice []string
 	// numTerminals is the number of inputs to the genetic program.
@@ -44,6 +42,11 @@ type Gene struct {
 	// are entirely inputs ("d*") and constants ("c*") whereas all
 	// choices following that are strictly function symbols.
 	numTerminals int
+	// opts are the options used to generate the gene, if any.
+	opts *Options
+	// funcs, if non-nil, is the map of floating-point functions to which the
+	// gene is bound (see Rebind). It defaults to mn.Math.
+	funcs functions.FuncMap
 }
 
 // New creates a new gene based on the Karva string representation.
@@ -54,7 +57,7 @@ func New(x string) *Gene {
 		if sym[0:1] == "d" {
 			index, err := strconv.Atoi(sym[1:])
 			if err != nil {
-				log.Fatalf("unable to parse variable index %q: %v", sym, err)
+				functions.Log.Fatalf("unable to parse variable index %q: %v", sym, err)
 			}
 			if index >= numTerminals {
 				numTerminals = index + 1
@@ -62,7 +65,7 @@ func New(x string) *Gene {
 		} else if sym[0:1] == "c" {
 			index, err := strconv.Atoi(sym[1:])
 			if err != nil {
-				log.Fatalf("unable to parse constant index %q: %v", sym, err)
+				functions.Log.Fatalf("unable to parse constant index %q: %v", sym, err)
 			}
 			if index >= numConstants {
 				numConstants = index + 1
@@ -78,11 +81,16 @@ func New(x string) *Gene {
 
 // RandomNew generates a new, random gene for further manipulation by the GEP
 // algorithm. The headSize, tailSize, numTerminals, and numConstants determine the respective
-// properties of the gene, and functions provide the available functions and
+// properties of the gene, and funcs provides the available functions and
 // their respective weights to be used in the creation of the gene.
-func RandomNew(headSize, tailSize, numTerminals, numConstants int, functions []FuncWeight) *Gene {
+func RandomNew(headSize, tailSize, numTerminals, numConstants int, funcs []FuncWeight) *Gene {
+	return randomNew(functions.DefaultRNG, headSize, tailSize, numTerminals, numConstants, funcs)
+}
+
+// randomNew is like RandomNew, but draws its randomness from rng.
+func randomNew(rng functions.RNG, headSize, tailSize, numTerminals, numConstants int, funcs []FuncWeight) *Gene {
 	totalWeight := numTerminals
-	for _, f := range functions {
+	for _, f := range funcs {
 		totalWeight += f.Weight
 	}
 	choiceSlice := make([]string, 0, totalWeight)
@@ -92,14 +100,14 @@ func RandomNew(headSize, tailSize, numTerminals, numConstants int, functions []F
 	var constants []float64
 	for i := 0; i < numConstants; i++ {
 		choiceSlice = append(choiceSlice, fmt.Sprintf("c%v", i))
-		constants = append(constants, rand.Float64())
+		constants = append(constants, rng.Float64())
 	}
-	for _, f := range functions {
+	for _, f := range funcs {
 		for i := 0; i < f.Weight; i++ {
 			choiceSlice = append(choiceSlice, f.Symbol)
 		}
 	}
-	choices := rand.Perm(totalWeight)
+	choices := functions.Perm(rng, totalWeight)
 	r := &Gene{
 		Symbols:      make([]string, 0, headSize+tailSize),
 		Constants:    constants,
@@ -118,11 +126,149 @@ func RandomNew(headSize, tailSize, numTerminals, numConstants int, functions []F
 	return r
 }
 
+// RandomLike generates a new, random gene with the same head size, tail size,
+// number of terminals and constants, weighted function choices, and function
+// binding (see Rebind) as g.
+// It returns nil if g was not created by RandomNew (or a copy of such a gene),
+// since only then is its structure known.
+func (g *Gene) RandomLike() *Gene {
+	return g.RandomLikeWith(functions.DefaultRNG)
+}
+
+// RandomLikeWith is like RandomLike, but draws its randomness from rng.
+func (g *Gene) RandomLikeWith(rng functions.RNG) *Gene {
+	if g == nil || g.headSize == 0 || len(g.choiceSlice) < g.numTerminals {
+		functions.Log.Printf("gene.RandomLike error: gene structure unknown")
+		return nil
+	}
+	var funcs []FuncWeight
+	index := map[string]int{}
+	for _, sym := range g.choiceSlice[g.numTerminals:] {
+		if i, ok := index[sym]; ok {
+			funcs[i].Weight++
+			continue
+		}
+		index[sym] = len(funcs)
+		funcs = append(funcs, FuncWeight{Symbol: sym, Weight: 1})
+	}
+	numConstants := len(g.Constants)
+	r := RandomNewWith(rng, g.headSize, len(g.Symbols)-g.headSize, g.numTerminals-numConstants, numConstants, funcs, g.opts)
+	r.funcs = g.funcs
+	return r
+}
+
 // String returns the Karva representation of the gene.
 func (g Gene) String() string {
 	return strings.Join(g.Symbols, ".")
 }
 
+// orfLength returns the length of the open reading frame (the expressed,
+// coding region) of the gene given the arities found in nodes.
+// If the symbols run out before the expression is complete, the returned
+// length exceeds len(g.Symbols).
+func (g *Gene) orfLength(nodes functions.FuncMap) int {
+	need := 1
+	for i := 0; i < need && i < len(g.Symbols); i++ {
+		if s, ok := nodes[g.Symbols[i]]; ok {
+			need += s.Terminals()
+		}
+	}
+	return need
+}
+
+// ParseTerminal parses sym as an input (d0, d1, ...) or a constant (c0, c1, ...),
+// returning its kind ("d" or "c") and its index. An error is returned if sym
+// is neither; note that function symbols (such as "d2x") are not consulted, so
+// the caller should look sym up in its function map first.
+func ParseTerminal(sym string) (kind string, index int, err error) {
+	if len(sym) < 2 || (sym[0:1] != "d" && sym[0:1] != "c") {
+		return "", 0, fmt.Errorf("unknown symbol %q", sym)
+	}
+	index, err = strconv.Atoi(sym[1:])
+	if err != nil || index < 0 {
+		return "", 0, fmt.Errorf("unable to parse terminal index %q", sym)
+	}
+	return sym[0:1], index, nil
+}
+
+// checkTerminal verifies that sym names a valid input or constant for the gene.
+func (g *Gene) checkTerminal(sym string) error {
+	kind, index, err := ParseTerminal(sym)
+	if err != nil {
+		return err
+	}
+	if kind == "c" {
+		if index >= len(g.Constants) {
+			return fmt.Errorf("constant %q exceeds length of constant slice (%v)", sym, len(g.Constants))
+		}
+		return nil
+	}
+	if n := g.numTerminals - len(g.Constants); index >= n {
+		return fmt.Errorf("input %q exceeds number of inputs (%v)", sym, n)
+	}
+	return nil
+}
+
+// Validate checks that the gene is well formed: every symbol must be a function
+// in nodes, an input (d0, d1, ...), or a constant (c0, c1, ...), the tail must
+// contain only terminals (when the head size is known, as it is for genes created
+// by RandomNew), and the open reading frame must fit within the gene.
+func (g *Gene) Validate(nodes functions.FuncMap) error {
+	if g == nil || len(g.Symbols) == 0 {
+		return fmt.Errorf("gene has no symbols")
+	}
+	for i, sym := range g.Symbols {
+		if _, ok := nodes[sym]; ok {
+			if g.headSize > 0 && i >= g.headSize {
+				return fmt.Errorf("function %q found in tail at position %v (head size %v)", sym, i, g.headSize)
+			}
+			continue
+		}
+		if err := g.checkTerminal(sym); err != nil {
+			return err
+		}
+	}
+	if n := g.orfLength(nodes); n > len(g.Symbols) {
+		return fmt.Errorf("expression needs %v symbols but gene only has %v", n, len(g.Symbols))
+	}
+	return nil
+}
+
+// ValidateKarva checks, without constructing a gene, that the Karva string
+// expr (such as "+.*.d0.d1.d2.d0.d1") is a well formed gene for the functions
+// fm with the given head size: every symbol must be a function in fm, an
+// input (d0, d1, ...), or a constant (c0, c1, ...), the tail must contain only
+// terminals, and the tail must have the length headSize*(n-1)+1 required
+// for the maximum arity n of the functions in fm (so that the open reading
+// frame always fits within the gene).
+func ValidateKarva(expr string, headSize int, fm functions.FuncMap) error {
+	if headSize < 1 {
+		return fmt.Errorf("head size %v must be positive", headSize)
+	}
+	maxArity := 1
+	for _, f := range fm {
+		if f.Terminals() > maxArity {
+			maxArity = f.Terminals()
+		}
+	}
+	syms := strings.Split(expr, ".")
+	if want := headSize + headSize*(maxArity-1) + 1; len(syms) != want {
+		return fmt.Errorf("expression has %v symbols, want %v (head size %v plus tail size %v)", len(syms), want, headSize, want-headSize)
+	}
+	for i, sym := range syms {
+		if _, ok := fm[sym]; ok {
+			if i >= headSize {
+				return fmt.Errorf("function %q found in tail at position %v (head size %v)", sym, i, headSize)
+			}
+			continue
+		}
+		if _, _, err := ParseTerminal(sym); err != nil {
+			return fmt.Errorf("%v at position %v", err, i)
+		}
+	}
+	return nil
+}
+
 func (g *Gene) getBoolArgOrder(nodes functions.FuncMap) [][]int {
 	argOrder := make([][]int, len(g.Symbols))
 	argCount := 0
@@ -146,11 +292,7 @@ func (g *Gene) getBoolArgOrder(nodes functions.FuncMap) [][]int {
 // "in" represents the boolean inputs available to the gene.
 // "nodes" is the map of available boolean functions to the gene.
 func (g *Gene) EvalBool(in []bool, nodes functions.FuncMap) bool {
-	if g.bf == nil {
-		argOrder := g.getBoolArgOrder(nodes)
-		g.bf, g.SymbolMap = g.buildBoolTree(0, argOrder, nodes)
-	}
-	return g.bf(in)
+	return g.boolFunc(nodes)(in)
 }
 
 func merge(dst *map[string]int, src map[string]int) {
@@ -163,7 +305,7 @@ func (g *Gene) buildBoolTree(symbolIndex int, argOrder [][]int, nodes functions.
 	count := make(map[string]int)
 	// log.Infof("buildBoolTree(%v, %#v, ...)", symbolIndex, argOrder)
 	if symbolIndex >= len(g.Symbols) {
-		log.Printf("bad symbolIndex %v for symbols: %v", symbolIndex, g.Symbols)
+		functions.Log.Printf("bad symbolIndex %v for symbols: %v", symbolIndex, g.Symbols)
 		return func(a []bool) bool { return false }, count
 	}
 	sym := g.Symbols[symbolIndex]
@@ -218,11 +360,11 @@ func (g *Gene) buildBoolTree(symbolIndex int, argOrder [][]int, nodes functions.
 	} else { // No named symbol found - look for d0, d1, ...
 		if sym[0:1] == "d" {
 			if index, err := strconv.Atoi(sym[1:]); err != nil {
-				log.Printf("unable to parse variable index: sym=%q", sym)
+				functions.Log.Printf("unable to parse variable index: sym=%q", sym)
 			} else {
 				return func(in []bool) bool {
 					if index >= len(in) {
-						log.Printf("error evaluating gene symbol %q: index %v >= d length (%v)", sym, index, len(in))
+						functions.Log.Printf("error evaluating gene symbol %q: index %v >= d length (%v)", sym, index, len(in))
 						return false
 					}
 					return in[index]
@@ -231,7 +373,7 @@ func (g *Gene) buildBoolTree(symbolIndex int, argOrder [][]int, nodes functions.
 		}
 		// Note that constants c0, c1, ... don't make sense for bool expressions
 	}
-	log.Printf("unable to return function: unknown gene symbol %q", sym)
+	functions.Log.Printf("unable to return function: unknown gene symbol %q", sym)
 	return func(in []bool) bool { return false }, count
 }
 
@@ -240,7 +382,7 @@ func (g *Gene) getMathArgOrder() [][]int {
 	argCount := 0
 	for i := 0; i < len(g.Symbols); i++ {
 		sym := g.Symbols[i]
-		if s, ok := mn.Math[sym]; ok {
+		if s, ok := g.mathNodes()[sym]; ok {
 			if s.Terminals() > 0 {
 				args := make([]int, s.Terminals())
 				for j := 0; j < s.Terminals(); j++ {
@@ -264,33 +406,147 @@ func (g *Gene) getMathArgOrder() [][]int {
 // A workaround for using it with other types is to evaluate the
 // Gene, and then g.symbolCount will already be populated.
 func (g *Gene) SymbolCount(sym string) int {
-	if g.SymbolMap == nil {
-		argOrder := g.getMathArgOrder()
-		g.mf, g.SymbolMap = g.buildMathTree(0, argOrder)
+	return g.symbols()[sym]
+}
+
+// CodingSymbolCount returns the number of times the symbol appears
+// within the open reading frame (the expressed, coding region) of the Gene.
+// Symbols in the non-coding region of the gene are not counted.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Gene) CodingSymbolCount(sym string) int {
+	count := 0
+	for _, v := range g.codingSymbols() {
+		if v == sym {
+			count++
+		}
+	}
+	return count
+}
+
+// CodingSymbolMap returns the number of times each symbol appears within
+// the open reading frame (the expressed, coding region) of the Gene.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Gene) CodingSymbolMap() map[string]int {
+	r := map[string]int{}
+	for _, v := range g.codingSymbols() {
+		r[v]++
+	}
+	return r
+}
+
+// CodingLength returns the length of the open reading frame (the expressed,
+// coding region) of the Gene.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Gene) CodingLength() int {
+	return len(g.codingSymbols())
+}
+
+// UsedInputs returns the sorted, distinct indices of the inputs (d0, d1, ...)
+// referenced within the open reading frame of the Gene.
+// Inputs appearing only in the non-coding region are not included.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Gene) UsedInputs() []int {
+	seen := map[int]bool{}
+	var result []int
+	for _, sym := range g.codingSymbols() {
+		if len(sym) < 2 || sym[0:1] != "d" {
+			continue
+		}
+		if _, ok := g.mathNodes()[sym]; ok {
+			continue
+		}
+		index, err := strconv.Atoi(sym[1:])
+		if err != nil || seen[index] {
+			continue
+		}
+		seen[index] = true
+		result = append(result, index)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// codingSymbols returns the symbols within the open reading frame of the gene.
+func (g *Gene) codingSymbols() []string {
+	n := g.orfLength(g.mathNodes())
+	if n > len(g.Symbols) {
+		n = len(g.Symbols)
 	}
-	return g.SymbolMap[sym]
+	return g.Symbols[:n]
 }
 
 // EvalMath evaluates the gene as a floating-point expression and returns the result.
 // in represents the float64 inputs available to the gene.
 func (g *Gene) EvalMath(in []float64) float64 {
-	if g.mf == nil {
-		argOrder := g.getMathArgOrder()
-		g.mf, g.SymbolMap = g.buildMathTree(0, argOrder)
+	return g.mathFunc()(in)
+}
+
+// EvalMathSafe is like EvalMath, but never logs: it evaluates the gene
+// directly from its symbols (without building or caching any functions) and
+// returns an error if the gene cannot be evaluated with the inputs in.
+// For genes of up to 32 symbols, it does not allocate.
+func (g *Gene) EvalMathSafe(in []float64) (float64, error) {
+	var valueBuf [32]float64
+	var firstBuf [32]int
+	values, first := valueBuf[:0], firstBuf[:0]
+	nodes := g.mathNodes()
+	// First pass: find the open reading frame and the first argument of each symbol.
+	next := 1
+	for i := 0; i < next; i++ {
+		if i >= len(g.Symbols) {
+			return 0, fmt.Errorf("gene %q is not a complete expression", g)
+		}
+		first = append(first, next)
+		values = append(values, 0)
+		if f, ok := nodes[g.Symbols[i]]; ok {
+			next += f.Terminals()
+		}
 	}
-	return g.mf(in)
+	// Second pass: evaluate the symbols in reverse order, so that the
+	// arguments of each function are evaluated before it.
+	for i := len(values) - 1; i >= 0; i-- {
+		sym := g.Symbols[i]
+		if f, ok := nodes[sym]; ok {
+			var x [4]float64
+			copy(x[:], values[first[i]:first[i]+f.Terminals()])
+			values[i] = f.Float64Function(x[0], x[1], x[2], x[3])
+			continue
+		}
+		if len(sym) < 2 {
+			return 0, fmt.Errorf("unknown gene symbol %q", sym)
+		}
+		index, err := strconv.Atoi(sym[1:])
+		if err != nil || index < 0 {
+			return 0, fmt.Errorf("unknown gene symbol %q", sym)
+		}
+		switch sym[0:1] {
+		case "d":
+			if index >= len(in) {
+				return 0, fmt.Errorf("input %q exceeds number of inputs (%v)", sym, len(in))
+			}
+			values[i] = in[index]
+		case "c":
+			if index >= len(g.Constants) {
+				return 0, fmt.Errorf("constant %q exceeds length of constant slice (%v)", sym, len(g.Constants))
+			}
+			values[i] = g.Constants[index]
+		default:
+			return 0, fmt.Errorf("unknown gene symbol %q", sym)
+		}
+	}
+	return values[0], nil
 }
 
 func (g *Gene) buildMathTree(symbolIndex int, argOrder [][]int) (func([]float64) float64, map[string]int) {
 	count := make(map[string]int)
 	// log.Infof("buildMathTree(%v, %#v, ...)", symbolIndex, argOrder)
 	if symbolIndex > len(g.Symbols) {
-		log.Printf("bad symbolIndex %v for symbols: %v", symbolIndex, g.Symbols)
+		functions.Log.Printf("bad symbolIndex %v for symbols: %v", symbolIndex, g.Symbols)
 		return func(a []float64) float64 { return 0.0 }, count
 	}
 	sym := g.Symbols[symbolIndex]
 	count[sym]++
-	if s, ok := mn.Math[sym]; ok {
+	if s, ok := g.mathNodes()[sym]; ok {
 		switch s.Terminals() {
 		case 0:
 			return func(in []float64) float64 {
@@ -340,11 +596,11 @@ func (g *Gene) buildMathTree(symbolIndex int, argOrder [][]int) (func([]float64)
 	} else { // No named symbol found - look for d0, d1, ...
 		if sym[0:1] == "d" {
 			if index, err := strconv.Atoi(sym[1:]); err != nil {
-				log.Printf("unable to parse variable index: sym=%q", sym)
+				functions.Log.Printf("unable to parse variable index: sym=%q", sym)
 			} else {
 				return func(in []float64) float64 {
 					if index >= len(in) {
-						log.Printf("error evaluating gene %q: index %v >= d length (%v)", sym, index, len(in))
+						functions.Log.Printf("error evaluating gene %q: index %v >= d length (%v)", sym, index, len(in))
 						return 0.0
 					}
 					return in[index]
@@ -352,11 +608,11 @@ func (g *Gene) buildMathTree(symbolIndex int, argOrder [][]int) (func([]float64)
 			}
 		} else if sym[0:1] == "c" {
 			if index, err := strconv.Atoi(sym[1:]); err != nil {
-				log.Printf("unable to parse constant index: sym=%v", sym)
+				functions.Log.Printf("unable to parse constant index: sym=%v", sym)
 			} else {
 				return func(in []float64) float64 {
 					if index >= len(g.Constants) {
-						log.Printf("error evaluating gene %q: index %v >= c length (%v)", sym, index, len(g.Constants))
+						functions.Log.Printf("error evaluating gene %q: index %v >= c length (%v)", sym, index, len(g.Constants))
 						return 0.0
 					}
 					return g.Constants[index]
@@ -364,56 +620,126 @@ func (g *Gene) buildMathTree(symbolIndex int, argOrder [][]int) (func([]float64)
 			}
 		}
 	}
-	log.Printf("unable to return function: unknown gene symbol %q", sym)
+	functions.Log.Printf("unable to return function: unknown gene symbol %q", sym)
 	return func(in []float64) float64 { return 0.0 }, count
 }
 
 // Mutate mutates a gene by performing a single random symbol exchange within the gene.
+// If the gene was generated with an Options.Constraint or Options.Types, mutations
+// violating them are resampled; if no permitted mutation is found, the gene is unchanged.
+// A gene whose structure is unknown (such as one created by New) is left unchanged.
 func (g *Gene) Mutate() {
-	position := rand.Intn(len(g.Symbols))
+	g.MutateWith(functions.DefaultRNG)
+}
+
+// MutateWith is like Mutate, but draws its randomness from rng.
+func (g *Gene) MutateWith(rng functions.RNG) {
+	if !g.opts.constrained() {
+		g.mutate(rng)
+		return
+	}
+	saved := make([]string, len(g.Symbols))
+	copy(saved, g.Symbols)
+	for n := 0; n < maxResamples; n++ {
+		g.mutate(rng)
+		if g.satisfiesConstraints() {
+			return
+		}
+		copy(g.Symbols, saved)
+	}
+}
+
+func (g *Gene) mutate(rng functions.RNG) {
+	if g.headSize == 0 || len(g.choiceSlice) < g.numTerminals {
+		functions.Log.Printf("gene.Mutate error: gene structure unknown")
+		return
+	}
+	position := rng.Intn(len(g.Symbols))
 	if g.numTerminals < 2 {
 		position %= g.headSize // Force choice to be within the head
 	}
 	if position < g.headSize {
 		if len(g.choiceSlice) < 2 {
-			log.Printf("error: must have choice of more than one function")
+			functions.Log.Printf("error: must have choice of more than one function")
 			return
 		}
 		symbol := g.Symbols[position]
+		if g.opts != nil && g.opts.Weights != nil {
+			if symbol = g.choose(rng, g.choiceSlice, symbol); symbol == g.Symbols[position] {
+				return // No other symbol has a positive weight.
+			}
+		}
 		for symbol == g.Symbols[position] { // Force new symbol to be different from old one
-			n := rand.Intn(len(g.choiceSlice))
+			n := rng.Intn(len(g.choiceSlice))
 			symbol = g.choiceSlice[n]
 		}
 		// fmt.Printf("\nChanging symbol #%v from %q to %q\n", position, g.Symbols[position], symbol)
 		g.Symbols[position] = symbol
 	} else { // Must choose strictly from terminals
 		terminal := g.Symbols[position]
+		if g.opts != nil && g.opts.Weights != nil {
+			if terminal = g.choose(rng, g.choiceSlice[:g.numTerminals], terminal); terminal == g.Symbols[position] {
+				return // No other terminal has a positive weight.
+			}
+		}
 		for terminal == g.Symbols[position] { // Force new terminal to be different from old one
-			n := rand.Intn(g.numTerminals)
+			n := rng.Intn(g.numTerminals)
 			terminal = g.choiceSlice[n]
 		}
 		// fmt.Printf("\nChanging terminal #%v from %q to %q\n", position, g.Symbols[position], terminal)
 		g.Symbols[position] = terminal
 	}
-	// Invalidate the cached function
-	g.bf = nil
-	g.mf = nil
+	g.invalidate()
+}
+
+// Recombine performs a one-point recombination between genes g1 and g2
+// by exchanging every symbol from position through the end of the genes.
+// Both genes are expected to have the same length.
+// The constants travel with the symbols that refer to them: each constant
+// that neither gene refers to ahead of position is exchanged as well, so the
+// exchanged symbols keep their values (and recombination at position 0 swaps
+// the genes entirely). A constant that is also referred to ahead of position
+// stays put, since the unexchanged symbols depend upon it, so an exchanged
+// reference to it takes the value of the constant of its new gene.
+func Recombine(g1, g2 *Gene, position int) {
+	if g1 == nil || g2 == nil {
+		functions.Log.Printf("gene.Recombine error: g1 and g2 must be non-nil")
+		return
+	}
+	kept := map[int]bool{} // the constants referred to ahead of position
+	for _, g := range []*Gene{g1, g2} {
+		for i := 0; i < position && i < len(g.Symbols); i++ {
+			if kind, index, err := ParseTerminal(g.Symbols[i]); err == nil && kind == "c" {
+				kept[index] = true
+			}
+		}
+	}
+	for i := position; i < len(g1.Symbols) && i < len(g2.Symbols); i++ {
+		g1.Symbols[i], g2.Symbols[i] = g2.Symbols[i], g1.Symbols[i]
+	}
+	for i := 0; i < len(g1.Constants) && i < len(g2.Constants); i++ {
+		if !kept[i] {
+			g1.Constants[i], g2.Constants[i] = g2.Constants[i], g1.Constants[i]
+		}
+	}
+	g1.invalidate()
+	g2.invalidate()
 }
 
 // Dup duplicates the gene into the provided destination gene.
 func (g *Gene) Dup() *Gene {
 	if g == nil {
-		log.Printf("gene.Dup error: src and dst must be non-nil")
+		functions.Log.Printf("gene.Dup error: src and dst must be non-nil")
 		return nil
 	}
 	r := &Gene{
 		Symbols:      make([]string, len(g.Symbols)),
 		Constants:    make([]float64, len(g.Constants)),
-		bf:           g.bf,
-		mf:           g.mf,
 		headSize:     g.headSize,
 		choiceSlice:  make([]string, len(g.choiceSlice)),
 		numTerminals: g.numTerminals,
+		opts:         g.opts,
+		funcs:        g.funcs,
 	}
 	for i := range g.Symbols {
 		r.Symbols[i] = g.Symbols[i]
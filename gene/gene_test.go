@@ -7,9 +7,12 @@ package gene
 import (
 	"math"
 	"reflect"
+	"sync"
 	"testing"
 
+	"github.com/gmlewis/gep/functions"
 	bn "github.com/gmlewis/gep/functions/bool_nodes"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
 )
 
 var nandTests = []struct {
@@ -134,6 +137,24 @@ func TestMath(t *testing.T) {
 	}
 }
 
+func TestCodingSymbolCount(t *testing.T) {
+	for _, test := range mathTests {
+		g := New(test.gene)
+		for sym, want := range test.count {
+			if got := g.CodingSymbolCount(sym); got != want {
+				t.Errorf("Gene %q CodingSymbolCount(%q) = %v, want %v", g, sym, got, want)
+			}
+		}
+	}
+	g := New("+.d0.d1.*.d2.d3.d4")
+	if got := g.CodingSymbolCount("*"); got != 0 {
+		t.Errorf("Gene %q CodingSymbolCount(*) = %v, want 0", g, got)
+	}
+	if got := g.CodingSymbolCount("d2"); got != 0 {
+		t.Errorf("Gene %q CodingSymbolCount(d2) = %v, want 0", g, got)
+	}
+}
+
 func TestGetBoolArgOrder(t *testing.T) {
 	nand := New("Or.And.Not.Not.Or.And.And.d0.d1.d1.d1.d0.d1.d1.d0")
 	got := nand.getBoolArgOrder(bn.BoolAllGates)
@@ -164,6 +185,15 @@ func TestDup(t *testing.T) {
 	}
 	validateMath(t, g1, test.in, test.out) // Force evaluation
 	validateMath(t, nand, test.in, test.out)
+
+	// The constants of the duplicate are its own.
+	c := New("*.c0.d0")
+	c.Constants[0] = 2
+	validateMath(t, c, []float64{3}, 6) // Force evaluation
+	dup := c.Dup()
+	dup.Constants[0] = 5
+	validateMath(t, dup, []float64{3}, 15)
+	validateMath(t, c, []float64{3}, 6)
 }
 
 func TestMutate(t *testing.T) {
@@ -184,6 +214,209 @@ func TestMutate(t *testing.T) {
 	}
 }
 
+func TestRecombine(t *testing.T) {
+	g1 := New("Or.And.Not.Not.Or.And.And.d0.d1.d1.d1.d0.d1.d1.d0")
+	g2 := New("Or.And.Not.d0.Not.And.Or.d0.d0.d1.d1.d0.d1.d1.d1")
+	validateNand(t, g1) // Force evaluation
+	Recombine(g1, g2, 3)
+	if got, want := g1.String(), "Or.And.Not.d0.Not.And.Or.d0.d0.d1.d1.d0.d1.d1.d1"; got != want {
+		t.Errorf("Recombine g1 = %q, want %q", got, want)
+	}
+	if got, want := g2.String(), "Or.And.Not.Not.Or.And.And.d0.d1.d1.d1.d0.d1.d1.d0"; got != want {
+		t.Errorf("Recombine g2 = %q, want %q", got, want)
+	}
+	validateNand(t, g1) // Cached function must be rebuilt
+
+	// The constants referred to only by the exchanged symbols are exchanged
+	// with them; c0, which g1 also refers to ahead of position, stays put, so
+	// the c0 that g1 receives takes the value of its own c0.
+	g1, g2 = New("+.c0.*.c1.c2.d0.d0"), New("-.d0.+.c0.c1.c2.d0")
+	g1.Constants, g2.Constants = []float64{1, 2, 3}, []float64{10, 20, 30}
+	Recombine(g1, g2, 2)
+	if got, want := g1.EvalMath(nil), 1+(1+20.0); got != want {
+		t.Errorf("Recombine g1 %q = %v, want %v", g1, got, want)
+	}
+	if got, want := g2.Constants, []float64{10, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Recombine g2 constants = %v, want %v", got, want)
+	}
+	g1, g2 = New("+.c0.c1"), New("*.c0.c1")
+	g1.Constants, g2.Constants = []float64{1, 2}, []float64{3, 4}
+	Recombine(g1, g2, 0)
+	if got, want := g1.EvalMath(nil), 12.0; got != want {
+		t.Errorf("Recombine at position 0: g1 %q = %v, want %v", g1, got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		gene     string
+		headSize int
+		ok       bool
+	}{
+		{gene: "Or.And.Not.Not.Or.And.And.d0.d1.d1.d1.d0.d1.d1.d0", ok: true},
+		{gene: "d0", ok: true},
+		{gene: "Or.And.Not.d0.d1", ok: false},                // ORF runs off the end
+		{gene: "Or.Bogus.d0.d1.d1", ok: false},               // unknown symbol
+		{gene: "Or.d0.And.d1.d0.d1", headSize: 2, ok: false}, // function in the tail
+		{gene: "Or.And.d0.d1.d0.d1", headSize: 2, ok: true},
+	}
+	for i, test := range tests {
+		g := New(test.gene)
+		g.headSize = test.headSize
+		err := g.Validate(bn.BoolAllGates)
+		if test.ok && err != nil {
+			t.Errorf("%v: Validate(%q) = %v, want nil", i, test.gene, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("%v: Validate(%q) = nil, want error", i, test.gene)
+		}
+	}
+	g := New("+.c0.d0")
+	g.Constants = nil
+	if err := g.Validate(mn.Math); err == nil {
+		t.Errorf("Validate(%q) with no constants = nil, want error", g)
+	}
+}
+
+func TestParseTerminal(t *testing.T) {
+	tests := []struct {
+		sym   string
+		kind  string
+		index int
+		ok    bool
+	}{
+		{sym: "d0", kind: "d", index: 0, ok: true},
+		{sym: "d12", kind: "d", index: 12, ok: true},
+		{sym: "c3", kind: "c", index: 3, ok: true},
+		{sym: "d", ok: false},
+		{sym: "dX", ok: false},
+		{sym: "c-1", ok: false},
+		{sym: "+", ok: false},
+	}
+	for _, test := range tests {
+		kind, index, err := ParseTerminal(test.sym)
+		if ok := err == nil; ok != test.ok || kind != test.kind || index != test.index {
+			t.Errorf("ParseTerminal(%q) = (%q, %v, %v), want (%q, %v, ok=%v)", test.sym, kind, index, err, test.kind, test.index, test.ok)
+		}
+	}
+}
+
+func TestValidateKarva(t *testing.T) {
+	fm := functions.FuncMap{"+": mn.Math["+"], "*": mn.Math["*"], "Sqrt": mn.Math["Sqrt"]}
+	tests := []struct {
+		expr     string
+		headSize int
+		ok       bool
+	}{
+		{expr: "+.*.d0.d1.d2.d0.d1", headSize: 3, ok: true},
+		{expr: "Sqrt.c0.d1.d0.d0.d1.d1", headSize: 3, ok: true},
+		{expr: "d0.d1.d0", headSize: 1, ok: true},
+		{expr: "+.*.d0.d1.d2.d0", headSize: 3},       // too few tail terminals
+		{expr: "+.*.d0.d1.d2.d0.d1.d1", headSize: 3}, // too many tail terminals
+		{expr: "+.*.d0.+.d2.d0.d1", headSize: 3},     // function in the tail
+		{expr: "+.-.d0.d1.d2.d0.d1", headSize: 3},    // unknown function
+		{expr: "+.*.d0.d1.dx.d0.d1", headSize: 3},    // bad terminal
+		{expr: "+.*.d0.d1..d0.d1", headSize: 3},      // empty symbol
+		{expr: "d0", headSize: 0},
+	}
+	for _, test := range tests {
+		err := ValidateKarva(test.expr, test.headSize, fm)
+		if test.ok && err != nil {
+			t.Errorf("ValidateKarva(%q, %v) = %v, want nil", test.expr, test.headSize, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("ValidateKarva(%q, %v) = nil, want error", test.expr, test.headSize)
+		}
+	}
+}
+
+func TestRNG(t *testing.T) {
+	funcs := []FuncWeight{{"+", 1}, {"-", 2}, {"*", 3}}
+	opts := &Options{FuncDensity: 0.7}
+	run := func() []string {
+		rng := functions.NewRNG(42)
+		a := RandomNewWith(rng, 8, 9, 3, 2, funcs, opts)
+		b := a.RandomLikeWith(rng)
+		var r []string
+		for i := 0; i < 20; i++ {
+			a.MutateWith(rng)
+			c1, c2 := SubtreeCrossoverWith(rng, a, b)
+			r = append(r, a.String(), c1.String(), c2.String())
+		}
+		return r
+	}
+	if got, want := run(), run(); !reflect.DeepEqual(got, want) {
+		t.Errorf("operators driven by identically seeded RNGs differ:\n%v\n%v", got, want)
+	}
+}
+
+func TestRandomLike(t *testing.T) {
+	funcs := []FuncWeight{
+		{"+", 1},
+		{"*", 3},
+	}
+	g1 := RandomNew(7, 8, 3, 2, funcs)
+	g2 := g1.RandomLike()
+	if g2 == nil {
+		t.Fatalf("RandomLike returned nil")
+	}
+	g2.Symbols = g1.Symbols
+	g2.Constants = g1.Constants
+	if err := CheckEqual(g1, g2); err != nil {
+		t.Errorf("RandomLike structure differs: %v", err)
+	}
+	if g := New("+.d0.d1").RandomLike(); g != nil {
+		t.Errorf("RandomLike on Karva gene = %v, want nil", g)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	g := New("+.*.d0.d1.d0.d1.d0")
+	g.Compile()
+	if g.getCache().mf == nil {
+		t.Fatal("Compile left the math function unbuilt")
+	}
+	if got := g.EvalMath([]float64{3, 4}); got != 15 {
+		t.Errorf("compiled EvalMath = %v, want 15", got)
+	}
+	if err := g.ReplaceSubtree(0, []string{"-", "d0", "d1"}); err != nil {
+		t.Fatalf("ReplaceSubtree = %v", err)
+	}
+	if g.getCache().mf != nil {
+		t.Error("ReplaceSubtree kept the compiled math function")
+	}
+	if got := g.EvalMath([]float64{3, 4}); got != -1 {
+		t.Errorf("EvalMath after ReplaceSubtree = %v, want -1", got)
+	}
+
+	// A gene made without a constructor is cached as well.
+	lit := &Gene{Symbols: []string{"*", "d0", "d0"}}
+	lit.Compile()
+	if lit.getCache().mf == nil {
+		t.Fatal("Compile did not cache the math function of a literal gene")
+	}
+	if got := lit.EvalMath([]float64{3}); got != 9 {
+		t.Errorf("compiled literal EvalMath = %v, want 9", got)
+	}
+
+	// Concurrent first evaluations of a literal gene share one cache.
+	lit = &Gene{Symbols: []string{"+", "d0", "d0"}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := lit.EvalMath([]float64{3}); got != 6 {
+				t.Errorf("concurrent literal EvalMath = %v, want 6", got)
+			}
+		}()
+	}
+	wg.Wait()
+	if lit.getCache().mf == nil {
+		t.Error("EvalMath did not cache the math function of a literal gene")
+	}
+}
+
 func BenchmarkMutate(b *testing.B) {
 	headSize := 7
 	maxArity := 2
@@ -221,3 +454,41 @@ func BenchmarkDup(b *testing.B) {
 	}
 	result = v
 }
+
+var evalResult float64
+
+func BenchmarkEvalMathCompiled(b *testing.B) {
+	headSize := 7
+	maxArity := 2
+	tailSize := headSize*(maxArity-1) + 1
+	numTerminals := 5
+	numConstants := 5
+	funcs := []FuncWeight{
+		{"+", 1},
+		{"-", 5},
+		{"*", 5},
+	}
+	in := []float64{1.5, -2.25, 3, 0.5, 4}
+	b.Run("compiled", func(b *testing.B) {
+		g := RandomNew(headSize, tailSize, numTerminals, numConstants, funcs)
+		g.Compile()
+		b.ReportAllocs()
+		b.ResetTimer()
+		var v float64
+		for i := 0; i < b.N; i++ {
+			v = g.EvalMath(in)
+		}
+		evalResult = v
+	})
+	b.Run("uncompiled", func(b *testing.B) {
+		g := RandomNew(headSize, tailSize, numTerminals, numConstants, funcs)
+		b.ReportAllocs()
+		b.ResetTimer()
+		var v float64
+		for i := 0; i < b.N; i++ {
+			g.invalidate() // Decode the symbols on every evaluation.
+			v = g.EvalMath(in)
+		}
+		evalResult = v
+	})
+}
@@ -0,0 +1,259 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package gene
+
+import (
+	"math"
+
+	"github.com/gmlewis/gep/functions"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+)
+
+// maxResamples is the number of attempts made to find a symbol (during random
+// generation) or a mutation that satisfies Options.Constraint.
+const maxResamples = 100
+
+// Options control the random generation of a gene.
+// The options are retained by the gene (and its duplicates) for use
+// by subsequent operations.
+type Options struct {
+	// FuncDensity, if non-zero, is the probability that each position of the
+	// head holds a function (rather than a terminal). Low densities produce
+	// small expressions and high densities produce large ones.
+	FuncDensity float64
+
+	// Constraint, if non-nil, reports whether the child symbol is allowed as
+	// an argument of the parent symbol. It is consulted during random
+	// generation and mutation, and violations are resolved by resampling.
+	Constraint func(parent, child string) bool
+
+	// Types, if non-nil, restricts the gene to type-compatible expressions.
+	// Like Constraint, it is consulted during random generation and mutation.
+	Types *TypedSet
+
+	// MaxDepth, if non-zero, limits the depth of the expression tree of the
+	// randomly generated gene (see Gene.Depth) by placing only terminals at
+	// that depth. Mutation may later deepen the expression.
+	MaxDepth int
+
+	// Full selects the "full" method of generation with MaxDepth: every
+	// position above the maximum depth holds a function (as far as the head
+	// allows), rather than the usual "grow" method of choosing freely.
+	Full bool
+
+	// Weights, if non-nil, biases the choice of symbols during random
+	// generation and mutation: each symbol is chosen in proportion to its
+	// weight, and symbols without a weight have weight 1. The weights combine
+	// with those of the FuncWeights, so a function listed with a FuncWeight of
+	// 2 and a Weights entry of 0.5 is as likely as an unweighted terminal.
+	Weights map[string]float64
+
+	// Nodes is the map of functions used to determine the structure of the
+	// expression when checking MaxDepth, Constraint, and Types.
+	// It defaults to mn.Math.
+	Nodes functions.FuncMap
+}
+
+func (o *Options) nodes() functions.FuncMap {
+	if o.Nodes == nil {
+		return mn.Math
+	}
+	return o.Nodes
+}
+
+// constrained reports whether the options restrict the structure of a gene.
+func (o *Options) constrained() bool {
+	return o != nil && (o.Constraint != nil || o.Types != nil)
+}
+
+// RandomNewWithOptions is like RandomNew, but the generation of the gene
+// is further controlled by opts (which may be nil).
+func RandomNewWithOptions(headSize, tailSize, numTerminals, numConstants int, fs []FuncWeight, opts *Options) *Gene {
+	return RandomNewWith(functions.DefaultRNG, headSize, tailSize, numTerminals, numConstants, fs, opts)
+}
+
+// RandomNewWith is like RandomNewWithOptions, but draws its randomness from rng.
+func RandomNewWith(rng functions.RNG, headSize, tailSize, numTerminals, numConstants int, fs []FuncWeight, opts *Options) *Gene {
+	r := randomNew(rng, headSize, tailSize, numTerminals, numConstants, fs)
+	r.opts = opts
+	if opts == nil {
+		return r
+	}
+	terminals, funcs := r.choiceSlice[:r.numTerminals], r.choiceSlice[r.numTerminals:]
+	switch {
+	case opts.FuncDensity > 0 && len(funcs) > 0 && len(terminals) > 0:
+		for i := 0; i < headSize; i++ {
+			if rng.Float64() < opts.FuncDensity {
+				r.Symbols[i] = r.choose(rng, funcs, "")
+			} else {
+				r.Symbols[i] = r.choose(rng, terminals, "")
+			}
+		}
+	case opts.Weights != nil && len(r.choiceSlice) > 0:
+		for i := 0; i < headSize; i++ {
+			r.Symbols[i] = r.choose(rng, r.choiceSlice, "")
+		}
+	}
+	if opts.Weights != nil && len(terminals) > 0 {
+		for i := headSize; i < len(r.Symbols); i++ {
+			r.Symbols[i] = r.choose(rng, terminals, "")
+		}
+	}
+	if opts.MaxDepth > 0 {
+		r.limitDepth(rng)
+	}
+	if opts.constrained() {
+		r.constrain(rng)
+	}
+	return r
+}
+
+// choose returns a random symbol (drawn from rng) from the non-empty choices,
+// other than exclude (if the choices hold any other symbol), that is weighted
+// by Options.Weights if given.
+func (g *Gene) choose(rng functions.RNG, choices []string, exclude string) string {
+	if g.opts == nil || g.opts.Weights == nil {
+		for {
+			if s := choices[rng.Intn(len(choices))]; s != exclude {
+				return s
+			}
+		}
+	}
+	weight := func(s string) float64 {
+		if s == exclude {
+			return 0
+		}
+		if w, ok := g.opts.Weights[s]; ok {
+			return math.Max(w, 0)
+		}
+		return 1
+	}
+	total := 0.0
+	for _, s := range choices {
+		total += weight(s)
+	}
+	if total <= 0 {
+		return exclude
+	}
+	x := rng.Float64() * total
+	var last string
+	for _, s := range choices {
+		if w := weight(s); w > 0 {
+			last = s
+			if x -= w; x < 0 {
+				return s
+			}
+		}
+	}
+	return last // Only reached through rounding error.
+}
+
+// parents returns, for each position of the gene, the position of its parent
+// within the expression and the index of the argument of the parent that it
+// fills, or -1 for the root and for non-coding positions.
+// The parent of a position depends only upon the symbols preceding it.
+func (g *Gene) parents(nodes functions.FuncMap) (parent, arg []int) {
+	parent, arg = make([]int, len(g.Symbols)), make([]int, len(g.Symbols))
+	for i := range parent {
+		parent[i], arg[i] = -1, -1
+	}
+	next := 1
+	for i := 0; i < len(g.Symbols) && i < next; i++ {
+		if s, ok := nodes[g.Symbols[i]]; ok {
+			for j := 0; j < s.Terminals() && next < len(g.Symbols); j++ {
+				parent[next], arg[next] = i, j
+				next++
+			}
+		}
+	}
+	return parent, arg
+}
+
+// depths returns the depth within the expression of each position of the gene
+// (1 for the root), or 0 for non-coding positions, given their parents.
+func depths(parent []int) []int {
+	result := make([]int, len(parent))
+	if len(result) > 0 {
+		result[0] = 1
+	}
+	for i := 1; i < len(parent); i++ {
+		if parent[i] >= 0 {
+			result[i] = result[parent[i]] + 1
+		}
+	}
+	return result
+}
+
+// limitDepth regenerates the head of a randomly generated gene so that only
+// terminals appear at the maximum depth (and, for the full method, only
+// functions appear above it).
+func (g *Gene) limitDepth(rng functions.RNG) {
+	terminals, funcs := g.choiceSlice[:g.numTerminals], g.choiceSlice[g.numTerminals:]
+	if len(terminals) == 0 {
+		return
+	}
+	for i := 0; i < g.headSize && i < len(g.Symbols); i++ {
+		parent, _ := g.parents(g.opts.nodes())
+		d := depths(parent)[i]
+		switch {
+		case d == 0: // non-coding
+		case d >= g.opts.MaxDepth:
+			g.Symbols[i] = g.choose(rng, terminals, "")
+		case g.opts.Full && len(funcs) > 0:
+			g.Symbols[i] = g.choose(rng, funcs, "")
+		}
+	}
+}
+
+// allowed reports whether the symbol at position i is permitted by g's
+// options, given its parent and argument index (as returned by parents).
+func (g *Gene) allowed(i, parent, arg int) bool {
+	o := g.opts
+	if parent < 0 {
+		return i > 0 || o.Types == nil || o.Types.Result == "" || o.Types.typeOf(g.Symbols[i]) == o.Types.Result
+	}
+	if o.Constraint != nil && !o.Constraint(g.Symbols[parent], g.Symbols[i]) {
+		return false
+	}
+	return o.Types == nil || o.Types.accepts(g.Symbols[parent], arg, g.Symbols[i])
+}
+
+// satisfiesConstraints reports whether every symbol within the expression
+// of g is permitted by g's options (if any).
+func (g *Gene) satisfiesConstraints() bool {
+	if !g.opts.constrained() {
+		return true
+	}
+	parent, arg := g.parents(g.opts.nodes())
+	for i := range parent {
+		if !g.allowed(i, parent[i], arg[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// constrain resamples, in order, each symbol of a randomly generated gene that
+// is not permitted by its options.
+func (g *Gene) constrain(rng functions.RNG) {
+	nodes := g.opts.nodes()
+	for i := 0; i < len(g.Symbols); i++ {
+		parent, arg := g.parents(nodes)
+		p, a := parent[i], arg[i]
+		if p < 0 && i > 0 {
+			continue
+		}
+		choices := g.choiceSlice
+		if i >= g.headSize || (g.opts.MaxDepth > 0 && depths(parent)[i] >= g.opts.MaxDepth) {
+			choices = choices[:g.numTerminals]
+		}
+		for n := 0; n < maxResamples && !g.allowed(i, p, a); n++ {
+			g.Symbols[i] = g.choose(rng, choices, "")
+		}
+		if !g.allowed(i, p, a) {
+			functions.Log.Printf("gene.RandomNewWithOptions: unable to find a permitted symbol for position %v of %q", i, g)
+		}
+	}
+}
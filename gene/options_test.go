@@ -0,0 +1,126 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package gene
+
+import (
+	"reflect"
+	"testing"
+
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+)
+
+func TestConstraint(t *testing.T) {
+	funcs := []FuncWeight{
+		{"Ln", 5},
+		{"+", 1},
+		{"*", 1},
+	}
+	opts := &Options{
+		Constraint: func(parent, child string) bool { return parent != "Ln" || child != "Ln" },
+	}
+	check := func(g *Gene) {
+		parent, _ := g.parents(opts.nodes())
+		for i, p := range parent {
+			if p >= 0 && g.Symbols[p] == "Ln" && g.Symbols[i] == "Ln" {
+				t.Fatalf("gene %q nests Ln (position %v) under Ln (position %v)", g, i, p)
+			}
+		}
+	}
+	for i := 0; i < 100; i++ {
+		g := RandomNewWithOptions(10, 11, 2, 1, funcs, opts)
+		check(g)
+		for j := 0; j < 100; j++ {
+			g.Mutate()
+			check(g)
+		}
+		if err := g.Validate(opts.nodes()); err != nil {
+			t.Fatalf("gene %q: Validate = %v", g, err)
+		}
+		check(g.RandomLike())
+	}
+}
+
+func TestParents(t *testing.T) {
+	g := New("*.+.d2.d1.d0.d1.d2")
+	parent, arg := g.parents((&Options{}).nodes())
+	if want := []int{-1, 0, 0, 1, 1, -1, -1}; !reflect.DeepEqual(parent, want) {
+		t.Errorf("Gene %q parents = %v, want %v", g, parent, want)
+	}
+	if want := []int{-1, 0, 1, 0, 1, -1, -1}; !reflect.DeepEqual(arg, want) {
+		t.Errorf("Gene %q parent args = %v, want %v", g, arg, want)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	funcs := []FuncWeight{
+		{"+", 1},
+		{"*", 1},
+		{"Ln", 1},
+	}
+	headSize := 20
+	tailSize := headSize + 1
+	for maxDepth := 1; maxDepth <= 6; maxDepth++ {
+		for i := 0; i < 200; i++ {
+			g := RandomNewWithOptions(headSize, tailSize, 3, 1, funcs, &Options{MaxDepth: maxDepth, FuncDensity: 0.9})
+			if err := g.Validate(mn.Math); err != nil {
+				t.Fatalf("MaxDepth %v: gene %q: Validate = %v", maxDepth, g, err)
+			}
+			if d := g.Depth(); d > maxDepth {
+				t.Fatalf("MaxDepth %v: gene %q has depth %v", maxDepth, g, d)
+			}
+		}
+	}
+
+	// The full method produces complete binary trees while the head allows it.
+	binary := []FuncWeight{
+		{"+", 1},
+		{"*", 1},
+	}
+	for maxDepth := 1; maxDepth <= 4; maxDepth++ {
+		g := RandomNewWithOptions(headSize, tailSize, 3, 1, binary, &Options{MaxDepth: maxDepth, Full: true})
+		if d := g.Depth(); d != maxDepth {
+			t.Errorf("full MaxDepth %v: gene %q has depth %v", maxDepth, g, d)
+		}
+		if got, want := g.CodingLength(), 1<<uint(maxDepth)-1; got != want {
+			t.Errorf("full MaxDepth %v: gene %q has coding length %v, want %v", maxDepth, g, got, want)
+		}
+	}
+	// A depth of 6 would need a head of 31 symbols, so the head limits the tree instead.
+	g := RandomNewWithOptions(headSize, tailSize, 3, 1, binary, &Options{MaxDepth: 6, Full: true})
+	if err := g.Validate(mn.Math); err != nil || g.Depth() > 6 {
+		t.Errorf("full MaxDepth 6: gene %q has depth %v, Validate = %v", g, g.Depth(), err)
+	}
+}
+
+func TestWeights(t *testing.T) {
+	funcs := []FuncWeight{
+		{"+", 1},
+		{"Sin", 1},
+		{"*", 1},
+	}
+	opts := &Options{Weights: map[string]float64{"+": 20, "Sin": 0.05, "d1": 0}}
+	counts := map[string]int{}
+	for i := 0; i < 200; i++ {
+		g := RandomNewWithOptions(10, 11, 3, 0, funcs, opts)
+		for j := 0; j < 50; j++ {
+			g.Mutate()
+			for _, sym := range g.Symbols {
+				counts[sym]++
+			}
+		}
+		if err := g.Validate(mn.Math); err != nil {
+			t.Fatalf("gene %q: Validate = %v", g, err)
+		}
+	}
+	if counts["+"] < 20*counts["Sin"] {
+		t.Errorf("weighted symbol + appeared %v times, down-weighted Sin %v times, want far more +", counts["+"], counts["Sin"])
+	}
+	if counts["*"] < 5*counts["Sin"] || counts["+"] < 2*counts["*"] {
+		t.Errorf("symbol counts = %v, want + more than the unweighted * more than Sin", counts)
+	}
+	if counts["d1"] != 0 {
+		t.Errorf("symbol d1 with zero weight appeared %v times", counts["d1"])
+	}
+}
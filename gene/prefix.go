@@ -0,0 +1,156 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package gene
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gmlewis/gep/functions"
+)
+
+// Prefix returns the expressed tree of the gene in prefix (Polish) notation:
+// the symbols separated by spaces, with each function preceding its arguments,
+// for example "+ * d0 d0 d1".
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Gene) Prefix() string {
+	var syms []string
+	var walk func(n *ExprNode)
+	walk = func(n *ExprNode) {
+		syms = append(syms, n.Symbol)
+		for _, v := range n.Args {
+			walk(v)
+		}
+	}
+	if root := g.Tree(g.mathNodes()); root != nil {
+		walk(root)
+	}
+	return strings.Join(syms, " ")
+}
+
+// FromPrefix parses expr, an expression in prefix (Polish) notation with its
+// symbols separated by whitespace, into a new gene with the given head size.
+// fm is the map of available functions; the tail is sized for the largest arity
+// in fm. The expression must fit within the gene, with all of its functions in
+// the head. The remainder of the gene is padded with d0, and any constants
+// (c0, c1, ...) referenced are zero-valued. Mutation of the gene chooses
+// uniformly from the terminals and functions referenced; see NewWithHead.
+func FromPrefix(expr string, fm functions.FuncMap, headSize int) (*Gene, error) {
+	tokens := strings.Fields(expr)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty prefix expression")
+	}
+	pos := 0
+	var parse func() (*ExprNode, error)
+	parse = func() (*ExprNode, error) {
+		if pos >= len(tokens) {
+			return nil, fmt.Errorf("prefix expression %q ends prematurely", expr)
+		}
+		sym := tokens[pos]
+		pos++
+		n := &ExprNode{Symbol: sym}
+		if f, ok := fm[sym]; ok {
+			for i := 0; i < f.Terminals(); i++ {
+				arg, err := parse()
+				if err != nil {
+					return nil, err
+				}
+				n.Args = append(n.Args, arg)
+			}
+			return n, nil
+		}
+		if _, _, err := ParseTerminal(sym); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	root, err := parse()
+	if err != nil {
+		return nil, err
+	}
+	if pos < len(tokens) {
+		return nil, fmt.Errorf("unexpected symbols %q after end of prefix expression", strings.Join(tokens[pos:], " "))
+	}
+
+	maxArity := 1
+	for _, f := range fm {
+		if f.Terminals() > maxArity {
+			maxArity = f.Terminals()
+		}
+	}
+	syms := root.Karva()
+	padded := make([]string, headSize+headSize*(maxArity-1)+1)
+	for i := range padded {
+		padded[i] = "d0"
+	}
+	copy(padded, syms)
+	r := NewWithHead(strings.Join(padded, "."), headSize, fm)
+	if err := r.fits(syms, fm); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewWithHead is like New, but creates a gene whose head size is known, so
+// that it may be mutated and evolved like a gene created by RandomNew.
+// Mutation of the gene chooses uniformly from the inputs and constants
+// referenced by x (at least d0) and those functions of fm used by x whose
+// arguments the tail can always hold: with head size h and tail length t, the
+// functions of arity n with h*(n-1)+1 <= t. Functions of fm not used by x are
+// never chosen, so a gene built by hand evolves with its own functions only.
+// Symbols that are neither functions of fm nor terminals are left in place;
+// see Validate.
+func NewWithHead(x string, headSize int, fm functions.FuncMap) *Gene {
+	r := New(x)
+	r.headSize = headSize
+	numInputs, numConstants := 1, len(r.Constants)
+	for _, sym := range r.Symbols {
+		if _, ok := fm[sym]; ok {
+			continue
+		}
+		if kind, index, err := ParseTerminal(sym); err == nil && kind == "d" && index >= numInputs {
+			numInputs = index + 1
+		}
+	}
+	r.numTerminals = numInputs + numConstants
+	for i := 0; i < numInputs; i++ {
+		r.choiceSlice = append(r.choiceSlice, fmt.Sprintf("d%v", i))
+	}
+	for i := 0; i < numConstants; i++ {
+		r.choiceSlice = append(r.choiceSlice, fmt.Sprintf("c%v", i))
+	}
+	tailSize := len(r.Symbols) - headSize
+	var funcs []string
+	used := map[string]bool{}
+	for _, sym := range r.Symbols {
+		if f, ok := fm[sym]; ok && !used[sym] && headSize*(f.Terminals()-1)+1 <= tailSize {
+			used[sym] = true
+			funcs = append(funcs, sym)
+		}
+	}
+	sort.Strings(funcs)
+	r.choiceSlice = append(r.choiceSlice, funcs...)
+	return r
+}
+
+// Structure returns the head size of the gene (0 if unknown, as for genes
+// created by New) and the choices from which its mutations draw, the first
+// numTerminals of which are terminals and the rest functions (repeated
+// according to their weights). It is the state that, together with the
+// symbols and constants, NewWithStructure needs to recreate the gene.
+func (g *Gene) Structure() (headSize int, choices []string, numTerminals int) {
+	return g.headSize, append([]string(nil), g.choiceSlice...), g.numTerminals
+}
+
+// NewWithStructure is like New, but gives the gene the head size and the
+// mutation choices (as returned by Structure) of the gene it recreates.
+func NewWithStructure(x string, headSize int, choices []string, numTerminals int) *Gene {
+	r := New(x)
+	r.headSize = headSize
+	r.choiceSlice = append([]string(nil), choices...)
+	r.numTerminals = numTerminals
+	return r
+}
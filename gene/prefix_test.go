@@ -0,0 +1,97 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package gene
+
+import (
+	"testing"
+
+	"github.com/gmlewis/gep/functions"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+)
+
+func TestPrefix(t *testing.T) {
+	tests := []struct {
+		gene   string
+		prefix string
+	}{
+		{gene: "+.*.d1.d0.d0.d1.d1", prefix: "+ * d0 d0 d1"},
+		{gene: "*.+.d2.d1.d0.d1.d2", prefix: "* + d1 d0 d2"},
+		{gene: "-.+.+.-.-.*.d0.d0.d0.d0.d0.d0.d0", prefix: "- + - d0 d0 - d0 d0 + * d0 d0 d0"},
+		{gene: "d1.+.d0.d0", prefix: "d1"},
+	}
+	inputs := [][]float64{{1, 2, 3}, {-2.5, 4, 0.5}, {7, -1, 2}}
+	for _, test := range tests {
+		g := New(test.gene)
+		if got := g.Prefix(); got != test.prefix {
+			t.Errorf("Gene %q Prefix = %q, want %q", test.gene, got, test.prefix)
+		}
+		r, err := FromPrefix(test.prefix, mn.Math, 10)
+		if err != nil {
+			t.Fatalf("FromPrefix(%q) = %v", test.prefix, err)
+		}
+		if err := r.Validate(mn.Math); err != nil {
+			t.Errorf("FromPrefix(%q) = %q: Validate = %v", test.prefix, r, err)
+		}
+		if got := r.Prefix(); got != test.prefix {
+			t.Errorf("FromPrefix(%q).Prefix = %q", test.prefix, got)
+		}
+		for _, in := range inputs {
+			if got, want := r.EvalMath(in), g.EvalMath(in); got != want {
+				t.Errorf("FromPrefix(%q).EvalMath(%v) = %v, want %v", test.prefix, in, got, want)
+			}
+		}
+		r.Mutate() // Must not fail.
+	}
+
+	errTests := []struct {
+		expr     string
+		headSize int
+	}{
+		{expr: "", headSize: 5},
+		{expr: "+ d0", headSize: 5},
+		{expr: "+ d0 d1 d2", headSize: 5},
+		{expr: "+ d0 Bogus", headSize: 5},
+		{expr: "+ dX d0", headSize: 5},
+		{expr: "+ * d0 d0 d1", headSize: 1}, // * would be in the tail
+	}
+	for _, test := range errTests {
+		if g, err := FromPrefix(test.expr, mn.Math, test.headSize); err == nil {
+			t.Errorf("FromPrefix(%q, %v) = %q, want error", test.expr, test.headSize, g)
+		}
+	}
+}
+
+func TestNewWithHead(t *testing.T) {
+	// With head size 2 and a tail of 3, the tail can hold the arguments of
+	// binary functions but not of the ternary ones, which are never chosen.
+	g := NewWithHead("+.c0.d1.d0.d0", 2, mn.Math)
+	if g.headSize != 2 || g.numTerminals != 3 {
+		t.Fatalf("NewWithHead head size = %v, terminals = %v, want 2 and 3", g.headSize, g.numTerminals)
+	}
+	rng := functions.NewRNG(1)
+	for i := 0; i < 1000; i++ {
+		g.MutateWith(rng)
+		if err := g.Validate(mn.Math); err != nil {
+			t.Fatalf("NewWithHead gene %q after %v mutations: Validate = %v", g, i+1, err)
+		}
+		for _, sym := range g.Symbols {
+			switch sym {
+			case "+", "d0", "d1", "c0":
+			default:
+				t.Fatalf("NewWithHead gene %q after %v mutations has symbol %q not used by the original gene", g, i+1, sym)
+			}
+		}
+	}
+}
+
+func TestStructure(t *testing.T) {
+	g := RandomNew(5, 6, 3, 2, []FuncWeight{{"+", 2}, {"*", 1}})
+	headSize, choices, numTerminals := g.Structure()
+	r := NewWithStructure(g.String(), headSize, choices, numTerminals)
+	r.Constants = g.Constants
+	if err := CheckEqual(r, g); err != nil {
+		t.Errorf("NewWithStructure(Structure()) = %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package gene
+
+import (
+	"github.com/gmlewis/gep/functions"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+)
+
+// mathNodes returns the map of floating-point functions to which g is bound.
+func (g *Gene) mathNodes() functions.FuncMap {
+	if g.funcs == nil {
+		return mn.Math
+	}
+	return g.funcs
+}
+
+// Rebind returns a copy of the gene whose floating-point evaluation (EvalMath
+// and EvalMathSafe) resolves its function symbols against fm instead of mn.Math,
+// such as to deploy a gene with protected division in place of plain division.
+// It returns an error if the gene is not valid with fm (see Validate). A
+// symbol may have a different arity in fm than in mn.Math, which changes the
+// expression of the gene; the analyses of the expression (such as Depth,
+// UsedInputs, and ReplaceSubtree) follow fm as well. The copy (and its
+// duplicates) remain bound to fm.
+func (g *Gene) Rebind(fm functions.FuncMap) (*Gene, error) {
+	if err := g.Validate(fm); err != nil {
+		return nil, err
+	}
+	r := g.Dup()
+	r.funcs = fm
+	r.invalidate()
+	return r, nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package gene
+
+import (
+	"fmt"
+
+	"github.com/gmlewis/gep/functions"
+)
+
+// ExprNode is a single node of the expression tree encoded by the open
+// reading frame of a gene. Functions have one argument per input terminal,
+// whereas inputs (d0, d1, ...) and constants (c0, c1, ...) are leaves.
+type ExprNode struct {
+	Symbol string
+	Args   []*ExprNode
+}
+
+// Tree decodes the open reading frame of the gene into an expression tree,
+// using nodes to determine the arity of each function symbol.
+// It returns nil if the gene has no symbols.
+func (g *Gene) Tree(nodes functions.FuncMap) *ExprNode {
+	if len(g.Symbols) == 0 {
+		return nil
+	}
+	argOrder := g.getBoolArgOrder(nodes)
+	return g.buildTree(0, argOrder)
+}
+
+func (g *Gene) buildTree(symbolIndex int, argOrder [][]int) *ExprNode {
+	n := &ExprNode{Symbol: g.Symbols[symbolIndex]}
+	for _, arg := range argOrder[symbolIndex] {
+		if arg >= len(g.Symbols) { // Invalid gene: the expression runs off the end.
+			break
+		}
+		n.Args = append(n.Args, g.buildTree(arg, argOrder))
+	}
+	return n
+}
+
+// Depth returns the height of the tree rooted at n: 1 for a single terminal,
+// or 0 for a nil tree.
+func (n *ExprNode) Depth() int {
+	if n == nil {
+		return 0
+	}
+	max := 0
+	for _, v := range n.Args {
+		if d := v.Depth(); d > max {
+			max = d
+		}
+	}
+	return max + 1
+}
+
+// Depth returns the height of the expression tree of the gene, so a gene
+// expressing a single terminal has depth 1.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Gene) Depth() int {
+	return g.Tree(g.mathNodes()).Depth()
+}
+
+// nodes returns the nodes of the tree in breadth-first order, which is
+// the order in which they appear in the Karva expression.
+func (n *ExprNode) nodes() []*ExprNode {
+	if n == nil {
+		return nil
+	}
+	result := []*ExprNode{n}
+	for i := 0; i < len(result); i++ {
+		result = append(result, result[i].Args...)
+	}
+	return result
+}
+
+// Karva returns the symbols of the tree encoded as a Karva expression
+// (that is, in breadth-first order).
+func (n *ExprNode) Karva() []string {
+	var result []string
+	for _, v := range n.nodes() {
+		result = append(result, v.Symbol)
+	}
+	return result
+}
+
+// ReplaceSubtree substitutes the subtree rooted at position pos of the open
+// reading frame with the expression whose Karva symbols are headSyms.
+// The open reading frame is re-encoded and written back over the gene,
+// leaving the remaining (non-coding) symbols untouched.
+// An error is returned, and the gene left unchanged, if pos lies outside the
+// open reading frame, headSyms is not a complete expression of known symbols,
+// or the resulting expression does not fit the head and tail of the gene.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Gene) ReplaceSubtree(pos int, headSyms []string) error {
+	nodes := g.mathNodes()
+	orf := g.orfLength(nodes)
+	if orf > len(g.Symbols) {
+		return fmt.Errorf("gene %q is not a complete expression", g)
+	}
+	if pos < 0 || pos >= orf {
+		return fmt.Errorf("position %v is outside the open reading frame (length %v)", pos, orf)
+	}
+	for _, sym := range headSyms {
+		if _, ok := nodes[sym]; ok {
+			continue
+		}
+		if err := g.checkTerminal(sym); err != nil {
+			return err
+		}
+	}
+	sub := &Gene{Symbols: headSyms}
+	if len(headSyms) == 0 || sub.orfLength(nodes) > len(headSyms) {
+		return fmt.Errorf("replacement %v is not a complete expression", headSyms)
+	}
+	root := g.Tree(nodes)
+	*root.nodes()[pos] = *sub.Tree(nodes)
+	syms := root.Karva()
+	if err := g.fits(syms, nodes); err != nil {
+		return err
+	}
+	copy(g.Symbols, syms)
+	g.invalidate()
+	return nil
+}
+
+// AddConstant appends the constant v to the constants of the gene, making it
+// available to the expression (such as through ReplaceSubtree) and to
+// subsequent mutations, and returns its terminal symbol (such as "c2").
+func (g *Gene) AddConstant(v float64) string {
+	sym := fmt.Sprintf("c%v", len(g.Constants))
+	g.Constants = append(g.Constants, v)
+	if len(g.choiceSlice) >= g.numTerminals && len(g.choiceSlice) > 0 {
+		choices := make([]string, 0, len(g.choiceSlice)+1)
+		choices = append(choices, g.choiceSlice[:g.numTerminals]...)
+		choices = append(choices, sym)
+		g.choiceSlice = append(choices, g.choiceSlice[g.numTerminals:]...)
+	}
+	g.numTerminals++
+	g.invalidate()
+	return sym
+}
+
+// fits checks that the Karva symbols syms can be written over the start of the
+// gene while respecting its length and, if known, its head size.
+func (g *Gene) fits(syms []string, nodes functions.FuncMap) error {
+	if len(syms) > len(g.Symbols) {
+		return fmt.Errorf("expression of length %v does not fit gene of length %v", len(syms), len(g.Symbols))
+	}
+	if g.headSize > 0 {
+		for i := g.headSize; i < len(syms); i++ {
+			if _, ok := nodes[syms[i]]; ok {
+				return fmt.Errorf("expression places function %q in the tail at position %v (head size %v)", syms[i], i, g.headSize)
+			}
+		}
+	}
+	return nil
+}
+
+// SubtreeCrossover swaps a randomly chosen subtree of the expression of gene a
+// with a randomly chosen subtree of the expression of gene b (as in genetic
+// programming) and returns the two resulting children, leaving a and b unchanged.
+// Because genes have a fixed length, each child is re-encoded to fit its head
+// and tail: subtrees that would place a function in the tail, or run off the
+// end of the gene, are truncated to a terminal. The non-coding region of each
+// child is kept from its parent. If a child fails validation, a duplicate of
+// its parent is returned in its place.
+// Like SymbolCount, this currently only works for Math expressions.
+func SubtreeCrossover(a, b *Gene) (*Gene, *Gene) {
+	return SubtreeCrossoverWith(functions.DefaultRNG, a, b)
+}
+
+// SubtreeCrossoverWith is like SubtreeCrossover, but draws its randomness from rng.
+func SubtreeCrossoverWith(rng functions.RNG, a, b *Gene) (*Gene, *Gene) {
+	if a == nil || b == nil {
+		functions.Log.Printf("gene.SubtreeCrossover error: a and b must be non-nil")
+		return nil, nil
+	}
+	c1, c2 := a.Dup(), b.Dup()
+	t1, t2 := a.Tree(a.mathNodes()), b.Tree(b.mathNodes())
+	n1, n2 := t1.nodes(), t2.nodes()
+	if len(n1) == 0 || len(n2) == 0 {
+		functions.Log.Printf("gene.SubtreeCrossover error: a and b must have symbols")
+		return c1, c2
+	}
+	p1, p2 := rng.Intn(len(n1)), rng.Intn(len(n2))
+	*n1[p1], *n2[p2] = *n2[p2], *n1[p1]
+	c1.writeTree(t1, a.mathNodes())
+	c2.writeTree(t2, b.mathNodes())
+	if err := c1.Validate(a.mathNodes()); err != nil {
+		functions.Log.Printf("gene.SubtreeCrossover: discarding child %q: %v", c1, err)
+		c1 = a.Dup()
+	}
+	if err := c2.Validate(b.mathNodes()); err != nil {
+		functions.Log.Printf("gene.SubtreeCrossover: discarding child %q: %v", c2, err)
+		c2 = b.Dup()
+	}
+	return c1, c2
+}
+
+// writeTree truncates the tree rooted at root so that it fits the gene and
+// writes its Karva encoding over the start of the gene.
+func (g *Gene) writeTree(root *ExprNode, nodes functions.FuncMap) {
+	queue := []*ExprNode{root}
+	for i := 0; i < len(queue); i++ {
+		n := queue[i]
+		if _, ok := nodes[n.Symbol]; ok && ((g.headSize > 0 && i >= g.headSize) || len(queue)+len(n.Args) > len(g.Symbols)) {
+			*n = *n.leftmostLeaf()
+		}
+		queue = append(queue, n.Args...)
+	}
+	copy(g.Symbols, root.Karva())
+	g.invalidate()
+}
+
+// leftmostLeaf returns a copy of the first terminal found by following the
+// first argument of each node down from n.
+func (n *ExprNode) leftmostLeaf() *ExprNode {
+	for len(n.Args) > 0 {
+		n = n.Args[0]
+	}
+	return &ExprNode{Symbol: n.Symbol}
+}
@@ -0,0 +1,241 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package gene
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gmlewis/gep/functions"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+)
+
+func TestTree(t *testing.T) {
+	g := New("*.+.d2.d1.d0.d1.d2")
+	want := &ExprNode{Symbol: "*", Args: []*ExprNode{
+		{Symbol: "+", Args: []*ExprNode{{Symbol: "d1"}, {Symbol: "d0"}}},
+		{Symbol: "d2"},
+	}}
+	if got := g.Tree(mn.Math); !reflect.DeepEqual(got, want) {
+		t.Errorf("Gene %q Tree = %#v, want %#v", g, got, want)
+	}
+	if got := (&Gene{}).Tree(mn.Math); got != nil {
+		t.Errorf("empty gene Tree = %#v, want nil", got)
+	}
+}
+
+func TestKarva(t *testing.T) {
+	g := New("*.+.d2.d1.d0.d1.d2")
+	if got, want := g.Tree(mn.Math).Karva(), []string{"*", "+", "d2", "d1", "d0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Gene %q Tree.Karva = %v, want %v", g, got, want)
+	}
+}
+
+func TestReplaceSubtree(t *testing.T) {
+	tests := []struct {
+		gene     string
+		headSize int
+		pos      int
+		sub      []string
+		want     string
+		before   float64
+		after    float64
+	}{
+		{ // leaf: d0+d1 => d0+(d0*d0)
+			gene: "+.d0.d1.d1.d0.d0.d1", headSize: 3, pos: 2, sub: []string{"*", "d0", "d0"},
+			want: "+.d0.*.d0.d0.d0.d1", before: 8, after: 12,
+		},
+		{ // internal node: (d0*d0)+d1 => (d1-d0)+d1
+			gene: "+.*.d1.d0.d0.d1.d1", headSize: 3, pos: 1, sub: []string{"-", "d1", "d0"},
+			want: "+.-.d1.d1.d0.d1.d1", before: 14, after: 7,
+		},
+		{ // root, with a shorter expression leaving the non-coding region alone
+			gene: "+.*.d1.d0.d0.d1.d1", headSize: 3, pos: 0, sub: []string{"d1"},
+			want: "d1.*.d1.d0.d0.d1.d1", before: 14, after: 5,
+		},
+	}
+	in := []float64{3, 5}
+	for i, test := range tests {
+		g := New(test.gene)
+		g.headSize = test.headSize
+		validateMath(t, g, in, test.before) // Force evaluation
+		if err := g.ReplaceSubtree(test.pos, test.sub); err != nil {
+			t.Fatalf("%v: ReplaceSubtree(%v, %v) = %v", i, test.pos, test.sub, err)
+		}
+		if got := g.String(); got != test.want {
+			t.Errorf("%v: ReplaceSubtree(%v, %v) = %q, want %q", i, test.pos, test.sub, got, test.want)
+		}
+		if err := g.Validate(mn.Math); err != nil {
+			t.Errorf("%v: Validate(%q) = %v", i, g, err)
+		}
+		validateMath(t, g, in, test.after) // Cached function must be rebuilt
+	}
+
+	errTests := []struct {
+		pos int
+		sub []string
+	}{
+		{pos: -1, sub: []string{"d0"}},
+		{pos: 5, sub: []string{"d0"}},                                        // outside the ORF
+		{pos: 2, sub: []string{"*", "d0"}},                                   // incomplete replacement
+		{pos: 2, sub: []string{"Bogus"}},                                     // unknown symbol
+		{pos: 2, sub: []string{"d7"}},                                        // unknown input
+		{pos: 3, sub: []string{"*", "d0", "d0"}},                             // function in the tail
+		{pos: 2, sub: []string{"*", "*", "*", "d0", "d0", "d0", "d0", "d0"}}, // too long
+	}
+	for i, test := range errTests {
+		g := New("+.*.d1.d0.d0.d1.d1")
+		g.headSize = 3
+		if err := g.ReplaceSubtree(test.pos, test.sub); err == nil {
+			t.Errorf("%v: ReplaceSubtree(%v, %v) = nil, want error", i, test.pos, test.sub)
+		}
+		if got, want := g.String(), "+.*.d1.d0.d0.d1.d1"; got != want {
+			t.Errorf("%v: failed ReplaceSubtree altered gene: %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestSubtreeCrossover(t *testing.T) {
+	// The parents share no coding symbols, so every swap changes both children.
+	newGene := func(karva string, headSize int) *Gene {
+		g := New(karva)
+		g.headSize = headSize
+		return g
+	}
+	tests := []struct {
+		a, b *Gene
+	}{
+		{a: newGene("+.+.d0.d0.d0.d0.d1", 3), b: newGene("*.*.*.d1.d1.d1.d1", 3)},
+		// Subtrees from b must be truncated to fit the much smaller head of a.
+		{a: newGene("+.d0.d0.d0.d1", 2), b: newGene("*.*.*.*.*.*.d1.d1.d1.d1.d1.d1.d1", 6)},
+	}
+	for i, test := range tests {
+		as, bs := test.a.String(), test.b.String()
+		for j := 0; j < 200; j++ {
+			c1, c2 := SubtreeCrossover(test.a, test.b)
+			if test.a.String() != as || test.b.String() != bs {
+				t.Fatalf("%v: SubtreeCrossover altered parents: %q, %q", i, test.a, test.b)
+			}
+			for _, c := range []*Gene{c1, c2} {
+				if err := c.Validate(mn.Math); err != nil {
+					t.Fatalf("%v: SubtreeCrossover child %q: Validate = %v", i, c, err)
+				}
+			}
+			if len(c1.Symbols) != len(test.a.Symbols) || len(c2.Symbols) != len(test.b.Symbols) {
+				t.Fatalf("%v: SubtreeCrossover changed gene lengths: %q, %q", i, c1, c2)
+			}
+			if c1.String() == as || c2.String() == bs {
+				t.Fatalf("%v: SubtreeCrossover children %q, %q, want different from parents", i, c1, c2)
+			}
+		}
+	}
+}
+
+func TestDepth(t *testing.T) {
+	tests := []struct {
+		gene string
+		want int
+	}{
+		{gene: "d0", want: 1},
+		{gene: "d0.+.d1.d2", want: 1},
+		{gene: "+.d0.d1", want: 2},
+		{gene: "+.*.-.d0.d1.d2.d3", want: 3},                 // balanced
+		{gene: "*.+.*.-./.d0.d1.d2.d3.d4.d5.d6.d7", want: 4}, // balanced
+		{gene: "Ln.Ln.Ln.Ln.d0.d1", want: 5},                 // degenerate chain
+		{gene: "+.d0.+.d1.+.d2.d3", want: 4},                 // degenerate chain
+	}
+	for _, test := range tests {
+		if got := New(test.gene).Depth(); got != test.want {
+			t.Errorf("Gene %q Depth = %v, want %v", test.gene, got, test.want)
+		}
+	}
+	if got := (&Gene{}).Depth(); got != 0 {
+		t.Errorf("empty gene Depth = %v, want 0", got)
+	}
+}
+
+func TestAddConstant(t *testing.T) {
+	g := RandomNew(3, 4, 2, 1, []FuncWeight{{"+", 1}, {"*", 1}})
+	if got := g.AddConstant(2.5); got != "c1" {
+		t.Fatalf("AddConstant = %q, want %q", got, "c1")
+	}
+	if got, want := g.Constants[1], 2.5; got != want {
+		t.Errorf("Constants[1] = %v, want %v", got, want)
+	}
+	if err := g.ReplaceSubtree(0, []string{"+", "c1", "d1"}); err != nil {
+		t.Fatalf("ReplaceSubtree with the new constant = %v", err)
+	}
+	if err := g.Validate(mn.Math); err != nil {
+		t.Errorf("Validate(%q) = %v", g, err)
+	}
+	validateMath(t, g, []float64{3, 5}, 7.5)
+	// The new constant is available to mutation, in the tail as well.
+	seen := false
+	for i := 0; i < 1000 && !seen; i++ {
+		g.Mutate()
+		for _, sym := range g.Symbols[3:] {
+			seen = seen || sym == "c1"
+		}
+		if err := g.Validate(mn.Math); err != nil {
+			t.Fatalf("Validate(%q) after Mutate = %v", g, err)
+		}
+	}
+	if !seen {
+		t.Errorf("Mutate never placed the new constant in the tail")
+	}
+}
+
+func TestReboundArity(t *testing.T) {
+	// "+" is ternary in fm, so the analyses of the rebound gene differ from
+	// those under mn.Math, where "+.d0.d1.d2..." would express d0+d1.
+	fm := functions.FuncMap{}
+	for k, v := range mn.Math {
+		fm[k] = v
+	}
+	fm["+"] = mn.Math["Add3"]
+	g, err := NewWithHead("+.d0.d1.d2.d0.d0.d0", 2, fm).Rebind(fm)
+	if err != nil {
+		t.Fatalf("Rebind = %v", err)
+	}
+	if got, want := g.UsedInputs(), []int{0, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UsedInputs = %v, want %v", got, want)
+	}
+	if got, want := g.CodingLength(), 4; got != want {
+		t.Errorf("CodingLength = %v, want %v", got, want)
+	}
+	if got, want := g.Prefix(), "+ d0 d1 d2"; got != want {
+		t.Errorf("Prefix = %q, want %q", got, want)
+	}
+	if got, want := g.Depth(), 2; got != want {
+		t.Errorf("Depth = %v, want %v", got, want)
+	}
+	if err := g.ReplaceSubtree(1, []string{"+", "d1", "d1", "d1"}); err != nil {
+		t.Fatalf("ReplaceSubtree = %v", err)
+	}
+	if got, want := g.String(), "+.+.d1.d2.d1.d1.d1"; got != want {
+		t.Errorf("ReplaceSubtree = %q, want %q", got, want)
+	}
+	if got, want := g.Depth(), 3; got != want {
+		t.Errorf("Depth after ReplaceSubtree = %v, want %v", got, want)
+	}
+	// (2+2+2) + 2 + 4
+	if got, want := g.EvalMath([]float64{1, 2, 4}), 12.0; got != want {
+		t.Errorf("EvalMath after ReplaceSubtree = %v, want %v", got, want)
+	}
+
+	other, err := NewWithHead("+.+.d2.d0.d1.d2.d0", 2, fm).Rebind(fm)
+	if err != nil {
+		t.Fatalf("Rebind = %v", err)
+	}
+	rng := functions.NewRNG(1)
+	for i := 0; i < 100; i++ {
+		c1, c2 := SubtreeCrossoverWith(rng, g, other)
+		for _, c := range []*Gene{c1, c2} {
+			if err := c.Validate(fm); err != nil {
+				t.Fatalf("SubtreeCrossover child %q: Validate = %v", c, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package gene
+
+// Signature describes the return type and argument types of a typed function.
+type Signature struct {
+	Return string
+	Args   []string
+}
+
+// TypedSet describes the types of the functions and terminals available to
+// a typed gene (see Options.Types). Only type-compatible expressions are
+// generated: each argument of a function must be a symbol of the type
+// expected by that argument, and the root of the expression must be of
+// type Result (if non-empty). Symbols missing from the set are never
+// permitted within an expression, and since the tail holds only terminals,
+// there should be a terminal of every argument type.
+type TypedSet struct {
+	Result    string               // type of the value of the expression
+	Funcs     map[string]Signature // function symbol => signature
+	Terminals map[string]string    // input or constant symbol (d0, c0, ...) => type
+}
+
+// typeOf returns the type of the value of symbol sym, or "" if unknown.
+func (ts *TypedSet) typeOf(sym string) string {
+	if s, ok := ts.Funcs[sym]; ok {
+		return s.Return
+	}
+	return ts.Terminals[sym]
+}
+
+// accepts reports whether child may be argument number arg of parent.
+func (ts *TypedSet) accepts(parent string, arg int, child string) bool {
+	s, ok := ts.Funcs[parent]
+	if !ok || arg >= len(s.Args) {
+		return false
+	}
+	t := ts.typeOf(child)
+	return t != "" && t == s.Args[arg]
+}
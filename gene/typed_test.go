@@ -0,0 +1,97 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package gene
+
+import (
+	"testing"
+
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+)
+
+var typedSet = &TypedSet{
+	Result: "float",
+	Funcs: map[string]Signature{
+		"+":    {Return: "float", Args: []string{"float", "float"}},
+		"*":    {Return: "float", Args: []string{"float", "float"}},
+		"LT2A": {Return: "bool", Args: []string{"float", "float"}},
+		"NOT":  {Return: "bool", Args: []string{"bool"}},
+		"AND1": {Return: "bool", Args: []string{"bool", "bool"}},
+		"Add3": {Return: "float", Args: []string{"bool", "float", "float"}}, // if-then-else
+	},
+	Terminals: map[string]string{
+		"d0": "float",
+		"d1": "float",
+		"d2": "bool",
+		"c0": "float",
+	},
+}
+
+// typeCheck returns the type of the expression rooted at n, or "" if
+// the expression is not type-compatible.
+func typeCheck(ts *TypedSet, n *ExprNode) string {
+	s, ok := ts.Funcs[n.Symbol]
+	if !ok {
+		return ts.Terminals[n.Symbol]
+	}
+	if len(n.Args) != len(s.Args) {
+		return ""
+	}
+	for i, arg := range n.Args {
+		if typeCheck(ts, arg) != s.Args[i] {
+			return ""
+		}
+	}
+	return s.Return
+}
+
+func TestTypedSet(t *testing.T) {
+	funcs := []FuncWeight{
+		{"+", 1},
+		{"*", 1},
+		{"LT2A", 1},
+		{"NOT", 1},
+		{"AND1", 1},
+		{"Add3", 1},
+	}
+	opts := &Options{Types: typedSet}
+	check := func(g *Gene) {
+		if got := typeCheck(typedSet, g.Tree(mn.Math)); got != typedSet.Result {
+			t.Fatalf("gene %q has type %q, want %q", g, got, typedSet.Result)
+		}
+	}
+	headSize := 10
+	tailSize := headSize*(3-1) + 1
+	for i := 0; i < 200; i++ {
+		g := RandomNewWithOptions(headSize, tailSize, 3, 1, funcs, opts)
+		check(g)
+		for j := 0; j < 50; j++ {
+			g.Mutate()
+			check(g)
+		}
+	}
+}
+
+func TestTypedSetAccepts(t *testing.T) {
+	tests := []struct {
+		parent string
+		arg    int
+		child  string
+		want   bool
+	}{
+		{"+", 0, "d0", true},
+		{"+", 1, "LT2A", false},
+		{"Add3", 0, "d2", true},
+		{"Add3", 0, "d0", false},
+		{"Add3", 3, "d0", false},
+		{"NOT", 0, "AND1", true},
+		{"Bogus", 0, "d0", false},
+		{"+", 0, "d9", false},
+	}
+	for _, test := range tests {
+		if got := typedSet.accepts(test.parent, test.arg, test.child); got != test.want {
+			t.Errorf("accepts(%q, %v, %q) = %v, want %v", test.parent, test.arg, test.child, got, test.want)
+		}
+	}
+}
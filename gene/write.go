@@ -6,10 +6,10 @@ package gene
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 
+	"github.com/gmlewis/gep/functions"
 	"github.com/gmlewis/gep/grammars"
 )
 
@@ -47,7 +47,7 @@ func (g *Gene) buildExp(symbolIndex int, argOrder [][]int, grammar *grammars.Gra
 			return "", fmt.Errorf("unable to parse variable index: sym=%v", sym)
 		}
 		if n := g.numTerminals - len(g.Constants); index > n {
-			log.Fatalf("terminal symbol name %q exceeds number of terminals (%v)", sym, n)
+			functions.Log.Fatalf("terminal symbol name %q exceeds number of terminals (%v)", sym, n)
 		}
 		return fmt.Sprintf("d[%v]", index), nil
 	}
@@ -57,7 +57,7 @@ func (g *Gene) buildExp(symbolIndex int, argOrder [][]int, grammar *grammars.Gra
 			return "", fmt.Errorf("unable to parse constant index: sym=%v", sym)
 		}
 		if index > len(g.Constants) {
-			log.Fatalf("constant symbol name %q exceeds length of constant slice (%v)", sym, len(g.Constants))
+			functions.Log.Fatalf("constant symbol name %q exceeds length of constant slice (%v)", sym, len(g.Constants))
 		}
 		return fmt.Sprintf("%f", g.Constants[index]), nil
 	}
@@ -0,0 +1,342 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gmlewis/gep/functions"
+	"github.com/gmlewis/gep/gene"
+)
+
+// binaryVersion is the version of the encoding written by MarshalBinary.
+const binaryVersion = 1
+
+// binaryHomeotic is the flag marking an encoded genome with a homeotic gene.
+const binaryHomeotic = 1
+
+// MarshalBinary encodes the genome (its genes, with their symbols, constants,
+// head sizes, and mutation choices, linking function, homeotic gene, and
+// score) compactly: each function symbol is written once to a table, and each
+// symbol of a gene is written as a varint index into the table or the index of
+// an input or constant. Other state, such as the Options of a gene and the
+// functions to which a genome is bound (see Rebind), is not encoded.
+// It implements encoding.BinaryMarshaler.
+func (g *Genome) MarshalBinary() ([]byte, error) {
+	genes := g.Genes
+	flags := byte(0)
+	if g.Homeotic != nil {
+		genes = append(append([]*gene.Gene{}, g.Genes...), g.Homeotic)
+		flags |= binaryHomeotic
+	}
+	var table []string
+	index := map[string]int{}
+	add := func(sym string) (int, error) {
+		if i, ok := index[sym]; ok {
+			return i, nil
+		}
+		if !functionSymbol(sym) {
+			return 0, fmt.Errorf("unable to encode symbol %q", sym)
+		}
+		index[sym] = len(table)
+		table = append(table, sym)
+		return index[sym], nil
+	}
+	link := 0
+	if g.LinkFunc != "" {
+		i, err := add(g.LinkFunc)
+		if err != nil {
+			return nil, err
+		}
+		link = i + 1
+	}
+	// Each symbol is encoded as 3*n plus 0 for function n of the table,
+	// 1 for input dn, or 2 for constant cn.
+	encode := func(syms []string) ([]uint64, error) {
+		var r []uint64
+		for _, sym := range syms {
+			if t, n, err := gene.ParseTerminal(sym); err == nil {
+				kind := uint64(1)
+				if t == "c" {
+					kind = 2
+				}
+				r = append(r, 3*uint64(n)+kind)
+				continue
+			}
+			n, err := add(sym)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, 3*uint64(n))
+		}
+		return r, nil
+	}
+	codes := make([][]uint64, len(genes))
+	choiceCodes := make([][]uint64, len(genes))
+	headSizes := make([]int, len(genes))
+	numTerminals := make([]int, len(genes))
+	for i, v := range genes {
+		var choices []string
+		headSizes[i], choices, numTerminals[i] = v.Structure()
+		var err error
+		if codes[i], err = encode(v.Symbols); err == nil {
+			choiceCodes[i], err = encode(choices)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gene #%v: %v", i, err)
+		}
+	}
+
+	r := []byte{binaryVersion, flags}
+	r = binary.AppendUvarint(r, uint64(len(table)))
+	for _, sym := range table {
+		r = binary.AppendUvarint(r, uint64(len(sym)))
+		r = append(r, sym...)
+	}
+	r = binary.AppendUvarint(r, uint64(link))
+	r = binary.LittleEndian.AppendUint64(r, math.Float64bits(g.Score))
+	r = binary.AppendUvarint(r, uint64(len(genes)))
+	for i, v := range genes {
+		r = binary.AppendUvarint(r, uint64(len(codes[i])))
+		for _, c := range codes[i] {
+			r = binary.AppendUvarint(r, c)
+		}
+		r = binary.AppendUvarint(r, uint64(len(v.Constants)))
+		for _, c := range v.Constants {
+			r = binary.LittleEndian.AppendUint64(r, math.Float64bits(c))
+		}
+		r = binary.AppendUvarint(r, uint64(headSizes[i]))
+		r = binary.AppendUvarint(r, uint64(numTerminals[i]))
+		r = binary.AppendUvarint(r, uint64(len(choiceCodes[i])))
+		for _, c := range choiceCodes[i] {
+			r = binary.AppendUvarint(r, c)
+		}
+	}
+	return r, nil
+}
+
+// UnmarshalBinary decodes a genome encoded by MarshalBinary into g. Malformed
+// data, including a gene whose head size or number of terminals is
+// inconsistent with its symbols and mutation choices, returns an error
+// wrapping ErrCorruptData. It implements encoding.BinaryUnmarshaler.
+func (g *Genome) UnmarshalBinary(data []byte) error {
+	return g.UnmarshalBinaryWith(data, nil)
+}
+
+// UnmarshalBinaryWith is like UnmarshalBinary, but if fm is non-nil, it also
+// checks that every function symbol (and the linking function) is found in fm.
+func (g *Genome) UnmarshalBinaryWith(data []byte, fm functions.FuncMap) error {
+	d := &decoder{data: data}
+	version := d.byte()
+	if d.err == nil && version != binaryVersion {
+		return corrupt("unsupported version %v", version)
+	}
+	flags := d.byte()
+	table := make([]string, d.length())
+	for i := range table {
+		table[i] = string(d.bytes(d.length()))
+		if fm != nil && d.err == nil {
+			if _, ok := fm[table[i]]; !ok {
+				return fmt.Errorf("unknown function %q", table[i])
+			}
+		}
+		if s := table[i]; d.err == nil && !functionSymbol(s) {
+			return corrupt("invalid function symbol %q", s)
+		}
+	}
+	link := ""
+	if n := d.uvarint(); n > 0 {
+		if n > uint64(len(table)) {
+			return corrupt("linking function index %v out of range", n-1)
+		}
+		link = table[n-1]
+	}
+	score := math.Float64frombits(d.uint64())
+	decode := func() ([]string, error) {
+		syms := make([]string, d.length())
+		for j := range syms {
+			c := d.uvarint()
+			n := c / 3
+			switch c % 3 {
+			case 0:
+				if n >= uint64(len(table)) {
+					return nil, fmt.Errorf("function index %v out of range", n)
+				}
+				syms[j] = table[n]
+			case 1:
+				if n > math.MaxInt32 {
+					return nil, fmt.Errorf("input index %v out of range", n)
+				}
+				syms[j] = "d" + strconv.FormatUint(n, 10)
+			case 2:
+				// Each constant takes 8 bytes, so there are fewer than len(d.data).
+				if n >= uint64(len(d.data)) {
+					return nil, fmt.Errorf("constant index %v out of range", n)
+				}
+				syms[j] = "c" + strconv.FormatUint(n, 10)
+			}
+		}
+		return syms, nil
+	}
+	genes := make([]*gene.Gene, d.length())
+	for i := range genes {
+		syms, err := decode()
+		if err != nil {
+			return corrupt("gene #%v: %v", i, err)
+		}
+		constants := make([]float64, d.length())
+		for j := range constants {
+			constants[j] = math.Float64frombits(d.uint64())
+		}
+		headSize, numTerminals := d.uvarint(), d.uvarint()
+		choices, err := decode()
+		if err != nil {
+			return corrupt("gene #%v choices: %v", i, err)
+		}
+		if d.err != nil {
+			break
+		}
+		if len(syms) == 0 {
+			return corrupt("gene #%v has no symbols", i)
+		}
+		if !structured(syms, headSize, numTerminals, choices) {
+			return corrupt("gene #%v: head size %v and %v terminals inconsistent with %v symbols and choices %q", i, headSize, numTerminals, len(syms), choices)
+		}
+		genes[i] = gene.NewWithStructure(strings.Join(syms, "."), int(headSize), choices, int(numTerminals))
+		if len(constants) < len(genes[i].Constants) {
+			return corrupt("gene #%v: %v constants provided but %v are referenced", i, len(constants), len(genes[i].Constants))
+		}
+		genes[i].Constants = constants
+	}
+	if d.err == nil && d.pos != len(d.data) {
+		d.err = corrupt("%v unexpected bytes after genome", len(d.data)-d.pos)
+	}
+	if d.err != nil {
+		return d.err
+	}
+	r := New(genes, link)
+	r.Score = score
+	if flags&binaryHomeotic != 0 {
+		if len(genes) == 0 {
+			return corrupt("homeotic genome has no homeotic gene")
+		}
+		r.Genes, r.Homeotic = genes[:len(genes)-1], genes[len(genes)-1]
+	}
+	*g = *r
+	return nil
+}
+
+// decoder reads the fields of an encoded genome, recording the first error.
+// After an error, every read returns zero.
+type decoder struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (d *decoder) fail(what string) {
+	if d.err == nil {
+		d.err = corrupt("truncated: invalid %v at byte %v", what, d.pos)
+	}
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil || d.pos >= len(d.data) {
+		d.fail("byte")
+		return 0
+	}
+	d.pos++
+	return d.data[d.pos-1]
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		d.fail("varint")
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+// length reads a count of items, each of which takes at least one byte.
+func (d *decoder) length() int {
+	n := d.uvarint()
+	if n > uint64(len(d.data)-d.pos) {
+		d.fail("length")
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil || n > len(d.data)-d.pos {
+		d.fail("bytes")
+		return nil
+	}
+	d.pos += n
+	return d.data[d.pos-n : d.pos]
+}
+
+func (d *decoder) uint64() uint64 {
+	b := d.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// functionSymbol reports whether sym may be encoded as a function symbol: it
+// is neither empty nor a terminal, and it holds no dot, which separates the
+// symbols of a gene.
+func functionSymbol(sym string) bool {
+	return sym != "" && sym[0:1] != "d" && sym[0:1] != "c" && !strings.Contains(sym, ".")
+}
+
+// structured reports whether the gene with the given symbols may have the
+// given head size, number of terminals, and mutation choices (as returned by
+// gene.Structure): either there are no choices and the gene is as created by
+// gene.New, whose structure is unknown, or the head lies within the gene and
+// the choices begin with the numTerminals distinct terminals. Unless there is
+// a single choice, there are at least two distinct choices, so that a
+// mutation can always change a symbol.
+func structured(syms []string, headSize, numTerminals uint64, choices []string) bool {
+	if len(choices) == 0 {
+		_, _, n := gene.New(strings.Join(syms, ".")).Structure()
+		return headSize == 0 && numTerminals == uint64(n)
+	}
+	if t := terminals(choices); headSize < 1 || headSize > uint64(len(syms)) || t < 0 || uint64(t) != numTerminals {
+		return false
+	}
+	distinct := map[string]bool{}
+	for _, sym := range choices {
+		distinct[sym] = true
+	}
+	return len(distinct) >= 2 || len(choices) == 1
+}
+
+// terminals returns the number of terminals leading the choices of a gene, or
+// -1 if any terminal follows a function or is repeated (as terminals never are).
+func terminals(choices []string) int {
+	n := 0
+	seen := map[string]bool{}
+	for i, sym := range choices {
+		if _, _, err := gene.ParseTerminal(sym); err == nil {
+			if n < i || seen[sym] {
+				return -1
+			}
+			seen[sym] = true
+			n++
+		}
+	}
+	return n
+}
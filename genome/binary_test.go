@@ -0,0 +1,137 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gmlewis/gep/functions"
+	bn "github.com/gmlewis/gep/functions/bool_nodes"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+// corruptEncodings are encodings of the genome +.d0.d1 linked by + (whose
+// encoded gene is 3, 0, 1, 4, 0 followed by its head size, number of
+// terminals, and choices) that UnmarshalBinary must reject.
+var corruptEncodings = [][]byte{
+	// An unsupported version.
+	{2, 0, 1, 1, '+', 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3, 0, 1, 4, 0, 1, 2, 3, 1, 4, 0},
+	// No choices, with head size 0 (on which Mutate divides) but otherwise
+	// unlike a gene created by gene.New, which has 2 terminals.
+	{1, 0, 1, 1, '+', 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3, 0, 1, 4, 0, 0, 1, 0},
+	// No choices, with a head size.
+	{1, 0, 1, 1, '+', 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3, 0, 1, 4, 0, 1, 2, 0},
+	// Choices with head size 0.
+	{1, 0, 1, 1, '+', 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3, 0, 1, 4, 0, 0, 2, 3, 1, 4, 0},
+	// A head size beyond the gene.
+	{1, 0, 1, 1, '+', 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3, 0, 1, 4, 0, 4, 2, 3, 1, 4, 0},
+	// More terminals than lead the choices.
+	{1, 0, 1, 1, '+', 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3, 0, 1, 4, 0, 1, 3, 3, 1, 4, 0},
+	// A repeated terminal.
+	{1, 0, 1, 1, '+', 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3, 0, 1, 4, 0, 1, 2, 3, 1, 1, 0},
+	// Two choices of the same function, which a mutation could never change.
+	{1, 0, 1, 1, '+', 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3, 0, 1, 4, 0, 1, 0, 2, 0, 0},
+	// A function symbol holding a dot, which would split the gene.
+	{1, 0, 1, 2, '+', '.', 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3, 0, 1, 4, 0, 0, 2, 0},
+	// A reference to c1000 (whose constants would not fit in the encoding).
+	{1, 0, 1, 1, '+', 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3, 0, 1, 0xba, 0x17, 0, 0, 2, 0},
+}
+
+func TestMarshalBinary(t *testing.T) {
+	boolean := newGenome("Or", "And.Not.d0.d1.d2", "Nor.d1.d0.d0")
+	homeotic := NewHomeotic([]*gene.Gene{gene.New("*.d0.d1"), gene.New("Sin.d0")}, gene.New("+.d0.d1"))
+	big := benchGenome(40)
+	big.Score = 123.5
+	tests := []struct {
+		g  *Genome
+		fm functions.FuncMap
+	}{
+		{g: big, fm: mn.Math},
+		{g: newConstGene("+.*.c1.c0.d0", 2, -1.25), fm: mn.Math},
+		{g: boolean, fm: bn.BoolAllGates},
+		{g: homeotic},
+	}
+	in := []float64{0.5, 1.5, -2, 3, 0.25}
+	for _, test := range tests {
+		data, err := test.g.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary(%v) = %v", test.g, err)
+		}
+		var got Genome
+		if err := got.UnmarshalBinaryWith(data, test.fm); err != nil {
+			t.Fatalf("UnmarshalBinaryWith(%v) = %v", test.g, err)
+		}
+		if got.String() != test.g.String() || got.LinkFunc != test.g.LinkFunc || got.Score != test.g.Score {
+			t.Errorf("round trip = %v (link %q, score %v), want %v (link %q, score %v)", got, got.LinkFunc, got.Score, test.g, test.g.LinkFunc, test.g.Score)
+		}
+		for i, v := range got.Genes {
+			if !reflect.DeepEqual(v.Constants, test.g.Genes[i].Constants) && len(v.Constants)+len(test.g.Genes[i].Constants) > 0 {
+				t.Errorf("round trip gene #%v constants = %v, want %v", i, v.Constants, test.g.Genes[i].Constants)
+			}
+		}
+		if test.fm == nil || test.fm["+"] != nil {
+			if a, b := got.EvalMath(in), test.g.EvalMath(in); a != b {
+				t.Errorf("round trip EvalMath = %v, want %v", a, b)
+			}
+		}
+		for i, v := range got.Genes {
+			if err := gene.CheckEqual(v, test.g.Genes[i]); err != nil {
+				t.Errorf("round trip gene #%v: %v", i, err)
+			}
+		}
+		var plain Genome
+		if err := plain.UnmarshalBinary(data); err != nil || plain.String() != test.g.String() {
+			t.Errorf("UnmarshalBinary = (%v, %v), want %v", plain, err, test.g)
+		}
+		// Every truncation is an error (and never a panic).
+		for n := 0; n < len(data); n++ {
+			if err := new(Genome).UnmarshalBinary(data[:n]); !errors.Is(err, ErrCorruptData) {
+				t.Errorf("UnmarshalBinary of %v of %v bytes = %v, want error matching %v", n, len(data), err, ErrCorruptData)
+			}
+		}
+		if err := new(Genome).UnmarshalBinary(append(data, 0)); !errors.Is(err, ErrCorruptData) {
+			t.Errorf("UnmarshalBinary with trailing byte = %v, want error matching %v", err, ErrCorruptData)
+		}
+	}
+
+	// A decoded random genome may be evolved further.
+	data, _ := big.MarshalBinary()
+	var decoded Genome
+	if err := decoded.UnmarshalBinaryWith(data, mn.Math); err != nil {
+		t.Fatalf("UnmarshalBinaryWith(%v) = %v", big, err)
+	}
+	rng := functions.NewRNG(1)
+	for i := 0; i < 100; i++ {
+		decoded.MutateWith(rng, 1)
+		if err := decoded.Validate(mn.Math); err != nil {
+			t.Fatalf("decoded genome after %v mutations: Validate = %v", i+1, err)
+		}
+	}
+	if got, want := decoded.SymbolCount("+"), decoded.SymbolCount("+"); got != want || decoded.SymbolMap == nil {
+		t.Errorf("decoded SymbolCount(+) = %v then %v with SymbolMap %v, want it cached", got, want, decoded.SymbolMap)
+	}
+
+	for i, data := range corruptEncodings {
+		if err := new(Genome).UnmarshalBinary(data); !errors.Is(err, ErrCorruptData) {
+			t.Errorf("UnmarshalBinary of corrupt encoding #%v = %v, want error matching %v", i, err, ErrCorruptData)
+		}
+	}
+
+	data, _ = boolean.MarshalBinary()
+	if err := new(Genome).UnmarshalBinaryWith(data, mn.Math); err == nil {
+		t.Errorf("UnmarshalBinaryWith boolean genome and mn.Math = nil, want unknown function error")
+	}
+	js, err := json.Marshal(big)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := big.MarshalBinary(); len(data)*3 > len(js) {
+		t.Errorf("MarshalBinary is %v bytes, want under a third of the %v bytes of JSON", len(data), len(js))
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gmlewis/gep/functions"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+// Builder assembles a genome from the Karva heads of its genes, which makes
+// it convenient to construct genomes by hand in tests and examples:
+//
+//	g, err := genome.Build().Gene("+ d0 d1").Gene("* d2 d0").Link("+").Genome()
+//
+// Errors (such as unknown symbols) are reported by Genome.
+type Builder struct {
+	fm        functions.FuncMap
+	heads     [][]string
+	constants [][]float64
+	link      string
+	err       error
+}
+
+// Build returns a new Builder using the mn.Math function map and the
+// "+" linking function.
+func Build() *Builder {
+	return &Builder{fm: mn.Math, link: "+"}
+}
+
+// Funcs sets the function map used to parse the genes and linking function.
+func (b *Builder) Funcs(fm functions.FuncMap) *Builder {
+	b.fm = fm
+	return b
+}
+
+// Gene adds a gene whose head holds the given symbols, separated by spaces
+// (or dots, as in Karva notation). The values of the constants c0, c1, ...
+// referenced by the gene may optionally be provided.
+func (b *Builder) Gene(head string, constants ...float64) *Builder {
+	syms := strings.FieldsFunc(head, func(r rune) bool { return r == ' ' || r == '\t' || r == '.' })
+	if len(syms) == 0 && b.err == nil {
+		b.err = invalidGene(fmt.Sprintf("gene #%v", len(b.heads)), errors.New("empty head"))
+	}
+	b.heads = append(b.heads, syms)
+	b.constants = append(b.constants, constants)
+	return b
+}
+
+// Link sets the linking function used to combine the genes.
+func (b *Builder) Link(sym string) *Builder {
+	b.link = sym
+	return b
+}
+
+// Genome assembles and validates the genome. All genes share the head size
+// of the longest head (shorter heads are padded with d0) and a tail sized
+// for the largest arity of the functions used, which is filled with d0.
+// The genes know their head size (see gene.NewWithHead), so the genome may be
+// mutated and evolved like a random one.
+func (b *Builder) Genome() (*Genome, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.heads) == 0 {
+		return nil, errNoGenes
+	}
+	headSize, maxArity := 0, 1
+	for i, head := range b.heads {
+		if len(head) > headSize {
+			headSize = len(head)
+		}
+		for _, sym := range head {
+			if f, ok := b.fm[sym]; ok {
+				if f.Terminals() > maxArity {
+					maxArity = f.Terminals()
+				}
+				continue
+			}
+			if _, _, err := gene.ParseTerminal(sym); err != nil {
+				return nil, invalidGene(fmt.Sprintf("gene #%v", i), err)
+			}
+		}
+	}
+	tailSize := headSize*(maxArity-1) + 1
+	genes := make([]*gene.Gene, len(b.heads))
+	for i, head := range b.heads {
+		syms := append([]string{}, head...)
+		for len(syms) < headSize+tailSize {
+			syms = append(syms, "d0")
+		}
+		g := gene.NewWithHead(strings.Join(syms, "."), headSize, b.fm)
+		if len(b.constants[i]) > 0 {
+			if len(b.constants[i]) < len(g.Constants) {
+				return nil, invalidGene(fmt.Sprintf("gene #%v", i), fmt.Errorf("%v constants provided but %v are referenced", len(b.constants[i]), len(g.Constants)))
+			}
+			g.Constants = b.constants[i]
+		}
+		genes[i] = g
+	}
+	r := New(genes, b.link)
+	if err := r.Validate(b.fm); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// FromSymbols creates a new genome from the full list of symbols (head and
+// tail) of each of its genes, as returned by Symbols, the head size of each
+// gene, as returned by HeadSizes, and the linking function. fm is the map of
+// available functions (such as those used to evolve the genome), so that the
+// genome may be mutated and evolved (see gene.NewWithHead). An error is
+// returned if a symbol is unknown, a head size is out of range, a function
+// lies within the tail, or the expression of a gene does not fit within it.
+func FromSymbols(genes [][]string, headSizes []int, linkFunc string, fm functions.FuncMap) (*Genome, error) {
+	if len(genes) == 0 {
+		return nil, errNoGenes
+	}
+	if len(headSizes) != len(genes) {
+		return nil, fmt.Errorf("%w: %v head sizes for %v genes", ErrInvalidGenome, len(headSizes), len(genes))
+	}
+	if _, ok := fm[linkFunc]; !ok {
+		return nil, missingLinkFunc(linkFunc)
+	}
+	r := make([]*gene.Gene, len(genes))
+	for i, syms := range genes {
+		if len(syms) == 0 {
+			return nil, invalidGene(fmt.Sprintf("gene #%v", i), errors.New("no symbols"))
+		}
+		headSize := headSizes[i]
+		if headSize < 1 || headSize > len(syms) {
+			return nil, invalidGene(fmt.Sprintf("gene #%v", i), fmt.Errorf("head size %v out of range for %v symbols", headSize, len(syms)))
+		}
+		for _, sym := range syms {
+			if _, ok := fm[sym]; ok {
+				continue
+			}
+			if _, _, err := gene.ParseTerminal(sym); err != nil {
+				return nil, invalidGene(fmt.Sprintf("gene #%v", i), err)
+			}
+		}
+		g := gene.NewWithHead(strings.Join(syms, "."), headSize, fm)
+		if err := g.Validate(fm); err != nil {
+			return nil, invalidGene(fmt.Sprintf("gene #%v", i), err)
+		}
+		r[i] = g
+	}
+	return New(r, linkFunc), nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gmlewis/gep/functions"
+	bn "github.com/gmlewis/gep/functions/bool_nodes"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+func TestBuilder(t *testing.T) {
+	g, err := Build().Gene("+ d0 d1").Gene("* d2 d0").Link("+").Genome()
+	if err != nil {
+		t.Fatalf("Build = %v", err)
+	}
+	// (d0+d1) + (d2*d0)
+	if got, want := g.EvalMath([]float64{2, 3, 5}), 15.0; got != want {
+		t.Errorf("%v EvalMath = %v, want %v", g, got, want)
+	}
+	if err := g.Validate(mn.Math); err != nil {
+		t.Errorf("%v Validate = %v", g, err)
+	}
+	if got, want := g.String(), "+.d0.d1.d0.d0.d0.d0|+|*.d2.d0.d0.d0.d0.d0"; got != want {
+		t.Errorf("Build = %q, want %q", got, want)
+	}
+
+	// Incomplete heads are completed by the tail, and constants may be supplied.
+	g, err = Build().Gene("Sub3 c0 Sqrt", 10).Link("*").Genome()
+	if err != nil {
+		t.Fatalf("Build = %v", err)
+	}
+	if got, want := g.EvalMath([]float64{4}), 10-2-4.0; got != want {
+		t.Errorf("%v EvalMath = %v, want %v", g, got, want)
+	}
+
+	// The genes know their head size, so the genome may be evolved.
+	rng := functions.NewRNG(1)
+	for i := 0; i < 100; i++ {
+		g.MutateWith(rng, 1)
+		if err := g.Validate(mn.Math); err != nil {
+			t.Fatalf("%v after %v mutations: Validate = %v", g, i+1, err)
+		}
+	}
+
+	g, err = Build().Funcs(bn.BoolAllGates).Gene("Nand d0 d1").Link("And").Genome()
+	if err != nil {
+		t.Fatalf("Build = %v", err)
+	}
+	if got := g.EvalBool([]bool{true, true}, bn.BoolAllGates); got {
+		t.Errorf("%v EvalBool(true, true) = %v, want false", g, got)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	tests := []struct {
+		b    *Builder
+		want string
+		is   error
+	}{
+		{b: Build().Gene("+ d0 Bogus"), want: `unknown symbol "Bogus"`, is: ErrInvalidGenome},
+		{b: Build().Gene("+ d0 dX"), want: `unable to parse terminal index "dX"`, is: ErrInvalidGenome},
+		{b: Build().Gene(""), want: "empty head", is: ErrInvalidGenome},
+		{b: Build(), want: "no genes", is: ErrInvalidGenome},
+		{b: Build().Gene("+ d0 d1").Link("Bogus"), want: "linking function", is: ErrMissingLinkFunc},
+		{b: Build().Gene("+ c0 c1", 1), want: "constants", is: ErrInvalidGenome},
+	}
+	for i, test := range tests {
+		g, err := test.b.Genome()
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: Genome() = (%v, %v), want error containing %q", i, g, err, test.want)
+		}
+		if !errors.Is(err, test.is) {
+			t.Errorf("%v: Genome() error %v does not match %v", i, err, test.is)
+		}
+	}
+}
+
+func TestFromSymbols(t *testing.T) {
+	fm := functions.FuncMap{"+": mn.Math["+"], "-": mn.Math["-"], "*": mn.Math["*"], "Sqrt": mn.Math["Sqrt"]}
+	want := newGenome("*", "+.d0.d1.d1.d0", "*.-.d0.d2.d0.d1.d0", "Sqrt.d1.d0")
+	g, err := FromSymbols(want.Symbols(), []int{2, 3, 1}, "*", fm)
+	if err != nil {
+		t.Fatalf("FromSymbols(%v) = %v", want.Symbols(), err)
+	}
+	if got := g.String(); got != want.String() {
+		t.Errorf("FromSymbols = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(g.LinearSymbols(), want.LinearSymbols()) {
+		t.Errorf("FromSymbols LinearSymbols = %v, want %v", g.LinearSymbols(), want.LinearSymbols())
+	}
+	in := []float64{3, 4, 5}
+	if got, want := g.EvalMath(in), want.EvalMath(in); got != want {
+		t.Errorf("FromSymbols EvalMath = %v, want %v", got, want)
+	}
+
+	rng := functions.NewRNG(1)
+	for i := 0; i < 100; i++ {
+		g.MutateWith(rng, 1)
+		if err := g.Validate(fm); err != nil {
+			t.Fatalf("FromSymbols genome %v after %v mutations: Validate = %v", g, i+1, err)
+		}
+	}
+
+	errTests := []struct {
+		name      string
+		genes     [][]string
+		headSizes []int
+		link      string
+	}{
+		{name: "no genes", link: "+"},
+		{name: "empty gene", genes: [][]string{{}}, headSizes: []int{1}, link: "+"},
+		{name: "missing head sizes", genes: [][]string{{"d0"}}, link: "+"},
+		{name: "zero head size", genes: [][]string{{"d0"}}, headSizes: []int{0}, link: "+"},
+		{name: "head size too large", genes: [][]string{{"d0"}}, headSizes: []int{2}, link: "+"},
+		{name: "unknown link", genes: [][]string{{"d0"}}, headSizes: []int{1}, link: "Bogus"},
+		{name: "unknown symbol", genes: [][]string{{"+", "d0", "Bogus"}}, headSizes: []int{1}, link: "+"},
+		{name: "bad terminal", genes: [][]string{{"+", "d0", "dX"}}, headSizes: []int{1}, link: "+"},
+		{name: "function in tail", genes: [][]string{{"+", "+", "d0", "d0"}}, headSizes: []int{1}, link: "+"},
+		{name: "function in tail of later gene", genes: [][]string{{"d0"}, {"*", "-", "-", "d0", "d0", "d1"}}, headSizes: []int{1, 2}, link: "+"},
+		{name: "incomplete expression", genes: [][]string{{"+", "+", "d0", "d0"}}, headSizes: []int{2}, link: "+"},
+	}
+	for _, test := range errTests {
+		want := ErrInvalidGenome
+		if test.name == "unknown link" {
+			want = ErrMissingLinkFunc
+		}
+		if g, err := FromSymbols(test.genes, test.headSizes, test.link, fm); !errors.Is(err, want) {
+			t.Errorf("%v: FromSymbols(%v) = (%v, %v), want error matching %v", test.name, test.genes, g, err, want)
+		}
+	}
+}
+
+func TestBuilderMutation(t *testing.T) {
+	g, err := Build().Gene("+ d0 d1").Gene("* d1 Sqrt").Genome()
+	if err != nil {
+		t.Fatalf("Build = %v", err)
+	}
+	rng := functions.NewRNG(1)
+	for i := 0; i < 1000; i++ {
+		g.MutateWith(rng, 1)
+		for _, v := range g.Genes {
+			for _, sym := range v.Symbols {
+				switch sym {
+				case "+", "*", "Sqrt", "d0", "d1":
+				default:
+					t.Fatalf("Build genome %v after %v mutations has symbol %q not used by the built genes", g, i+1, sym)
+				}
+			}
+		}
+	}
+}
+
+func TestFromSymbolsRoundTrip(t *testing.T) {
+	// The largest arity of mn.Math (4) exceeds that of the genes, whose head
+	// sizes cannot therefore be inferred from their lengths.
+	funcs := []gene.FuncWeight{{"+", 1}, {"-", 1}, {"*", 1}}
+	rng := functions.NewRNG(1)
+	for i := 0; i < 100; i++ {
+		genes := []*gene.Gene{gene.RandomNewWith(rng, 7, 8, 2, 1, funcs, nil), gene.RandomNewWith(rng, 3, 4, 2, 1, funcs, nil)}
+		want := New(genes, "+")
+		g, err := FromSymbols(want.Symbols(), want.HeadSizes(), "+", mn.Math)
+		if err != nil {
+			t.Fatalf("FromSymbols(%v, %v) = %v", want.Symbols(), want.HeadSizes(), err)
+		}
+		if got := g.String(); got != want.String() {
+			t.Errorf("FromSymbols = %q, want %q", got, want)
+		}
+		if got := g.HeadSizes(); !reflect.DeepEqual(got, []int{7, 3}) {
+			t.Errorf("FromSymbols HeadSizes = %v, want [7 3]", got)
+		}
+		g.MutateWith(rng, 5)
+		if err := g.Validate(mn.Math); err != nil {
+			t.Fatalf("FromSymbols genome %v after mutation: Validate = %v", g, err)
+		}
+	}
+}
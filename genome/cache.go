@@ -0,0 +1,59 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import "sync"
+
+// EvalCache memoizes the scores of genomes by their CanonicalHash so that
+// duplicate genomes within a generation (for example, copies made by
+// replication) are scored only once. It is safe for concurrent use; when
+// several goroutines evaluate the same genome at once, only one of them
+// calls the scoring function and the others wait for its result.
+// The cache grows without bound, so it is intended to be Reset each generation.
+type EvalCache struct {
+	mu      sync.Mutex
+	entries map[uint64]*cacheEntry
+}
+
+type cacheEntry struct {
+	once  sync.Once
+	score float64
+}
+
+// NewEvalCache returns a new, empty evaluation cache.
+func NewEvalCache() *EvalCache {
+	return &EvalCache{entries: map[uint64]*cacheEntry{}}
+}
+
+// Reset empties the cache.
+func (c *EvalCache) Reset() {
+	c.mu.Lock()
+	c.entries = map[uint64]*cacheEntry{}
+	c.mu.Unlock()
+}
+
+// Len returns the number of distinct genomes held in the cache.
+func (c *EvalCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Wrap returns a scoring function that returns the cached score of a genome
+// if one exists, and otherwise scores the genome with sf and caches the result.
+func (c *EvalCache) Wrap(sf ScoringFunc) ScoringFunc {
+	return func(g *Genome) float64 {
+		key := g.CanonicalHash()
+		c.mu.Lock()
+		e, ok := c.entries[key]
+		if !ok {
+			e = &cacheEntry{}
+			c.entries[key] = e
+		}
+		c.mu.Unlock()
+		e.once.Do(func() { e.score = sf(g) })
+		return e.score
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestEvalCache(t *testing.T) {
+	var calls int64
+	sf := func(g *Genome) float64 {
+		atomic.AddInt64(&calls, 1)
+		return g.EvalMath([]float64{2, 3})
+	}
+	distinct := []*Genome{
+		newGenome("+", "+.d0.d1"),
+		newGenome("+", "*.d0.d1"),
+		newGenome("+", "-.d0.d1"),
+		newGenome("+", "-.d1.d0"),
+	}
+	var pop []*Genome
+	for i := 0; i < 100; i++ {
+		pop = append(pop, distinct[i%len(distinct)].Dup())
+	}
+	pop = append(pop, newGenome("+", "+.d1.d0")) // Same expression as distinct[0]
+
+	cache := NewEvalCache()
+	for gen := 1; gen <= 2; gen++ {
+		c := make(chan *Genome)
+		for _, v := range pop {
+			go v.Evaluate(cache.Wrap(sf), c)
+		}
+		for range pop {
+			<-c
+		}
+		if got, want := atomic.LoadInt64(&calls), int64(gen*len(distinct)); got != want {
+			t.Errorf("generation %v: scoring function called %v times, want %v", gen, got, want)
+		}
+		if got, want := cache.Len(), len(distinct); got != want {
+			t.Errorf("generation %v: cache.Len = %v, want %v", gen, got, want)
+		}
+		for i, v := range pop {
+			if want := sf(v); v.Score != want {
+				t.Errorf("generation %v: genome #%v %v Score = %v, want %v", gen, i, v, v.Score, want)
+			}
+			atomic.AddInt64(&calls, -1)
+		}
+		cache.Reset()
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gmlewis/gep/functions"
+	bn "github.com/gmlewis/gep/functions/bool_nodes"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+// commutative lists the function symbols whose results do not depend on the order of their arguments.
+var commutative = map[string]bool{
+	"+": true, "*": true, "Add3": true, "Add4": true, "Mul3": true, "Mul4": true,
+	"Min2": true, "Min3": true, "Min4": true, "Max2": true, "Max3": true, "Max4": true,
+	"Avg2": true, "Avg3": true, "Avg4": true,
+	"And": true, "Or": true, "Nand": true, "Nor": true, "Xor": true, "Nxor": true,
+	"And3": true, "Or3": true, "Nand3": true, "Nor3": true, "Odd3": true, "Even3": true,
+	"And4": true, "Or4": true, "Nand4": true, "Nor4": true,
+}
+
+// canonicalNodes is the union of the math and boolean function maps. Their symbols
+// never conflict in arity, so either kind of genome can be decoded with it.
+var canonicalNodes = func() functions.FuncMap {
+	r := functions.FuncMap{}
+	for k, v := range bn.BoolAllGates {
+		r[k] = v
+	}
+	for k, v := range mn.Math {
+		r[k] = v
+	}
+	return r
+}()
+
+// CanonicalHash returns a hash of the expressed expression of the genome that
+// ignores the order of the operands of commutative functions (such as +, *, And,
+// and Or), so that for example "+.d0.d1" and "+.d1.d0" hash identically.
+// Non-commutative functions are left alone, non-coding regions are ignored,
+// constants are hashed by value, and when the linking function is commutative
+// the order of the genes is ignored as well. The homeotic gene (if any) is
+// hashed too, after the ADFs, whose order it depends upon.
+func (g *Genome) CanonicalHash() uint64 {
+	h, _ := g.canonical()
+	return h
+}
+
+// canonical returns the CanonicalHash of the genome along with its size: the
+// number of expressed nodes of its genes (and homeotic gene, if any). Since
+// the genes are decoded with canonicalNodes, the size is the same as the
+// CodingLength for math genomes, and is meaningful for boolean genomes too.
+func (g *Genome) canonical() (hash uint64, size int) {
+	genes := make([]string, len(g.Genes))
+	for i, v := range g.Genes {
+		tree := v.Tree(canonicalNodes)
+		genes[i], size = canonicalString(v, tree), size+treeSize(tree)
+	}
+	h := fnv.New64a()
+	if len(genes) > 1 && g.Homeotic == nil {
+		if commutative[g.LinkFunc] {
+			sort.Strings(genes)
+		}
+		h.Write([]byte(g.LinkFunc))
+	}
+	for _, s := range genes {
+		h.Write([]byte{0})
+		h.Write([]byte(s))
+	}
+	if g.Homeotic != nil {
+		tree := g.Homeotic.Tree(canonicalNodes)
+		size += treeSize(tree)
+		h.Write([]byte{1}) // Marks the homeotic gene, which no gene string contains.
+		h.Write([]byte(canonicalString(g.Homeotic, tree)))
+	}
+	return h.Sum64(), size
+}
+
+// treeSize returns the number of nodes of the expression tree n.
+func treeSize(n *gene.ExprNode) int {
+	if n == nil {
+		return 0
+	}
+	r := 1
+	for _, v := range n.Args {
+		r += treeSize(v)
+	}
+	return r
+}
+
+// DistinctSubtrees returns the number of distinct subtrees (including the
+// terminals) within the expressions of all the genes of the genome, where
+// subtrees are compared as by CanonicalHash. A count much smaller than the
+// total number of coding symbols indicates repeated subexpressions that could
+// be factored out into modules. The subtrees of the homeotic gene (if any) are
+// counted separately from those of the ADFs, since its terminals stand for
+// the results of the ADFs rather than for the inputs.
+func (g *Genome) DistinctSubtrees() int {
+	seen := map[string]bool{}
+	for _, v := range g.Genes {
+		subtreeStrings(v, v.Tree(canonicalNodes), seen)
+	}
+	if g.Homeotic == nil {
+		return len(seen)
+	}
+	homeotic := map[string]bool{}
+	subtreeStrings(g.Homeotic, g.Homeotic.Tree(canonicalNodes), homeotic)
+	return len(seen) + len(homeotic)
+}
+
+// subtreeStrings records in seen the canonical string of every subtree of the
+// expression tree n of gene g, and returns that of n itself.
+func subtreeStrings(g *gene.Gene, n *gene.ExprNode, seen map[string]bool) string {
+	if n == nil {
+		return ""
+	}
+	s := canonicalString(g, &gene.ExprNode{Symbol: n.Symbol})
+	if len(n.Args) > 0 {
+		args := make([]string, len(n.Args))
+		for i, v := range n.Args {
+			args[i] = subtreeStrings(g, v, seen)
+		}
+		if commutative[n.Symbol] {
+			sort.Strings(args)
+		}
+		s = n.Symbol + "(" + strings.Join(args, ",") + ")"
+	}
+	seen[s] = true
+	return s
+}
+
+// EqualCoding reports whether the genomes express identical expressions:
+// their genes have the same expression trees in order (with constants compared
+// by value), as do their homeotic genes (if any), and multigenic genomes have
+// the same linking function. Unlike a comparison of their Karva strings, it
+// ignores the non-coding regions of the genes, which do not affect the
+// result. Unlike CanonicalHash, it does not reorder the operands of
+// commutative functions.
+func (g *Genome) EqualCoding(other *Genome) bool {
+	if len(g.Genes) != len(other.Genes) || (len(g.Genes) > 1 && g.LinkFunc != other.LinkFunc) {
+		return false
+	}
+	if (g.Homeotic == nil) != (other.Homeotic == nil) {
+		return false
+	}
+	equal := func(a, b *gene.Gene) bool {
+		return exprString(a, a.Tree(canonicalNodes), false) == exprString(b, b.Tree(canonicalNodes), false)
+	}
+	for i, v := range g.Genes {
+		if !equal(v, other.Genes[i]) {
+			return false
+		}
+	}
+	return g.Homeotic == nil || equal(g.Homeotic, other.Homeotic)
+}
+
+// canonicalString renders the expression tree n of gene g with the arguments
+// of commutative functions sorted.
+func canonicalString(g *gene.Gene, n *gene.ExprNode) string {
+	return exprString(g, n, true)
+}
+
+// exprString renders the expression tree n of gene g with its constants
+// replaced by their values, and, if canonical, with the arguments of
+// commutative functions sorted.
+func exprString(g *gene.Gene, n *gene.ExprNode, canonical bool) string {
+	if n == nil {
+		return ""
+	}
+	if len(n.Args) == 0 {
+		if _, ok := canonicalNodes[n.Symbol]; !ok && len(n.Symbol) > 1 && n.Symbol[0:1] == "c" {
+			if index, err := strconv.Atoi(n.Symbol[1:]); err == nil && index < len(g.Constants) {
+				return strconv.FormatFloat(g.Constants[index], 'g', -1, 64)
+			}
+		}
+		return n.Symbol
+	}
+	args := make([]string, len(n.Args))
+	for i, v := range n.Args {
+		args[i] = exprString(g, v, canonical)
+	}
+	if canonical && commutative[n.Symbol] {
+		sort.Strings(args)
+	}
+	return n.Symbol + "(" + strings.Join(args, ",") + ")"
+}
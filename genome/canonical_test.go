@@ -0,0 +1,95 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"testing"
+
+	"github.com/gmlewis/gep/gene"
+)
+
+func newGenome(linkFunc string, genes ...string) *Genome {
+	var r []*gene.Gene
+	for _, v := range genes {
+		r = append(r, gene.New(v))
+	}
+	return New(r, linkFunc)
+}
+
+func TestCanonicalHash(t *testing.T) {
+	tests := []struct {
+		a, b *Genome
+		same bool
+	}{
+		{a: newGenome("+", "+.d0.d1"), b: newGenome("+", "+.d1.d0"), same: true},
+		{a: newGenome("+", "-.d0.d1"), b: newGenome("+", "-.d1.d0"), same: false},
+		{a: newGenome("+", "+.d0.d1.d0"), b: newGenome("+", "+.d0.d1.d1"), same: true}, // tails differ
+		{a: newGenome("+", "*.+.d2.d1.d0.d1.d2"), b: newGenome("+", "*.d2.+.d0.d1.d0"), same: true},
+		{a: newGenome("+", "d0", "d1"), b: newGenome("+", "d1", "d0"), same: true},
+		{a: newGenome("-", "d0", "d1"), b: newGenome("-", "d1", "d0"), same: false},
+		{a: newGenome("Or", "And.d0.d1"), b: newGenome("Or", "And.d1.d0"), same: true},
+		{a: newGenome("+", "+.d0.d1"), b: newGenome("+", "*.d0.d1"), same: false},
+	}
+	for i, test := range tests {
+		if got := test.a.CanonicalHash() == test.b.CanonicalHash(); got != test.same {
+			t.Errorf("%v: CanonicalHash(%q) == CanonicalHash(%q) is %v, want %v", i, test.a, test.b, got, test.same)
+		}
+	}
+	c1, c2 := newConstGene("+.d0.c0", 1), newConstGene("+.d0.c0", 2)
+	if c1.CanonicalHash() == c2.CanonicalHash() {
+		t.Errorf("CanonicalHash ignores constant values: %q", c1)
+	}
+}
+
+func TestEqualCoding(t *testing.T) {
+	tests := []struct {
+		a, b *Genome
+		want bool
+	}{
+		{a: newGenome("+", "+.d0.d1.d0.d0", "*.d1.d1.d0"), b: newGenome("+", "+.d0.d1.d1.d1", "*.d1.d1.d1"), want: true}, // tails differ
+		{a: newGenome("+", "d0.d1.d1"), b: newGenome("*", "d0.d0.d0"), want: true},                                       // one gene: linking is unused
+		{a: newGenome("+", "d0", "d1"), b: newGenome("*", "d0", "d1"), want: false},
+		{a: newGenome("+", "+.d0.d1"), b: newGenome("+", "+.d1.d0"), want: false}, // operands reordered
+		{a: newGenome("+", "d0", "d1"), b: newGenome("+", "d1", "d0"), want: false},
+		{a: newGenome("+", "d0", "d1"), b: newGenome("+", "d0"), want: false},
+		{a: newGenome("+", "+.d0.d1"), b: newGenome("+", "*.d0.d1"), want: false},
+	}
+	for i, test := range tests {
+		if got := test.a.EqualCoding(test.b); got != test.want {
+			t.Errorf("%v: EqualCoding(%q, %q) = %v, want %v", i, test.a, test.b, got, test.want)
+		}
+		if got := test.b.EqualCoding(test.a); got != test.want {
+			t.Errorf("%v: EqualCoding(%q, %q) = %v, want %v", i, test.b, test.a, got, test.want)
+		}
+	}
+	if c1, c2 := newConstGene("+.d0.c0.c1", 1, 2), newConstGene("+.d0.c0.c0", 1, 3); !c1.EqualCoding(c2) {
+		t.Errorf("EqualCoding(%q, %q) = false, want true (unused constants differ)", c1, c2)
+	}
+	if c1, c2 := newConstGene("+.d0.c0", 1), newConstGene("+.d0.c0", 2); c1.EqualCoding(c2) {
+		t.Errorf("EqualCoding ignores constant values: %q", c1)
+	}
+}
+
+func TestDistinctSubtrees(t *testing.T) {
+	acrossGenes := newGenome("+", "*.d0.d1.d0.d0", "*.d1.d0.d0.d0", "+.c0.c1.d0.d0")
+	acrossGenes.Genes[2].Constants = []float64{2, 2}
+	tests := []struct {
+		name string
+		g    *Genome
+		want int
+	}{
+		{name: "single terminal", g: newGenome("+", "d0.d1.d1"), want: 1},
+		{name: "no repetition", g: newGenome("+", "+.*.d2.d0.d1.d1.d1"), want: 5},
+		// (d0*d1)+(d1*d0): 7 coding symbols, but only +, d0*d1, d0, and d1.
+		{name: "repeated subexpression", g: newGenome("+", "+.*.*.d0.d1.d1.d0"), want: 4},
+		// Two genes compute d0*d1, and the equal constants c0 and c1 count once.
+		{name: "across genes", g: acrossGenes, want: 5},
+	}
+	for _, test := range tests {
+		if got := test.g.DistinctSubtrees(); got != test.want {
+			t.Errorf("%v: DistinctSubtrees(%v) = %v, want %v", test.name, test.g, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Dataset is a collection of fitness cases for floating-point genomes.
+// Each row of Inputs is evaluated by the genome and compared against
+// the corresponding value in Targets.
+type Dataset struct {
+	Inputs  [][]float64
+	Targets []float64
+	// Weights (optional) gives the relative importance of each row.
+	// If nil, all rows are weighted equally.
+	Weights []float64
+	// Config (optional) controls how the scoring helpers score the rows.
+	// If nil, DefaultScoringConfig is used.
+	Config *ScoringConfig
+}
+
+// ScoringConfig controls the behavior of the scoring helpers (RMSE, Accuracy,
+// LogLoss, RSquared, StreamRMSE, and CaseErrors). Its zero value is safe: each
+// row whose prediction is NaN or infinite contributes the default penalty of
+// 1e6 as its error (or loss), and counts as misclassified, rather than
+// poisoning the aggregate.
+type ScoringConfig struct {
+	// AllowNonFinite disables the penalty, so that a non-finite prediction is
+	// scored as is (and so typically poisons the aggregate).
+	AllowNonFinite bool
+	// NonFinitePenalty is the error of a non-finite row unless AllowNonFinite
+	// is set. If zero, the default of 1e6 is used.
+	NonFinitePenalty float64
+	// MaxNodes, if positive, caps the number of nodes that may be evaluated
+	// per row, which guards against pathological genomes: a genome whose
+	// EvaluatedNodes exceed it is not evaluated at all (see CheckNodes), and
+	// the evaluation of any other genome is cut short as soon as it exceeds
+	// it (see Genome.EvalMathLimit, which counts each call of an ADF). Either
+	// way, the genome scores 0.
+	MaxNodes int
+}
+
+// defaultNonFinitePenalty is the NonFinitePenalty used when none is set.
+const defaultNonFinitePenalty = 1e6
+
+// DefaultScoringConfig is the ScoringConfig used when a Dataset has none.
+var DefaultScoringConfig = ScoringConfig{
+	NonFinitePenalty: defaultNonFinitePenalty,
+}
+
+// Len returns the number of rows (fitness cases) in the dataset.
+func (d *Dataset) Len() int {
+	return len(d.Targets)
+}
+
+// Shuffle reproducibly permutes the rows of the dataset using a random number
+// generator seeded with seed. The inputs, targets, and weights (if any) of each
+// row are moved together, so each row remains intact.
+func (d *Dataset) Shuffle(seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(d.Len(), func(i, j int) {
+		d.Inputs[i], d.Inputs[j] = d.Inputs[j], d.Inputs[i]
+		d.Targets[i], d.Targets[j] = d.Targets[j], d.Targets[i]
+		if d.Weights != nil {
+			d.Weights[i], d.Weights[j] = d.Weights[j], d.Weights[i]
+		}
+	})
+}
+
+// DomainCoverage returns the fraction of the rows of testInputs that lie
+// within the domain of the dataset: that is, each of whose inputs lies within
+// the range of that input over the rows of the dataset (ignoring NaNs). Models
+// are most likely to fail on the remaining rows, which require extrapolation.
+// A row with more inputs than the dataset lies outside of its domain.
+// If testInputs is empty, DomainCoverage returns NaN.
+func (d *Dataset) DomainCoverage(testInputs [][]float64) float64 {
+	if len(testInputs) == 0 {
+		return math.NaN()
+	}
+	var lo, hi []float64
+	for _, in := range d.Inputs {
+		for i, v := range in {
+			if i >= len(lo) {
+				lo, hi = append(lo, math.Inf(1)), append(hi, math.Inf(-1))
+			}
+			if !math.IsNaN(v) {
+				lo[i], hi[i] = math.Min(lo[i], v), math.Max(hi[i], v)
+			}
+		}
+	}
+	inside := 0
+	for _, in := range testInputs {
+		ok := len(in) <= len(lo)
+		for i := 0; ok && i < len(in); i++ {
+			ok = in[i] >= lo[i] && in[i] <= hi[i]
+		}
+		if ok {
+			inside++
+		}
+	}
+	return float64(inside) / float64(len(testInputs))
+}
+
+// CheckNodes returns an error wrapping ErrTooManyNodes if the EvaluatedNodes
+// of the genome exceed the MaxNodes of c. This is a quick check made before
+// evaluation; the scoring helpers also enforce MaxNodes during evaluation.
+func (c *ScoringConfig) CheckNodes(g *Genome) error {
+	if n := g.EvaluatedNodes(); c.MaxNodes > 0 && n > c.MaxNodes {
+		return fmt.Errorf("%w: genome evaluates %v nodes per row, exceeding the maximum of %v", ErrTooManyNodes, n, c.MaxNodes)
+	}
+	return nil
+}
+
+// eval evaluates the genome with the inputs in, enforcing the MaxNodes of c
+// (if any) with EvalMathLimit. Only a genome exceeding MaxNodes returns an
+// error; any other failure of evaluation yields NaN, as with EvalMath.
+func (c *ScoringConfig) eval(g *Genome, in []float64) (float64, error) {
+	if c.MaxNodes <= 0 {
+		return g.EvalMath(in), nil
+	}
+	r, err := g.EvalMathLimit(in, c.MaxNodes)
+	switch {
+	case errors.Is(err, ErrTooManyNodes):
+		return 0, err
+	case err != nil:
+		return math.NaN(), nil
+	}
+	return r, nil
+}
+
+// config returns the scoring configuration of the dataset.
+func (d *Dataset) config() *ScoringConfig {
+	if d.Config == nil {
+		return &DefaultScoringConfig
+	}
+	return d.Config
+}
+
+// penalized reports whether prediction v must be replaced by the penalty of c.
+func (c *ScoringConfig) penalized(v float64) bool {
+	return !c.AllowNonFinite && !isFinite(v)
+}
+
+// penalty returns the error of a penalized prediction.
+func (c *ScoringConfig) penalty() float64 {
+	if c.NonFinitePenalty == 0 {
+		return defaultNonFinitePenalty
+	}
+	return c.NonFinitePenalty
+}
+
+// weight returns the weight of row i (1 if the dataset has no weights).
+func (d *Dataset) weight(i int) float64 {
+	if d.Weights == nil {
+		return 1.0
+	}
+	return d.Weights[i]
+}
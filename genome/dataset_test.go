@@ -0,0 +1,83 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestShuffle(t *testing.T) {
+	newDataset := func() *Dataset {
+		d := &Dataset{}
+		for i := 0; i < 20; i++ {
+			v := float64(i)
+			d.Inputs = append(d.Inputs, []float64{v, -v})
+			d.Targets = append(d.Targets, 10*v)
+			d.Weights = append(d.Weights, 100*v)
+		}
+		return d
+	}
+	d1, d2, d3 := newDataset(), newDataset(), newDataset()
+	d1.Shuffle(42)
+	d2.Shuffle(42)
+	d3.Shuffle(7)
+	if !reflect.DeepEqual(d1, d2) {
+		t.Errorf("Shuffle with the same seed produced different datasets:\n%v\n%v", d1.Targets, d2.Targets)
+	}
+	if reflect.DeepEqual(d1.Targets, d3.Targets) {
+		t.Errorf("Shuffle with different seeds produced the same permutation: %v", d1.Targets)
+	}
+	if reflect.DeepEqual(d1.Targets, newDataset().Targets) {
+		t.Errorf("Shuffle left the rows in order: %v", d1.Targets)
+	}
+	seen := map[float64]bool{}
+	for i, in := range d1.Inputs {
+		v := in[0]
+		if in[1] != -v || d1.Targets[i] != 10*v || d1.Weights[i] != 100*v {
+			t.Errorf("row %v = (%v, %v, %v), want inputs, target, and weight of row %v", i, in, d1.Targets[i], d1.Weights[i], v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != 20 {
+		t.Errorf("Shuffle lost rows: %v distinct rows remain, want 20", len(seen))
+	}
+
+	unweighted := newDataset()
+	unweighted.Weights = nil
+	unweighted.Shuffle(42)
+	if !reflect.DeepEqual(unweighted.Targets, d1.Targets) || unweighted.Weights != nil {
+		t.Errorf("Shuffle of unweighted dataset = %v, %v, want %v, nil", unweighted.Targets, unweighted.Weights, d1.Targets)
+	}
+}
+
+func TestDomainCoverage(t *testing.T) {
+	d := &Dataset{
+		Inputs:  [][]float64{{0, 10}, {5, 20}, {2, math.NaN()}, {-1, 15}},
+		Targets: []float64{0, 0, 0, 0},
+	}
+	tests := []struct {
+		name string
+		in   [][]float64
+		want float64
+	}{
+		{name: "inside", in: [][]float64{{0, 12}}, want: 1},
+		{name: "on the boundary", in: [][]float64{{-1, 20}, {5, 10}}, want: 1},
+		{name: "outside", in: [][]float64{{6, 12}}, want: 0},
+		{name: "one feature outside", in: [][]float64{{2, 25}}, want: 0},
+		{name: "NaN", in: [][]float64{{math.NaN(), 12}}, want: 0},
+		{name: "extra feature", in: [][]float64{{0, 12, 1}}, want: 0},
+		{name: "mixed", in: [][]float64{{0, 12}, {6, 12}, {1, 11}, {-2, 30}}, want: 0.5},
+	}
+	for _, test := range tests {
+		if got := d.DomainCoverage(test.in); got != test.want {
+			t.Errorf("%v: DomainCoverage(%v) = %v, want %v", test.name, test.in, got, test.want)
+		}
+	}
+	if got := d.DomainCoverage(nil); !math.IsNaN(got) {
+		t.Errorf("DomainCoverage(nil) = %v, want NaN", got)
+	}
+}
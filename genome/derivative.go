@@ -0,0 +1,332 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+// Derivative returns a new genome whose floating-point expression is the
+// partial derivative of that of g with respect to the input d<inputIndex>,
+// found by symbolic differentiation of the expressed (linked) expression.
+// The result has a single gene, whose constants include those of g.
+// Differentiation is supported for the arithmetic functions (such as +, -,
+// *, /, Add3, Mul4, and Avg2), powers and roots (Pow, Sqrt, X2, ..., X5, Inv),
+// exponentials and logarithms (Exp, Pow10, Ln, Log), and the trigonometric and
+// hyperbolic functions Sin, Cos, Tan, Sinh, Cosh, and Tanh. Any function may
+// appear in a subexpression that does not depend upon the input; otherwise
+// an unsupported (for example, non-differentiable) function is an error.
+func (g *Genome) Derivative(inputIndex int) (*Genome, error) {
+	if inputIndex < 0 {
+		return nil, fmt.Errorf("invalid input index %v", inputIndex)
+	}
+	d := &deriv{input: "d" + strconv.Itoa(inputIndex)}
+	expr, err := d.expression(g)
+	if err != nil {
+		return nil, err
+	}
+	dx, err := d.derive(expr)
+	if err != nil {
+		return nil, err
+	}
+	if dx == nil {
+		dx = d.constant(0)
+	}
+	r := gene.New(strings.Join(dx.Karva(), "."))
+	r.Constants = d.constants
+	result := NewAdditive([]*gene.Gene{r})
+	result.MissingPolicy = g.MissingPolicy
+	return result, nil
+}
+
+// deriv holds the state of a symbolic differentiation with respect to input.
+type deriv struct {
+	input     string
+	constants []float64
+	// params, if non-nil, records the constants of the genes, which are then
+	// kept distinct from each other and from the constants introduced by
+	// differentiation, so that they may be treated as parameters. The
+	// constant c<i> is then params[i], for i < len(params).
+	params []param
+}
+
+// param identifies a constant of a gene.
+type param struct {
+	g     *gene.Gene
+	index int
+}
+
+// constant returns a terminal for the constant v.
+func (d *deriv) constant(v float64) *gene.ExprNode {
+	for i := len(d.params); i < len(d.constants); i++ {
+		if c := d.constants[i]; c == v || (math.IsNaN(c) && math.IsNaN(v)) {
+			return &gene.ExprNode{Symbol: "c" + strconv.Itoa(i)}
+		}
+	}
+	d.constants = append(d.constants, v)
+	return &gene.ExprNode{Symbol: "c" + strconv.Itoa(len(d.constants)-1)}
+}
+
+// param returns a terminal for the constant index of gene g as a parameter.
+func (d *deriv) param(g *gene.Gene, index int) *gene.ExprNode {
+	p := param{g: g, index: index}
+	i := 0
+	for i < len(d.params) && d.params[i] != p {
+		i++
+	}
+	if i == len(d.params) {
+		d.params = append(d.params, p)
+		d.constants = append(d.constants, g.Constants[index])
+	}
+	return &gene.ExprNode{Symbol: "c" + strconv.Itoa(i)}
+}
+
+// expression returns the whole expression of g as a single tree, whose
+// constants are those of d.
+func (d *deriv) expression(g *Genome) (*gene.ExprNode, error) {
+	if len(g.Genes) == 0 {
+		return nil, errNoGenes
+	}
+	trees := make([]*gene.ExprNode, len(g.Genes))
+	for i, v := range g.Genes {
+		t, err := d.geneTree(v)
+		if err != nil {
+			return nil, fmt.Errorf("gene #%v: %v", i, err)
+		}
+		trees[i] = t
+	}
+	if g.Homeotic != nil {
+		t, err := d.geneTree(g.Homeotic)
+		if err != nil {
+			return nil, fmt.Errorf("homeotic gene: %v", err)
+		}
+		return substituteADFs(t, trees)
+	}
+	if f, ok := mn.Math[g.LinkFunc]; len(trees) > 1 && (!ok || f.Terminals() != 2) {
+		return nil, missingLinkFunc(g.LinkFunc)
+	}
+	result := trees[0]
+	for _, t := range trees[1:] {
+		result = node(g.LinkFunc, result, t)
+	}
+	return result, nil
+}
+
+// geneTree returns the expression tree of gene g with its constants replaced
+// by those of d.
+func (d *deriv) geneTree(g *gene.Gene) (*gene.ExprNode, error) {
+	var convert func(n *gene.ExprNode) (*gene.ExprNode, error)
+	convert = func(n *gene.ExprNode) (*gene.ExprNode, error) {
+		if f, ok := mn.Math[n.Symbol]; ok {
+			if len(n.Args) != f.Terminals() {
+				return nil, fmt.Errorf("gene %q is not a complete expression", g)
+			}
+			args := make([]*gene.ExprNode, len(n.Args))
+			for i, v := range n.Args {
+				a, err := convert(v)
+				if err != nil {
+					return nil, err
+				}
+				args[i] = a
+			}
+			return node(n.Symbol, args...), nil
+		}
+		kind, index, err := gene.ParseTerminal(n.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		if kind == "c" {
+			if index >= len(g.Constants) {
+				return nil, fmt.Errorf("constant %v not found in gene %q", n.Symbol, g)
+			}
+			if d.params != nil {
+				return d.param(g, index), nil
+			}
+			return d.constant(g.Constants[index]), nil
+		}
+		return &gene.ExprNode{Symbol: n.Symbol}, nil
+	}
+	root := g.Tree(mn.Math)
+	if root == nil {
+		return nil, fmt.Errorf("gene has no symbols")
+	}
+	return convert(root)
+}
+
+// substituteADFs replaces each input terminal d<k> of the homeotic tree t with the tree of ADF k.
+func substituteADFs(t *gene.ExprNode, adfs []*gene.ExprNode) (*gene.ExprNode, error) {
+	if len(t.Args) == 0 {
+		if t.Symbol[0:1] != "d" {
+			return t, nil
+		}
+		index, _ := strconv.Atoi(t.Symbol[1:])
+		if index >= len(adfs) {
+			return nil, fmt.Errorf("homeotic gene refers to missing ADF %v", t.Symbol)
+		}
+		return adfs[index], nil
+	}
+	args := make([]*gene.ExprNode, len(t.Args))
+	for i, v := range t.Args {
+		a, err := substituteADFs(v, adfs)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = a
+	}
+	return node(t.Symbol, args...), nil
+}
+
+// node returns a new expression node.
+func node(sym string, args ...*gene.ExprNode) *gene.ExprNode {
+	return &gene.ExprNode{Symbol: sym, Args: args}
+}
+
+// depends reports whether the expression n depends upon the input of d.
+func (d *deriv) depends(n *gene.ExprNode) bool {
+	if n.Symbol == d.input {
+		return true
+	}
+	for _, v := range n.Args {
+		if d.depends(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// derive returns the derivative of the expression n, or nil if it is zero.
+func (d *deriv) derive(n *gene.ExprNode) (*gene.ExprNode, error) {
+	if !d.depends(n) {
+		return nil, nil
+	}
+	if len(n.Args) == 0 {
+		return d.constant(1), nil
+	}
+	dargs := make([]*gene.ExprNode, len(n.Args))
+	for i, v := range n.Args {
+		da, err := d.derive(v)
+		if err != nil {
+			return nil, err
+		}
+		dargs[i] = da
+	}
+	u, du := n.Args[0], dargs[0]
+	switch n.Symbol {
+	case "Zero", "One", "Zero2", "One2", "Pi", "E": // constant functions
+		return nil, nil
+	case "+", "-", "Add3", "Add4", "Sub3", "Sub4", "Avg2", "Avg3", "Avg4":
+		// The derivative of a linear combination is the same combination of the derivatives.
+		for i, v := range dargs {
+			if v == nil {
+				dargs[i] = d.constant(0)
+			}
+		}
+		return node(n.Symbol, dargs...), nil
+	case "*", "Mul3", "Mul4":
+		// Product rule: the sum over each factor of the product with that factor differentiated.
+		var result *gene.ExprNode
+		for i, v := range dargs {
+			if v == nil {
+				continue
+			}
+			factors := append([]*gene.ExprNode{}, n.Args...)
+			factors[i] = v
+			result = d.sum(result, node(n.Symbol, factors...))
+		}
+		return result, nil
+	case "/":
+		v, dv := n.Args[1], dargs[1]
+		var num *gene.ExprNode
+		if du != nil {
+			num = node("*", du, v)
+		}
+		if dv != nil {
+			num = d.difference(num, node("*", u, dv))
+		}
+		return node("/", num, node("*", v, v)), nil
+	case "Div3", "Div4":
+		// x0/x1/x2... is x0 divided by the product of the others.
+		return d.derive(node("/", u, node(mulOf[len(n.Args)-1], n.Args[1:]...)))
+	case "Neg":
+		return node("Neg", du), nil
+	case "Nop":
+		return du, nil
+	case "Inv":
+		return d.chain(du, node("Neg", node("Inv", node("X2", u)))), nil
+	case "Sqrt":
+		return d.chain(du, node("/", d.constant(0.5), node("Sqrt", u))), nil
+	case "X2":
+		return d.chain(du, node("*", d.constant(2), u)), nil
+	case "X3", "X4", "X5":
+		k := int(n.Symbol[1] - '0')
+		return d.chain(du, node("*", d.constant(float64(k)), node("X"+strconv.Itoa(k-1), u))), nil
+	case "Exp":
+		return d.chain(du, node("Exp", u)), nil
+	case "Pow10":
+		return d.chain(du, node("*", d.constant(math.Ln10), node("Pow10", u))), nil
+	case "Ln":
+		return d.chain(du, node("Inv", u)), nil
+	case "Log":
+		return d.chain(du, node("Inv", node("*", d.constant(math.Ln10), u))), nil
+	case "Pow":
+		v, dv := n.Args[1], dargs[1]
+		if dv == nil { // d(u^v) = v * u^(v-1) * du for v independent of the input.
+			return d.chain(du, node("*", v, node("Pow", u, node("-", v, d.constant(1))))), nil
+		}
+		// d(u^v) = u^v * (dv*ln(u) + v*du/u)
+		inner := node("*", dv, node("Ln", u))
+		if du != nil {
+			inner = node("+", inner, node("/", node("*", v, du), u))
+		}
+		return node("*", node("Pow", u, v), inner), nil
+	case "Sin":
+		return d.chain(du, node("Cos", u)), nil
+	case "Cos":
+		return d.chain(du, node("Neg", node("Sin", u))), nil
+	case "Tan":
+		return d.chain(du, node("X2", node("Sec", u))), nil
+	case "Sinh":
+		return d.chain(du, node("Cosh", u)), nil
+	case "Cosh":
+		return d.chain(du, node("Sinh", u)), nil
+	case "Tanh":
+		return d.chain(du, node("X2", node("Sech", u))), nil
+	}
+	return nil, fmt.Errorf("unable to differentiate function %q", n.Symbol)
+}
+
+// mulOf maps a number of factors to the function that multiplies them.
+var mulOf = map[int]string{1: "Nop", 2: "*", 3: "Mul3"}
+
+// chain applies the chain rule, multiplying the derivative du of the
+// argument by the derivative df of the function with respect to it.
+func (d *deriv) chain(du, df *gene.ExprNode) *gene.ExprNode {
+	if du == nil {
+		return nil
+	}
+	return node("*", du, df)
+}
+
+// sum returns a+b, where either (but not both) may be nil for zero.
+func (d *deriv) sum(a, b *gene.ExprNode) *gene.ExprNode {
+	if a == nil {
+		return b
+	}
+	return node("+", a, b)
+}
+
+// difference returns a-b, where a may be nil for zero.
+func (d *deriv) difference(a, b *gene.ExprNode) *gene.ExprNode {
+	if a == nil {
+		return node("Neg", b)
+	}
+	return node("-", a, b)
+}
@@ -0,0 +1,97 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"math"
+	"testing"
+
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+func TestDerivative(t *testing.T) {
+	// x^3 + 4x + x*y - 5
+	g := newGenome("+", "*.*.d0.d0.d0", "*.c0.d0", "*.d0.d1", "c1")
+	g.Genes[1].Constants = []float64{4}
+	g.Genes[3].Constants = []float64{0, -5}
+	dx, err := g.Derivative(0)
+	if err != nil {
+		t.Fatalf("Derivative(0) = %v", err)
+	}
+	dy, err := g.Derivative(1)
+	if err != nil {
+		t.Fatalf("Derivative(1) = %v", err)
+	}
+	dz, err := g.Derivative(2)
+	if err != nil {
+		t.Fatalf("Derivative(2) = %v", err)
+	}
+	for _, x := range []float64{-2, -0.5, 0, 1, 3.25} {
+		for _, y := range []float64{-1, 0, 2} {
+			in := []float64{x, y}
+			if got, want := dx.EvalMath(in), 3*x*x+4+y; math.Abs(got-want) > 1e-9 {
+				t.Errorf("d/dx at %v = %v, want %v", in, got, want)
+			}
+			if got, want := dy.EvalMath(in), x; math.Abs(got-want) > 1e-9 {
+				t.Errorf("d/dy at %v = %v, want %v", in, got, want)
+			}
+			if got := dz.EvalMath(in); got != 0 {
+				t.Errorf("d/dz at %v = %v, want 0", in, got)
+			}
+		}
+	}
+}
+
+func TestDerivativeRules(t *testing.T) {
+	tests := []*Genome{
+		newGenome("+", "Sin.*.d0.d0", "Exp.Cos.d0", "Ln.X2.d0.d0"),
+		newGenome("*", "/.Sqrt.d1.d0", "Pow.d0.d1.d0"),
+		newGenome("-", "Tan.Inv.d0", "X5.X3.d0", "X4.Tanh.d0"),
+		newGenome("/", "Div3.d0.d1.d0.d0", "Mul3.d0.Sinh.d1.Cosh.d0"),
+		newGenome("+", "Pow10.Log.d0", "Add3.d0.Neg.Avg2.d1.d0.d0", "Sub4.d0.d1.Nop.d0.d1.d0"),
+		newGenome("Pow", "Pow.d1.Sqrt.d0", "d0"),
+		newGenome("+", "*.Floor.d0.c0"), // Floor is not differentiable, but does not depend on d0.
+		newGenome("+", "+.One.Pi.d0.d0"),
+		NewHomeotic([]*gene.Gene{gene.New("*.d0.d1"), gene.New("Sin.d0")}, gene.New("*.d0.+.d1.d0")),
+	}
+	tests[6].Genes[0].Constants = []float64{2.5}
+	for _, g := range tests {
+		for input := 0; input < 2; input++ {
+			dg, err := g.Derivative(input)
+			if err != nil {
+				t.Fatalf("%v: Derivative(%v) = %v", g, input, err)
+			}
+			if err := dg.Validate(mn.Math); err != nil {
+				t.Fatalf("%v: Derivative(%v) = %v: Validate = %v", g, input, dg, err)
+			}
+			for _, in := range [][]float64{{0.7, 1.3}, {1.1, 0.4}, {0.3, 0.9}} {
+				const h = 1e-6
+				hi, lo := append([]float64{}, in...), append([]float64{}, in...)
+				hi[input] += h
+				lo[input] -= h
+				want := (g.EvalMath(hi) - g.EvalMath(lo)) / (2 * h)
+				if got := dg.EvalMath(in); math.Abs(got-want) > 1e-5*math.Max(1, math.Abs(want)) {
+					t.Errorf("%v: d/dd%v at %v = %v, want %v (finite difference)", g, input, in, got, want)
+				}
+			}
+		}
+	}
+
+	errTests := []*Genome{
+		newGenome("+", "Abs.d0"),
+		newGenome("+", "d0", "Max2.d1.d0"),
+		newGenome("Bogus", "d0", "d1"),
+		New(nil, "+"),
+	}
+	for _, g := range errTests {
+		if dg, err := g.Derivative(0); err == nil {
+			t.Errorf("%v: Derivative(0) = %v, want error", g, dg)
+		}
+	}
+	if _, err := newGenome("+", "d0").Derivative(-1); err == nil {
+		t.Errorf("Derivative(-1) = nil error, want error")
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The errors returned by the error-returning methods of the package (such as
+// EvalMathSafe, Validate, and Rebind) wrap these sentinel errors, so that
+// callers can distinguish the failures with errors.Is.
+var (
+	// ErrMissingLinkFunc reports that the linking function of a genome is
+	// not found in the map of available functions.
+	ErrMissingLinkFunc = errors.New("unable to find linking function")
+	// ErrNilScoringFunc reports that a nil ScoringFunc was provided.
+	ErrNilScoringFunc = errors.New("ScoringFunc must not be nil")
+	// ErrInvalidGenome reports that a genome is malformed, such as having
+	// no genes or an invalid gene.
+	ErrInvalidGenome = errors.New("invalid genome")
+	// ErrInvalidIR reports that an intermediate representation (see ToIR)
+	// is malformed.
+	ErrInvalidIR = errors.New("invalid IR")
+	// ErrTooManyNodes reports that the evaluation of a genome would exceed
+	// the MaxNodes of its ScoringConfig (see EvalMathLimit).
+	ErrTooManyNodes = errors.New("too many nodes")
+	// ErrCorruptData reports that an encoded genome (see UnmarshalBinary)
+	// is truncated or malformed.
+	ErrCorruptData = errors.New("corrupt genome encoding")
+)
+
+// errNoGenes reports a genome without any genes.
+var errNoGenes = fmt.Errorf("%w: genome has no genes", ErrInvalidGenome)
+
+// missingLinkFunc returns the error for the missing linking function sym.
+func missingLinkFunc(sym string) error {
+	return fmt.Errorf("%w: %v", ErrMissingLinkFunc, sym)
+}
+
+// invalidGene returns the error for the invalid gene described by what
+// (such as "gene #2").
+func invalidGene(what string, err error) error {
+	return fmt.Errorf("%w: %v: %w", ErrInvalidGenome, what, err)
+}
+
+// corrupt returns the error for an encoded genome that is malformed as
+// described by the format and arguments.
+func corrupt(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %v", ErrCorruptData, fmt.Sprintf(format, a...))
+}
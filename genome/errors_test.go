@@ -0,0 +1,45 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"errors"
+	"testing"
+
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	_, missingLink := newGenome("Bogus", "d0", "d1").EvalMathSafe([]float64{1, 2})
+	_, noGenes := New(nil, "+").EvalMathSafe([]float64{1})
+	_, badGene := New([]*gene.Gene{gene.New("+.d0")}, "+").EvalMathSafe([]float64{1})
+	_, nilScoring := newGenome("+", "d0").FitnessSafe(nil)
+	_, builtLink := Build().Gene("+ d0 d1").Link("Bogus").Genome()
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "EvalMathSafe with missing link", err: missingLink, want: ErrMissingLinkFunc},
+		{name: "EvalMathSafe with no genes", err: noGenes, want: ErrInvalidGenome},
+		{name: "EvalMathSafe with incomplete gene", err: badGene, want: ErrInvalidGenome},
+		{name: "Validate with missing link", err: newGenome("Bogus", "d0", "d1").Validate(mn.Math), want: ErrMissingLinkFunc},
+		{name: "Validate with invalid gene", err: newGenome("+", "Bogus.d0").Validate(mn.Math), want: ErrInvalidGenome},
+		{name: "Builder with missing link", err: builtLink, want: ErrMissingLinkFunc},
+		{name: "FitnessSafe with nil ScoringFunc", err: nilScoring, want: ErrNilScoringFunc},
+	}
+	for _, test := range tests {
+		if !errors.Is(test.err, test.want) {
+			t.Errorf("%v: error = %v, want it to match %v", test.name, test.err, test.want)
+		}
+	}
+	if errors.Is(missingLink, ErrInvalidGenome) {
+		t.Errorf("missing link error %v matches ErrInvalidGenome", missingLink)
+	}
+	if got, err := newGenome("+", "d0").FitnessSafe(func(*Genome) float64 { return 42 }); got != 42 || err != nil {
+		t.Errorf("FitnessSafe = (%v, %v), want (42, nil)", got, err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"sync"
+
+	"github.com/gmlewis/gep/functions"
+)
+
+// BestTracker records a copy of the best genome (as ranked by Better) reported
+// to it. It is safe for concurrent use, so parallel scoring goroutines may
+// share a single BestTracker. The zero value is ready to use.
+type BestTracker struct {
+	mu   sync.Mutex
+	best *Genome
+	key  rankKey
+}
+
+// Update records a copy of g if it ranks ahead of the best genome seen so far,
+// and reports whether it did so. g must not be modified during the call.
+func (t *BestTracker) Update(g *Genome) bool {
+	key := g.rankKey()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.best != nil && !key.better(t.key) {
+		return false
+	}
+	t.best, t.key = g.Dup(), key
+	return true
+}
+
+// Best returns a copy of the best genome seen so far, or nil if none.
+func (t *BestTracker) Best() *Genome {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.best == nil {
+		return nil
+	}
+	return t.best.Dup()
+}
+
+// EvaluatePopulation scores every genome of pop with sf using the given number
+// of concurrent workers (at least 1), and reports each scored genome to best
+// (if non-nil). Each genome is scored by exactly one worker, so the genomes
+// of pop must be distinct.
+func EvaluatePopulation(pop []*Genome, sf ScoringFunc, workers int, best *BestTracker) {
+	if sf == nil {
+		functions.Log.Fatalf("genome.EvaluatePopulation: %v", ErrNilScoringFunc)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan *Genome)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for g := range jobs {
+				g.Score = sf(g)
+				if best != nil {
+					best.Update(g)
+				}
+			}
+		}()
+	}
+	for _, g := range pop {
+		jobs <- g
+	}
+	close(jobs)
+	wg.Wait()
+}
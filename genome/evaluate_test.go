@@ -0,0 +1,52 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"sync"
+	"testing"
+)
+
+// Run with -race to check that concurrent updates of the shared best are safe.
+func TestEvaluatePopulation(t *testing.T) {
+	var pop []*Genome
+	for i := 0; i < 500; i++ {
+		pop = append(pop, newConstGene("*.c0.d0", float64(i%250)))
+	}
+	sf := func(g *Genome) float64 { return g.EvalMath([]float64{1}) }
+	var best BestTracker
+	if got := best.Best(); got != nil {
+		t.Errorf("empty BestTracker.Best = %v, want nil", got)
+	}
+	EvaluatePopulation(pop, sf, 32, &best)
+	for i, v := range pop {
+		if want := float64(i % 250); v.Score != want {
+			t.Errorf("genome #%v Score = %v, want %v", i, v.Score, want)
+		}
+	}
+	if got := best.Best(); got == nil || got.Score != 249 {
+		t.Errorf("BestTracker.Best = %v, want score 249", got)
+	}
+
+	// Many goroutines updating the same tracker directly.
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			g := newConstGene("c0", float64(i))
+			g.Score = float64(1000 + i)
+			best.Update(g)
+			best.Best()
+		}(i)
+	}
+	wg.Wait()
+	if got := best.Best(); got == nil || got.Score != 1063 {
+		t.Errorf("BestTracker.Best = %v, want score 1063", got)
+	}
+	if best.Update(newConstGene("c0", 1)) {
+		t.Errorf("BestTracker.Update(worse) = true, want false")
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"math"
+	"strings"
+
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+// FoldConstantSubtrees returns a duplicate of the genome in which each subtree
+// of the expression of each gene whose output is constant (within tol) over
+// every row of the dataset, such as "2 * 3" or "d0 - d0", is replaced by a new
+// constant terminal holding its value (see gene.AddConstant). Unlike algebraic
+// simplification, this is driven by the data: a subtree is folded only if,
+// afterwards, the output of the genome still agrees with that of the original
+// within tol for every row. Larger subtrees are folded before the subtrees
+// within them. The homeotic gene (if any) is left unfolded, and with no rows,
+// nothing is folded.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) FoldConstantSubtrees(ds *Dataset, tol float64) *Genome {
+	r := g.Dup()
+	if ds == nil || len(ds.Inputs) == 0 {
+		return r
+	}
+	tol = math.Max(tol, 0)
+	want := g.EvalMathBatch(ds.Inputs)
+	equivalent := func() bool {
+		for i, in := range ds.Inputs {
+			got := r.EvalMath(in)
+			if got != want[i] && !(math.Abs(got-want[i]) <= tol) && !(math.IsNaN(got) && math.IsNaN(want[i])) {
+				return false
+			}
+		}
+		return true
+	}
+	for i := range r.Genes {
+		tried := map[int]bool{}
+		for pos := 0; ; pos++ {
+			nodes := treeNodes(r.Genes[i].Tree(mn.Math))
+			if pos >= len(nodes) {
+				break
+			}
+			if tried[pos] || len(nodes[pos].Args) == 0 {
+				continue
+			}
+			tried[pos] = true
+			v, ok := r.constantOutput(r.Genes[i], nodes[pos], ds, tol)
+			if !ok {
+				continue
+			}
+			saved := r.Genes[i]
+			r.Genes[i] = saved.Dup()
+			if err := r.Genes[i].ReplaceSubtree(pos, []string{r.Genes[i].AddConstant(v)}); err != nil || !equivalent() {
+				r.Genes[i] = saved
+				continue
+			}
+			// The positions following pos have changed.
+			tried, pos = map[int]bool{}, pos-1
+		}
+	}
+	r.invalidate()
+	return r
+}
+
+// constantOutput evaluates the subtree n of gene v over every row of the
+// dataset and returns the midpoint of its outputs, if they are finite and
+// span no more than tol.
+func (g *Genome) constantOutput(v *gene.Gene, n *gene.ExprNode, ds *Dataset, tol float64) (float64, bool) {
+	sub := gene.New(strings.Join(n.Karva(), "."))
+	sub.Constants = v.Constants
+	if g.funcs != nil {
+		var err error
+		if sub, err = sub.Rebind(g.funcs); err != nil {
+			return 0, false
+		}
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, in := range ds.Inputs {
+		x := sub.EvalMath(g.MissingPolicy.apply(in))
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return 0, false
+		}
+		lo, hi = math.Min(lo, x), math.Max(hi, x)
+	}
+	if hi-lo > tol {
+		return 0, false
+	}
+	return lo + (hi-lo)/2, true
+}
+
+// treeNodes returns the nodes of the tree rooted at n in breadth-first order,
+// which is the order of their positions within the Karva expression.
+func treeNodes(n *gene.ExprNode) []*gene.ExprNode {
+	if n == nil {
+		return nil
+	}
+	result := []*gene.ExprNode{n}
+	for i := 0; i < len(result); i++ {
+		result = append(result, result[i].Args...)
+	}
+	return result
+}
+
+// Canonicalize returns a minimal duplicate of the genome that is semantically
+// equivalent to it over the dataset (within tol, as by MatchesTarget), for
+// cleaning up a solution for reporting or deduplication: its constant
+// subtrees are folded (see FoldConstantSubtrees), and then each gene whose
+// removal leaves the output unchanged (such as one adding 0, or d0 - d0) is
+// pruned, from the last gene to the first. A genome always keeps at least one
+// gene, and the genes of a homeotic genome are never pruned. Canonicalizing
+// the result again leaves it unchanged (as it was already minimal).
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) Canonicalize(ds *Dataset, tol float64) *Genome {
+	r := g.FoldConstantSubtrees(ds, tol)
+	if r.Homeotic != nil {
+		return r
+	}
+	for i := len(r.Genes) - 1; i >= 0 && len(r.Genes) > 1; i-- {
+		trial := r.Dup()
+		trial.Genes = append(trial.Genes[:i], trial.Genes[i+1:]...)
+		if trial.MatchesTarget(g, ds, tol) {
+			r = trial
+		}
+	}
+	return r
+}
@@ -0,0 +1,71 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFoldConstantSubtrees(t *testing.T) {
+	tests := []struct {
+		g         *Genome
+		constants []float64
+		want      string
+		folded    []float64
+	}{
+		{ // d0 + 2*3 => d0 + 6
+			g: newGenome("+", "+.*.d0.c0.c1"), constants: []float64{2, 3},
+			want: "+.c2.d0.c0.c1", folded: []float64{2, 3, 6},
+		},
+		{ // d0 * (d0 - d0) => 0, folding the whole expression once its subtree is folded
+			g: newGenome("+", "*.d0.-.d0.d0.d0.d0"), want: "c0.d0.-.d0.d0.d0.d0", folded: []float64{0},
+		},
+		{ // (d0 + d0) * d0 depends upon the input throughout.
+			g: newGenome("+", "*.+.d0.d0.d0.d0.d0"), want: "*.+.d0.d0.d0.d0.d0", folded: []float64{},
+		},
+	}
+	for i, test := range tests {
+		if test.constants != nil {
+			test.g.Genes[0].Constants = test.constants
+		}
+		before := test.g.String()
+		got := test.g.FoldConstantSubtrees(regressionDataset, 1e-9)
+		if s := got.Genes[0].String(); s != test.want {
+			t.Errorf("%v: FoldConstantSubtrees = %q, want %q", i, s, test.want)
+		}
+		if c := got.Genes[0].Constants; !reflect.DeepEqual(c, test.folded) {
+			t.Errorf("%v: FoldConstantSubtrees constants = %v, want %v", i, c, test.folded)
+		}
+		if test.g.String() != before {
+			t.Errorf("%v: FoldConstantSubtrees altered the genome %q: %q", i, before, test.g)
+		}
+		if !got.MatchesTarget(test.g, regressionDataset, 1e-9) {
+			t.Errorf("%v: FoldConstantSubtrees = %q, want numerically equivalent to %q", i, got, test.g)
+		}
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	minimal := newGenome("+", "*.d0.d0.d0", "d0") // x*x + x
+	got := minimal.Canonicalize(regressionDataset, 1e-9)
+	if got == minimal || got.String() != minimal.String() || got.CodingLength() != minimal.CodingLength() {
+		t.Errorf("Canonicalize(%q) = %q, want an equivalent duplicate of the same size", minimal, got)
+	}
+
+	// (x*x + (2-2)) + (x - x) + x prunes the dead genes and folds the constant subtree.
+	bloated := newGenome("+", "+.*.-.d0.d0.c0.c0", "-.d0.d0", "d0")
+	bloated.Genes[0].Constants = []float64{2}
+	got = bloated.Canonicalize(regressionDataset, 1e-9)
+	if !got.MatchesTarget(bloated, regressionDataset, 1e-9) {
+		t.Errorf("Canonicalize(%q) = %q, want numerically equivalent", bloated, got)
+	}
+	if want := minimal.CodingLength() + 2; got.CodingLength() != want || len(got.Genes) != 2 {
+		t.Errorf("Canonicalize(%q) = %q (coding length %v), want 2 genes of coding length %v", bloated, got, got.CodingLength(), want)
+	}
+	if again := got.Canonicalize(regressionDataset, 1e-9); again.String() != got.String() {
+		t.Errorf("Canonicalize is not idempotent: %q, then %q", got, again)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/gmlewis/gep/functions"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+// corpusFuncs are the math functions (of mixed arity) used to build the random corpus.
+var corpusFuncs = []string{"+", "-", "*", "/", "Sqrt", "Neg", "Add3", "Mul4"}
+
+// randomPair generates two random, valid math genomes sharing the same structure
+// (head size, gene count, inputs, constants, and function set) using only r
+// (rather than functions.DefaultRNG), so that the result is reproducible from
+// the seed of r.
+func randomPair(r functions.RNG) (*Genome, *Genome) {
+	headSize := 1 + r.Intn(20)
+	numGenes := 1 + r.Intn(4)
+	numTerminals := 1 + r.Intn(5)
+	numConstants := r.Intn(4)
+	var funcs []gene.FuncWeight
+	maxArity := 0
+	for _, i := range functions.Perm(r, len(corpusFuncs))[:1+r.Intn(len(corpusFuncs))] {
+		sym := corpusFuncs[i]
+		funcs = append(funcs, gene.FuncWeight{Symbol: sym, Weight: 1 + r.Intn(3)})
+		if n := mn.Math[sym].Terminals(); n > maxArity {
+			maxArity = n
+		}
+	}
+	tailSize := headSize*(maxArity-1) + 1
+	var terminals []string
+	for i := 0; i < numTerminals; i++ {
+		terminals = append(terminals, fmt.Sprintf("d%v", i))
+	}
+	for i := 0; i < numConstants; i++ {
+		terminals = append(terminals, fmt.Sprintf("c%v", i))
+	}
+	head := append([]string{}, terminals...)
+	for _, f := range funcs {
+		head = append(head, f.Symbol)
+	}
+	newGenome := func() *Genome {
+		genes := make([]*gene.Gene, numGenes)
+		for i := range genes {
+			// RandomNewWith provides the head/tail structure; the symbols and
+			// constants are then overwritten from r.
+			g := gene.RandomNewWith(r, headSize, tailSize, numTerminals, numConstants, funcs, nil)
+			for j := range g.Symbols {
+				if j < headSize {
+					g.Symbols[j] = head[r.Intn(len(head))]
+				} else {
+					g.Symbols[j] = terminals[r.Intn(len(terminals))]
+				}
+			}
+			for j := range g.Constants {
+				g.Constants[j] = 10*r.Float64() - 5
+			}
+			genes[i] = g
+		}
+		return New(genes, "+")
+	}
+	return newGenome(), newGenome()
+}
+
+// randomCorpus generates n random, valid math genomes reproducibly from seed.
+// Consecutive genomes (0 and 1, 2 and 3, ...) share the same structure so that
+// they may be used as recombination partners.
+func randomCorpus(seed int64, n int) []*Genome {
+	r := functions.NewRNG(seed)
+	result := make([]*Genome, 0, n)
+	for len(result) < n {
+		g1, g2 := randomPair(r)
+		result = append(result, g1, g2)
+	}
+	return result[:n]
+}
+
+func TestRandomCorpus(t *testing.T) {
+	c1, c2 := randomCorpus(2014, 50), randomCorpus(2014, 50)
+	for i := range c1 {
+		if c1[i].String() != c2[i].String() {
+			t.Errorf("randomCorpus genome #%v not reproducible: %q != %q", i, c1[i], c2[i])
+		}
+	}
+}
+
+func TestOperatorsPreserveValidity(t *testing.T) {
+	in := []float64{1, 2, 3, 4, 5}
+	corpus := randomCorpus(1, 2000)
+	for i, g := range corpus {
+		if err := g.Validate(mn.Math); err != nil {
+			t.Fatalf("corpus genome #%v %q: Validate = %v", i, g, err)
+		}
+	}
+	rng := functions.NewRNG(1)
+	for i := 0; i+1 < len(corpus); i += 2 {
+		g1, g2 := corpus[i], corpus[i+1]
+		g1.MutateWith(rng, 3)
+		OnePointRecombinationWith(rng, g1, g2)
+		for _, g := range []*Genome{g1, g2} {
+			if err := g.Validate(mn.Math); err != nil {
+				t.Fatalf("corpus genome %q: Validate after operators = %v", g, err)
+			}
+			g.EvalMath(in)
+		}
+	}
+}
+
+func FuzzMutate(f *testing.F) {
+	for _, seed := range []int64{0, 1, 42, 2014} {
+		f.Add(seed, uint8(1))
+	}
+	f.Fuzz(func(t *testing.T, seed int64, numMutations uint8) {
+		rng := functions.NewRNG(seed)
+		for i, g := range randomCorpus(seed, 10) {
+			g.MutateWith(rng, 1+int(numMutations%16))
+			if err := g.Validate(mn.Math); err != nil {
+				t.Errorf("genome #%v %q: Validate after Mutate = %v", i, g, err)
+			}
+		}
+	})
+}
+
+func FuzzOnePointRecombination(f *testing.F) {
+	for _, seed := range []int64{0, 1, 42, 2014} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, seed int64) {
+		corpus, rng := randomCorpus(seed, 10), functions.NewRNG(seed)
+		for i := 0; i+1 < len(corpus); i += 2 {
+			OnePointRecombinationWith(rng, corpus[i], corpus[i+1])
+			for _, g := range corpus[i : i+2] {
+				if err := g.Validate(mn.Math); err != nil {
+					t.Errorf("genome %q: Validate after OnePointRecombination = %v", g, err)
+				}
+			}
+		}
+	})
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	// Mutating a gene of unknown structure logs an error.
+	defer func(l functions.Logger) { functions.Log = l }(functions.Log)
+	functions.Log = log.New(io.Discard, "", 0)
+	for _, g := range randomCorpus(1, 4) {
+		data, err := g.MarshalBinary()
+		if err != nil {
+			f.Fatalf("MarshalBinary(%v) = %v", g, err)
+		}
+		f.Add(data)
+	}
+	if data, err := newGenome("+", "*.d0.d1", "Sqrt.d1").MarshalBinary(); err == nil {
+		f.Add(data)
+	}
+	for _, data := range corruptEncodings {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var g Genome
+		if err := g.UnmarshalBinary(data); err != nil {
+			if !errors.Is(err, ErrCorruptData) {
+				t.Errorf("UnmarshalBinary = %v, want error matching %v", err, ErrCorruptData)
+			}
+			return
+		}
+		// A decoded genome may be encoded again, and mutated without panicking.
+		again, err := g.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary(%v) = %v", &g, err)
+		}
+		var r Genome
+		if err := r.UnmarshalBinary(again); err != nil || r.String() != g.String() {
+			t.Errorf("UnmarshalBinary(MarshalBinary(%v)) = (%v, %v)", &g, &r, err)
+		}
+		if len(g.Genes) > 0 {
+			g.MutateWith(functions.NewRNG(1), 10)
+		}
+	})
+}
@@ -7,9 +7,12 @@
 package genome
 
 import (
-	"log"
-	"math/rand"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/gmlewis/gep/functions"
 	mn "github.com/gmlewis/gep/functions/math_nodes"
@@ -23,13 +26,46 @@ type Genome struct {
 	Genes    []*gene.Gene
 	LinkFunc string
 	Score    float64
+	// Frozen marks the genome as immutable: the mutating operators
+	// (Mutate, AddGene, RemoveGene, and OnePointRecombination) leave a
+	// frozen genome unchanged. Duplicates of a frozen genome are not frozen,
+	// but its survivors in the population during model training are.
+	Frozen bool
+	// Homeotic, if non-nil, is the homeotic gene of the genome, which combines
+	// the results of the other genes (as ADFs) in place of LinkFunc.
+	// See NewHomeotic.
+	Homeotic *gene.Gene
+	// MissingPolicy, if non-nil, determines how the floating-point evaluation
+	// of the genome (such as EvalMath) treats missing (NaN) inputs.
+	// If nil, missing inputs propagate.
+	MissingPolicy *MissingPolicy
+
+	// funcs, if non-nil, is the map of floating-point functions to which the
+	// genome is bound (see Rebind). It defaults to mn.Math.
+	funcs functions.FuncMap
 
 	SymbolMap map[string]int // do not use directly.  Use SymbolCount() instead.
+	// symbolsOnce guards the lazy construction of SymbolMap, so that the
+	// genome may be used concurrently. The operators that alter the genome
+	// replace it (see invalidate).
+	symbolsOnce *sync.Once
 }
 
 // New creates a new genome from the given genes and linking function.
 func New(genes []*gene.Gene, linkFunc string) *Genome {
-	return &Genome{Genes: genes, LinkFunc: linkFunc}
+	return &Genome{Genes: genes, LinkFunc: linkFunc, symbolsOnce: &sync.Once{}}
+}
+
+// NewAdditive creates a new genome from the given genes whose results
+// are summed (that is, linked by mn.Sum).
+func NewAdditive(genes []*gene.Gene) *Genome {
+	return New(genes, mn.Sum)
+}
+
+// NewMultiplicative creates a new genome from the given genes whose results
+// are multiplied (that is, linked by mn.Product).
+func NewMultiplicative(genes []*gene.Gene) *Genome {
+	return New(genes, mn.Product)
 }
 
 func merge(dst *map[string]int, src map[string]int) {
@@ -43,30 +79,316 @@ func merge(dst *map[string]int, src map[string]int) {
 // Note that this count is typically different from the number
 // of times the symbol appears in the Karva expression.  This can be
 // a handy metric to assist in the fitness evaluation of a Genome.
+// The linking function is counted once for each join between genes,
+// matching the number of times it is applied during evaluation. For a
+// homeotic genome, the symbols expressed by the homeotic gene are counted
+// instead, except its terminals d0, d1, ..., which stand for the ADFs.
 // Note also that this currently only works for Math expressions.
 // Hopefully this restriction will be lifted in the future.
 // A workaround for using it with other types is to evaluate the
 // Genome, and then g.symbolCount will already be populated.
 func (g *Genome) SymbolCount(sym string) int {
-	if g.SymbolMap == nil {
-		g.SymbolMap = make(map[string]int)
-		g.SymbolMap[g.LinkFunc] = len(g.Genes) - 1
-		for i := 0; i < len(g.Genes); i++ {
-			g.Genes[i].SymbolCount(sym) // force evaluation
-			m := g.Genes[i].SymbolMap
-			merge(&(g.SymbolMap), m)
-		}
+	if len(g.Genes) == 0 {
+		functions.Log.Printf("genome.SymbolCount error: genome has no genes")
+		return 0
 	}
+	if g.symbolsOnce == nil { // Genomes made without a constructor are not cached.
+		return g.symbols()[sym]
+	}
+	g.symbolsOnce.Do(func() { g.SymbolMap = g.symbols() })
 	return g.SymbolMap[sym]
 }
 
+// symbols builds the SymbolMap of the genome from those of its genes and
+// the symbols of its link tree (see LinkTree), whose leaves d0, d1, ... stand
+// for the genes and are not counted.
+func (g *Genome) symbols() map[string]int {
+	r := make(map[string]int)
+	for i := 0; i < len(g.Genes); i++ {
+		g.Genes[i].SymbolCount("") // force evaluation
+		m := g.Genes[i].SymbolMap
+		merge(&r, m)
+	}
+	var link func(n *gene.ExprNode)
+	link = func(n *gene.ExprNode) {
+		if kind, _, err := gene.ParseTerminal(n.Symbol); err == nil && kind == "d" && len(n.Args) == 0 {
+			return
+		}
+		r[n.Symbol]++
+		for _, v := range n.Args {
+			link(v)
+		}
+	}
+	if root := g.LinkTree(); root != nil {
+		link(root)
+	}
+	return r
+}
+
+// invalidate discards the SymbolMap of the genome, which must be done
+// whenever its genes or linking function change.
+func (g *Genome) invalidate() {
+	g.symbolsOnce, g.SymbolMap = &sync.Once{}, nil
+}
+
+// CodingSymbolCount returns the number of times the symbol appears
+// within the expressed (coding) regions of all the genes in the Genome.
+// Unlike SymbolCount, no count is added for the linking function, making
+// this the appropriate metric for parsimony pressure.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) CodingSymbolCount(sym string) int {
+	count := 0
+	for _, v := range g.Genes {
+		count += v.CodingSymbolCount(sym)
+	}
+	return count
+}
+
+// CodingSymbolMap returns the number of times each symbol appears within
+// the expressed (coding) regions of all the genes in the Genome.
+// As with CodingSymbolCount, the linking function is not counted.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) CodingSymbolMap() map[string]int {
+	r := map[string]int{}
+	for _, v := range g.Genes {
+		for sym, n := range v.CodingSymbolMap() {
+			r[sym] += n
+		}
+	}
+	return r
+}
+
+// CodingLength returns the total length of the expressed (coding) regions
+// of all the genes in the Genome, including the homeotic gene, if any.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) CodingLength() int {
+	n := 0
+	for _, v := range g.Genes {
+		n += v.CodingLength()
+	}
+	if g.Homeotic != nil {
+		n += g.Homeotic.CodingLength()
+	}
+	return n
+}
+
+// Complexity returns the weighted size of the expressed (coding) regions of
+// all the genes in the Genome: the sum, over every coding symbol, of its
+// weight, with symbols missing from weights (which may be nil) weighing 1.
+// Weighting costly functions (such as Sin) more heavily than cheap ones
+// (such as +) makes a parsimony pressure that reflects the real cost of
+// evaluating the expression. As with CodingSymbolCount, the linking function
+// is not counted.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) Complexity(weights map[string]float64) float64 {
+	result := 0.0
+	for sym, n := range g.CodingSymbolMap() {
+		w, ok := weights[sym]
+		if !ok {
+			w = 1
+		}
+		result += w * float64(n)
+	}
+	return result
+}
+
+// NonCodingLength returns the total length of the non-coding regions (the
+// symbols following the expressed regions) of all the genes in the Genome,
+// including the homeotic gene, if any.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) NonCodingLength() int {
+	n := 0
+	for _, v := range g.Genes {
+		n += len(v.Symbols)
+	}
+	if g.Homeotic != nil {
+		n += len(g.Homeotic.Symbols)
+	}
+	return n - g.CodingLength()
+}
+
+// NonCodingFraction returns the fraction of all the symbols of the genes in the
+// Genome that lie within non-coding regions, or 0 if the genes have no
+// symbols. Tracked over the generations, it reveals neutral (bloat) growth.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) NonCodingFraction() float64 {
+	n := g.NonCodingLength() + g.CodingLength()
+	if n == 0 {
+		return 0
+	}
+	return float64(g.NonCodingLength()) / float64(n)
+}
+
+// EvaluatedNodes returns the number of nodes evaluated per row by EvalMath:
+// those of the expressed regions of all the genes (including the homeotic
+// gene, if any) plus the applications of the linking function. This is the
+// total work of an evaluation, for cost-penalized fitness and scheduling:
+// unlike Depth or CodingLength, it includes the linking function.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) EvaluatedNodes() int {
+	n := g.CodingLength()
+	if g.Homeotic == nil && len(g.Genes) > 1 {
+		n += len(g.Genes) - 1
+	}
+	return n
+}
+
+// EvalCost returns the total work of evaluating the genome for one row of
+// inputs, in node evaluations, for cost-penalized fitness and scheduling.
+//
+// Deprecated: EvalCost is an alias of EvaluatedNodes; use EvaluatedNodes.
+func (g *Genome) EvalCost() int {
+	return g.EvaluatedNodes()
+}
+
+// Symbols returns a copy of the full list of symbols (head and tail) of each
+// gene of the Genome, in order.
+func (g *Genome) Symbols() [][]string {
+	r := make([][]string, len(g.Genes))
+	for i, v := range g.Genes {
+		r[i] = append([]string(nil), v.Symbols...)
+	}
+	return r
+}
+
+// HeadSizes returns the head size of each gene of the Genome, in order, or 0
+// for a gene whose head size is unknown (such as one created by gene.New).
+// Together with Symbols, they are the arguments of FromSymbols.
+func (g *Genome) HeadSizes() []int {
+	r := make([]int, len(g.Genes))
+	for i, v := range g.Genes {
+		r[i], _, _ = v.Structure()
+	}
+	return r
+}
+
+// LinearSymbols returns the symbols of all the genes of the Genome
+// concatenated into a single slice, as seen by OnePointRecombination.
+func (g *Genome) LinearSymbols() []string {
+	var r []string
+	for _, v := range g.Genes {
+		r = append(r, v.Symbols...)
+	}
+	return r
+}
+
+// MaxDepth returns the depth of the deepest expression tree among the genes
+// of the Genome, plus 1 for the linking function when there is more than one
+// gene (since only then is it applied). For a homeotic genome, it is the depth
+// of the expression of the homeotic gene with its ADFs substituted.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) MaxDepth() int {
+	if g.Homeotic != nil {
+		return g.homeoticDepth(g.LinkTree())
+	}
+	max := 0
+	for _, v := range g.Genes {
+		if d := v.Depth(); d > max {
+			max = d
+		}
+	}
+	if len(g.Genes) > 1 {
+		max++
+	}
+	return max
+}
+
+// UsedInputs returns the sorted, distinct indices of the inputs (d0, d1, ...)
+// referenced within the expressed (coding) regions of all the genes in the Genome.
+// This identifies the features that the genome actually depends upon.
+// For a homeotic genome, only the ADFs called by the homeotic gene are included.
+func (g *Genome) UsedInputs() []int {
+	genes := g.Genes
+	if g.Homeotic != nil {
+		genes = nil
+		for _, index := range g.Homeotic.UsedInputs() {
+			if index < len(g.Genes) {
+				genes = append(genes, g.Genes[index])
+			}
+		}
+	}
+	seen := map[int]bool{}
+	var result []int
+	for _, v := range genes {
+		for _, index := range v.UsedInputs() {
+			if !seen[index] {
+				seen[index] = true
+				result = append(result, index)
+			}
+		}
+	}
+	sort.Ints(result)
+	return result
+}
+
+// CheckInputs returns an error if the genome references an input (see UsedInputs)
+// that is not among the first numInputs inputs, as happens when a genome is
+// evaluated against a dataset with fewer features than it was trained on.
+// Such inputs evaluate to 0 with EvalMath (which logs each occurrence) and are
+// reported as an error by EvalMathSafe, unless they are supplied by a
+// MissingPolicy.
+func (g *Genome) CheckInputs(numInputs int) error {
+	if used := g.UsedInputs(); len(used) > 0 && used[len(used)-1] >= numInputs {
+		var p []float64
+		if g.MissingPolicy != nil {
+			p = g.MissingPolicy.Defaults
+		}
+		for _, index := range used {
+			if index >= numInputs && (index >= len(p) || math.IsNaN(p[index])) {
+				return fmt.Errorf("genome references input d%v, but only %v inputs are available", index, numInputs)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks that the genome is well formed: it must have at least one gene,
+// its linking function must be found in fm, and every gene must be valid.
+// A homeotic genome has no linking function; instead its homeotic gene must be
+// valid, and may call only the ADFs (genes) of the genome.
+func (g *Genome) Validate(fm functions.FuncMap) error {
+	if len(g.Genes) == 0 {
+		return errNoGenes
+	}
+	if _, ok := fm[g.LinkFunc]; !ok && g.Homeotic == nil {
+		return missingLinkFunc(g.LinkFunc)
+	}
+	for i, v := range g.Genes {
+		if err := v.Validate(fm); err != nil {
+			return invalidGene(fmt.Sprintf("gene #%v", i), err)
+		}
+	}
+	if g.Homeotic != nil {
+		if err := g.Homeotic.Validate(fm); err != nil {
+			return invalidGene("homeotic gene", err)
+		}
+		if used := g.Homeotic.UsedInputs(); len(used) > 0 && used[len(used)-1] >= len(g.Genes) {
+			return invalidGene("homeotic gene", fmt.Errorf("ADF d%v called, but the genome has only %v genes", used[len(used)-1], len(g.Genes)))
+		}
+	}
+	return nil
+}
+
 // EvalBool evaluates the genome as a boolean expression and returns the result.
 // in represents the boolean inputs available to the genome.
 // fm is the map of available boolean functions to the genome.
+// The homeotic gene of a homeotic genome is evaluated with the (boolean)
+// results of its ADFs, as with EvalMath.
 func (g *Genome) EvalBool(in []bool, fm functions.FuncMap) bool {
+	if g.Homeotic != nil {
+		adfs := make([]bool, len(g.Genes))
+		for i, v := range g.Genes {
+			adfs[i] = v.EvalBool(in, fm)
+		}
+		return g.Homeotic.EvalBool(adfs, fm)
+	}
 	lf, ok := fm[g.LinkFunc]
 	if !ok {
-		log.Printf("Unable to find linking function: %v", g.LinkFunc)
+		functions.Log.Printf("Unable to find linking function: %v", g.LinkFunc)
+		return false
+	}
+	if len(g.Genes) == 0 {
+		functions.Log.Printf("genome.EvalBool error: genome has no genes")
 		return false
 	}
 	result := g.Genes[0].EvalBool(in, fm)
@@ -79,9 +401,17 @@ func (g *Genome) EvalBool(in []bool, fm functions.FuncMap) bool {
 // EvalMath evaluates the genome as a floating-point expression and returns the result.
 // in represents the float64 inputs available to the genome.
 func (g *Genome) EvalMath(in []float64) float64 {
-	lf, ok := mn.Math[g.LinkFunc]
+	in = g.MissingPolicy.apply(in)
+	if g.Homeotic != nil {
+		return g.Homeotic.EvalMath(g.adfResults(in))
+	}
+	lf, ok := g.mathNodes()[g.LinkFunc]
 	if !ok {
-		log.Printf("Unable to find linking function: %v", g.LinkFunc)
+		functions.Log.Printf("Unable to find linking function: %v", g.LinkFunc)
+		return 0.0
+	}
+	if len(g.Genes) == 0 {
+		functions.Log.Printf("genome.EvalMath error: genome has no genes")
 		return 0.0
 	}
 	result := g.Genes[0].EvalMath(in)
@@ -91,39 +421,325 @@ func (g *Genome) EvalMath(in []float64) float64 {
 	return result
 }
 
+// EvalMathPerGene evaluates each gene of the genome (or each ADF of a homeotic
+// genome) as a floating-point expression with the inputs in (after applying
+// the MissingPolicy) and returns their results, in order, before they are
+// combined by the linking function.
+func (g *Genome) EvalMathPerGene(in []float64) []float64 {
+	return g.adfResults(g.MissingPolicy.apply(in))
+}
+
+// SplitGenes returns a single-gene genome holding a copy of each gene of the
+// genome (or each ADF of a homeotic genome), in order, so that each gene can
+// be evaluated and interpreted as a model of its own. With a single gene,
+// the linking function is never applied; the genomes keep the linking
+// function of g (or "+" for a homeotic genome, which has none), its
+// MissingPolicy, and its function binding (see Rebind).
+func (g *Genome) SplitGenes() []*Genome {
+	link := g.LinkFunc
+	if g.Homeotic != nil {
+		link = "+"
+	}
+	result := make([]*Genome, len(g.Genes))
+	for i, v := range g.Genes {
+		result[i] = New([]*gene.Gene{v.Dup()}, link)
+		result[i].MissingPolicy, result[i].funcs = g.MissingPolicy, g.funcs
+	}
+	return result
+}
+
+// Combine returns a new multigenic genome holding a copy of the gene of each
+// of the single-gene genomes, in order, joined by linkFunc; it is the inverse
+// of SplitGenes. This turns an ensemble of genomes into a single linked model.
+// The genomes must not be homeotic, their genes must have the same length, and
+// they must share the same function binding (see Rebind), which the new genome
+// keeps, along with the MissingPolicy of the first genome. linkFunc must be
+// found among the bound functions (or mn.Math).
+func Combine(genomes []*Genome, linkFunc string) (*Genome, error) {
+	if len(genomes) == 0 {
+		return nil, errNoGenes
+	}
+	first := genomes[0]
+	genes := make([]*gene.Gene, len(genomes))
+	for i, v := range genomes {
+		switch {
+		case v == nil || len(v.Genes) != 1 || v.Homeotic != nil:
+			return nil, fmt.Errorf("%w: genome #%v is not a single-gene genome", ErrInvalidGenome, i)
+		case len(v.Genes[0].Symbols) != len(first.Genes[0].Symbols):
+			return nil, fmt.Errorf("%w: gene of genome #%v has length %v, want %v", ErrInvalidGenome, i, len(v.Genes[0].Symbols), len(first.Genes[0].Symbols))
+		case reflect.ValueOf(v.funcs).Pointer() != reflect.ValueOf(first.funcs).Pointer():
+			return nil, fmt.Errorf("%w: genome #%v is bound to different functions", ErrInvalidGenome, i)
+		}
+		genes[i] = v.Genes[0].Dup()
+	}
+	if _, ok := first.mathNodes()[linkFunc]; !ok {
+		return nil, missingLinkFunc(linkFunc)
+	}
+	r := New(genes, linkFunc)
+	r.MissingPolicy, r.funcs = first.MissingPolicy, first.funcs
+	return r, nil
+}
+
+// EvalMathSafe is like EvalMath, but never logs: it returns an error if the
+// genome cannot be evaluated (for example, because its linking function is
+// missing) instead. It is intended for environments such as WASM, where the
+// side effects of logging are unwanted.
+func (g *Genome) EvalMathSafe(in []float64) (float64, error) {
+	if len(g.Genes) == 0 {
+		return 0, errNoGenes
+	}
+	in = g.MissingPolicy.apply(in)
+	if g.Homeotic != nil {
+		return g.evalHomeoticSafe(in)
+	}
+	lf, ok := g.mathNodes()[g.LinkFunc]
+	if !ok {
+		return 0, missingLinkFunc(g.LinkFunc)
+	}
+	result, err := g.Genes[0].EvalMathSafe(in)
+	if err != nil {
+		return 0, invalidGene("gene #0", err)
+	}
+	for i := 1; i < len(g.Genes); i++ {
+		v, err := g.Genes[i].EvalMathSafe(in)
+		if err != nil {
+			return 0, invalidGene(fmt.Sprintf("gene #%v", i), err)
+		}
+		result = lf.Float64Function(result, v, 0.0, 0.0)
+	}
+	return result, nil
+}
+
+// Compile decodes the expressions of all the genes of the genome (including
+// its homeotic gene) ahead of their evaluation; see Gene.Compile. This moves
+// the cost of decoding out of the first EvalMath, such as before timing or
+// sharing the genome between goroutines. The mutating operators discard the
+// compiled genes.
+func (g *Genome) Compile() {
+	for _, v := range g.Genes {
+		v.Compile()
+	}
+	if g.Homeotic != nil {
+		g.Homeotic.Compile()
+	}
+}
+
+// EvalMathBatch evaluates the genome as a floating-point expression for each
+// row of inputs and returns the results in order.
+func (g *Genome) EvalMathBatch(inputs [][]float64) []float64 {
+	result := make([]float64, len(inputs))
+	for i, in := range inputs {
+		result[i] = g.EvalMath(in)
+	}
+	return result
+}
+
+// EvalMathClamped evaluates the genome as a floating-point expression and
+// returns the result clamped to the range [lo, hi]. A NaN result maps to lo.
+func (g *Genome) EvalMathClamped(in []float64, lo, hi float64) float64 {
+	r := g.EvalMath(in)
+	switch {
+	case math.IsNaN(r), r < lo:
+		return lo
+	case r > hi:
+		return hi
+	}
+	return r
+}
+
+// EvalMathRounded evaluates the genome as a floating-point expression and
+// returns the result rounded to the nearest multiple of step (with halfway
+// cases rounded away from zero), which suits quantized targets such as counts
+// or ratings. A step of 1 rounds to integers. A non-positive step leaves the
+// result unrounded, as do NaN and infinite results.
+func (g *Genome) EvalMathRounded(in []float64, step float64) float64 {
+	r := g.EvalMath(in)
+	if step <= 0 || !isFinite(r) {
+		return r
+	}
+	return math.Round(r/step) * step
+}
+
+// EvalProbability evaluates the genome as a floating-point expression and
+// passes the result through the logistic function, yielding a value in (0,1)
+// suitable for binary classification with a 0.5 threshold.
+// A NaN or infinite raw result maps to 0.5 (maximum uncertainty).
+func (g *Genome) EvalProbability(in []float64) float64 {
+	return probability(g.EvalMath(in))
+}
+
+// probability passes r through the logistic function, mapping NaN or Inf to 0.5.
+func probability(r float64) float64 {
+	if !isFinite(r) {
+		return 0.5
+	}
+	return 1.0 / (1.0 + math.Exp(-r))
+}
+
+// isFinite reports whether v is neither NaN nor infinite.
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
 // String returns the Karva representation of the genome.
 func (g Genome) String() string {
+	if len(g.Genes) == 0 {
+		return ""
+	}
 	result := []string{}
 	for _, v := range g.Genes {
 		result = append(result, v.String())
 	}
+	if g.Homeotic != nil {
+		return strings.Join(result, "|") + "|H|" + g.Homeotic.String()
+	}
 	return strings.Join(result, "|"+g.LinkFunc+"|")
 }
 
+// GeneStrings returns the Karva representation of each gene of the genome,
+// in order.
+func (g *Genome) GeneStrings() []string {
+	r := make([]string, len(g.Genes))
+	for i, v := range g.Genes {
+		r[i] = v.String()
+	}
+	return r
+}
+
 // Mutate mutates a genome by performing numMutations random symbol exchanges within the genome.
+// The homeotic gene (if any) is mutated like the others.
+// A frozen genome is left unchanged.
 func (g *Genome) Mutate(numMutations int) {
+	g.MutateWith(functions.DefaultRNG, numMutations)
+}
+
+// MutateWith is like Mutate, but draws its randomness from rng.
+func (g *Genome) MutateWith(rng functions.RNG, numMutations int) {
+	if g.Frozen {
+		return
+	}
 	for i := 0; i < numMutations; i++ {
-		n := rand.Intn(len(g.Genes))
+		if g.Homeotic != nil && rng.Intn(len(g.Genes)+1) == len(g.Genes) {
+			g.Homeotic.MutateWith(rng)
+			continue
+		}
+		n := rng.Intn(len(g.Genes))
 		// fmt.Printf("\nMutating gene #%v, before:\n%v\n", n, g.Genes[n])
-		g.Genes[n].Mutate()
+		g.Genes[n].MutateWith(rng)
 		// fmt.Printf("after:\n%v\n", g.Genes[n])
 	}
+	g.invalidate()
+}
+
+// AddGene grows the genome by appending a new, random gene having the same
+// structure as the existing genes. The linking function joins the new gene
+// to the others just like the rest. fm is the map of available functions and
+// is used to validate the new gene; if no valid gene can be made, the genome
+// is left unchanged. A frozen genome is left unchanged.
+func (g *Genome) AddGene(fm functions.FuncMap) {
+	g.AddGeneWith(functions.DefaultRNG, fm)
+}
+
+// AddGeneWith is like AddGene, but draws its randomness from rng.
+func (g *Genome) AddGeneWith(rng functions.RNG, fm functions.FuncMap) {
+	if g.Frozen {
+		return
+	}
+	if len(g.Genes) == 0 {
+		functions.Log.Printf("genome.AddGene error: genome has no genes to use as a template")
+		return
+	}
+	ng := g.Genes[rng.Intn(len(g.Genes))].RandomLikeWith(rng)
+	if ng == nil {
+		return
+	}
+	if err := ng.Validate(fm); err != nil {
+		functions.Log.Printf("genome.AddGene error: %v", err)
+		return
+	}
+	g.Genes = append(g.Genes, ng)
+	g.invalidate()
+}
+
+// RemoveGene shrinks the genome by removing a random gene.
+// A genome always keeps at least one gene. A frozen genome is left unchanged.
+func (g *Genome) RemoveGene() {
+	g.RemoveGeneWith(functions.DefaultRNG)
+}
+
+// RemoveGeneWith is like RemoveGene, but draws its randomness from rng.
+func (g *Genome) RemoveGeneWith(rng functions.RNG) {
+	if g.Frozen || len(g.Genes) < 2 {
+		return
+	}
+	n := rng.Intn(len(g.Genes))
+	g.Genes = append(g.Genes[:n], g.Genes[n+1:]...)
+	g.invalidate()
+}
+
+// OnePointRecombination performs a one-point recombination between genomes g1 and g2.
+// A point is chosen at random along the length of the chromosomes and all symbols
+// downstream of that point are exchanged between the two genomes.
+// Both genomes must have the same structure (number of genes and gene lengths).
+// Since the exchange alters both genomes, nothing is done if either is frozen;
+// to use a frozen genome as a parent, recombine a duplicate of it instead.
+func OnePointRecombination(g1, g2 *Genome) {
+	OnePointRecombinationWith(functions.DefaultRNG, g1, g2)
+}
+
+// OnePointRecombinationWith is like OnePointRecombination, but draws its
+// randomness from rng.
+func OnePointRecombinationWith(rng functions.RNG, g1, g2 *Genome) {
+	if g1 == nil || g2 == nil || len(g1.Genes) != len(g2.Genes) {
+		functions.Log.Printf("genome.OnePointRecombination error: genomes must be non-nil and have the same number of genes")
+		return
+	}
+	if g1.Frozen || g2.Frozen {
+		return
+	}
+	total := 0
+	for _, v := range g1.Genes {
+		total += len(v.Symbols)
+	}
+	if total == 0 {
+		return
+	}
+	point := rng.Intn(total)
+	for i := range g1.Genes {
+		n := len(g1.Genes[i].Symbols)
+		switch {
+		case point < 0: // Entire gene is downstream of the recombination point
+			g1.Genes[i], g2.Genes[i] = g2.Genes[i], g1.Genes[i]
+		case point < n:
+			gene.Recombine(g1.Genes[i], g2.Genes[i], point)
+		}
+		point -= n
+	}
+	g1.invalidate()
+	g2.invalidate()
 }
 
 // Dup duplicates the genome into the provided destination genome.
+// The duplicate is never frozen, so it may be altered freely.
 func (g *Genome) Dup() *Genome {
 	if g == nil {
-		log.Printf("denome.Dup error: src and dst must be non-nil")
+		functions.Log.Printf("denome.Dup error: src and dst must be non-nil")
 		return nil
 	}
 	dst := &Genome{
-		Genes:    make([]*gene.Gene, len(g.Genes)),
-		LinkFunc: g.LinkFunc,
-		Score:    g.Score,
+		Genes:         make([]*gene.Gene, len(g.Genes)),
+		LinkFunc:      g.LinkFunc,
+		Score:         g.Score,
+		MissingPolicy: g.MissingPolicy, // Policies are never altered, so may be shared.
+		funcs:         g.funcs,
+		symbolsOnce:   &sync.Once{},
 	}
 	for i := range g.Genes {
 		dst.Genes[i] = g.Genes[i].Dup()
 	}
+	if g.Homeotic != nil {
+		dst.Homeotic = g.Homeotic.Dup()
+	}
 	return dst
 }
 
@@ -135,8 +751,28 @@ type ScoringFunc func(g *Genome) float64
 // Evaluate scores a genome and sends the result to a channel.
 func (g *Genome) Evaluate(sf ScoringFunc, c chan<- *Genome) {
 	if sf == nil {
-		log.Fatalf("genome.Evaluate: ScoringFunc must not be nil")
+		functions.Log.Fatalf("genome.Evaluate: %v", ErrNilScoringFunc)
 	}
 	g.Score = sf(g)
 	c <- g
 }
+
+// Fitness returns the score of the genome by sf without assigning it to
+// g.Score (unlike Evaluate), which makes it suitable for computing other
+// metrics, such as the fitness on a holdout dataset, without disturbing the
+// score used for selection.
+func (g *Genome) Fitness(sf ScoringFunc) float64 {
+	if sf == nil {
+		functions.Log.Fatalf("genome.Fitness: %v", ErrNilScoringFunc)
+	}
+	return sf(g)
+}
+
+// FitnessSafe is like Fitness, but returns an error wrapping ErrNilScoringFunc
+// if sf is nil instead of stopping with a fatal error.
+func (g *Genome) FitnessSafe(sf ScoringFunc) (float64, error) {
+	if sf == nil {
+		return 0, fmt.Errorf("genome.FitnessSafe: %w", ErrNilScoringFunc)
+	}
+	return sf(g), nil
+}
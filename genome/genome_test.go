@@ -5,12 +5,19 @@
 package genome
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"log"
 	"math"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/gmlewis/gep/functions"
 	bn "github.com/gmlewis/gep/functions/bool_nodes"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
 	"github.com/gmlewis/gep/gene"
 )
 
@@ -843,6 +850,106 @@ func TestFuelConsumption(t *testing.T) {
 	}
 }
 
+// countingNode wraps a FuncNode and counts the number of times it is evaluated.
+type countingNode struct {
+	functions.FuncNode
+	calls *int
+}
+
+func (n countingNode) Float64Function(a, b, c, d float64) float64 {
+	*n.calls++
+	return n.FuncNode.Float64Function(a, b, c, d)
+}
+
+func TestSymbolCountLinkFunc(t *testing.T) {
+	tests := []struct {
+		genes []string
+	}{
+		{genes: []string{"*.d0.d1.d0"}},
+		{genes: []string{"*.d0.d1.d0", "-.d1.*.d0.d0"}},
+		{genes: []string{"*.d0.d1.d0", "-.d1.*.d0.d0", "d0.d1.d1", "*.-.d0.d1.d0.d0.d1"}},
+	}
+	orig := mn.Math["+"]
+	defer func() { mn.Math["+"] = orig }()
+	for _, test := range tests {
+		var genes []*gene.Gene
+		for _, v := range test.genes {
+			genes = append(genes, gene.New(v))
+		}
+		gn := New(genes, "+")
+		calls := 0
+		mn.Math["+"] = countingNode{FuncNode: orig, calls: &calls}
+		gn.EvalMath([]float64{1, 2})
+		if got := gn.SymbolCount("+"); got != calls {
+			t.Errorf("Genome %q SymbolCount(+) = %v, want %v link applications", gn, got, calls)
+		}
+		if _, ok := gn.SymbolMap["+"]; len(genes) == 1 && ok {
+			t.Errorf("Genome %q SymbolMap has link entry for single gene: %v", gn, gn.SymbolMap)
+		}
+	}
+}
+
+func TestCodingSymbolCount(t *testing.T) {
+	gn := New([]*gene.Gene{
+		gene.New("+.d0.d1.Sqrt.d0.d1.d1"),
+		gene.New("*.d1.+.d0.d0.Sqrt.d1"),
+	}, "+")
+	tests := []struct {
+		sym  string
+		want int
+	}{
+		{"+", 2},
+		{"*", 1},
+		{"Sqrt", 0}, // only found in the non-coding tails
+		{"d0", 3},
+		{"d1", 2},
+	}
+	for _, test := range tests {
+		if got := gn.CodingSymbolCount(test.sym); got != test.want {
+			t.Errorf("Genome %q CodingSymbolCount(%q) = %v, want %v", gn, test.sym, got, test.want)
+		}
+	}
+}
+
+func TestUsedInputs(t *testing.T) {
+	gn := New([]*gene.Gene{
+		gene.New("+.d2.d0.d1.d1"),
+		gene.New("*.d0.c0.d1.d1"),
+		gene.New("d2.d1.d1"),
+	}, "+")
+	gn.Genes[1].Constants = []float64{2}
+	if got, want := gn.UsedInputs(), []int{0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Genome %q UsedInputs = %v, want %v", gn, got, want)
+	}
+	if got := New([]*gene.Gene{gene.New("c0.d0")}, "+").UsedInputs(); got != nil {
+		t.Errorf("UsedInputs of constant genome = %v, want nil", got)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	gn := New([]*gene.Gene{
+		gene.New("+.d2.d0.d1.d1"),
+		gene.New("+.d0.+.d1.+.d2.d3"),
+		gene.New("d2.d1.d1"),
+	}, "+")
+	if got, want := gn.MaxDepth(), 5; got != want {
+		t.Errorf("Genome %q MaxDepth = %v, want %v", gn, got, want)
+	}
+	gn = New([]*gene.Gene{gene.New("d0")}, "+")
+	if got, want := gn.MaxDepth(), 1; got != want {
+		t.Errorf("Genome %q MaxDepth = %v, want %v", gn, got, want)
+	}
+}
+
+func TestSymbolCountAfterMutate(t *testing.T) {
+	gn := benchGenome(8)
+	gn.SymbolCount("+")
+	gn.Mutate(5)
+	if gn.SymbolMap != nil {
+		t.Errorf("Mutate left stale SymbolMap: %v", gn.SymbolMap)
+	}
+}
+
 func checkEqual(g1 *Genome, g2 *Genome) error {
 	if g1 == nil || g2 == nil {
 		return fmt.Errorf("genome.checkEqual error: g1 and g2 must be non-nil")
@@ -914,31 +1021,565 @@ func TestMutate(t *testing.T) {
 	}
 }
 
-func BenchmarkMutate(b *testing.B) {
+func TestZeroGenes(t *testing.T) {
+	gn := New(nil, "+")
+	if got := gn.EvalMath([]float64{1, 2}); got != 0 {
+		t.Errorf("EvalMath on zero-gene genome = %v, want 0", got)
+	}
+	if got := gn.SymbolCount("+"); got != 0 {
+		t.Errorf("SymbolCount on zero-gene genome = %v, want 0", got)
+	}
+	if got := gn.String(); got != "" {
+		t.Errorf("String on zero-gene genome = %q, want \"\"", got)
+	}
+	gn = New([]*gene.Gene{}, "Or")
+	if got := gn.EvalBool([]bool{true, true}, bn.BoolAllGates); got {
+		t.Errorf("EvalBool on zero-gene genome = %v, want false", got)
+	}
+}
+
+func TestAddRemoveGene(t *testing.T) {
+	in := []float64{1.5, -2.25, 3, 0.5, 4}
+	gn := benchGenome(8)
+	before := gn.EvalMath(in)
+	gn.AddGene(mn.Math)
+	if len(gn.Genes) != 5 {
+		t.Fatalf("AddGene: got %v genes, want 5", len(gn.Genes))
+	}
+	if err := gn.Validate(mn.Math); err != nil {
+		t.Errorf("AddGene: Validate = %v", err)
+	}
+	added := gn.Genes[4].EvalMath(in)
+	if got, want := gn.EvalMath(in), before+added; math.Abs(got-want) > 1e-9 {
+		t.Errorf("AddGene: EvalMath = %v, want %v", got, want)
+	}
+	for i := 0; i < 10; i++ {
+		gn.RemoveGene()
+		if err := gn.Validate(mn.Math); err != nil {
+			t.Errorf("RemoveGene: Validate = %v", err)
+		}
+		want := 0.0
+		for _, v := range gn.Genes {
+			want += v.EvalMath(in)
+		}
+		if got := gn.EvalMath(in); math.Abs(got-want) > 1e-9 {
+			t.Errorf("RemoveGene: EvalMath = %v, want %v", got, want)
+		}
+	}
+	if len(gn.Genes) != 1 {
+		t.Errorf("RemoveGene: got %v genes, want 1", len(gn.Genes))
+	}
+}
+
+func TestEvalMathClamped(t *testing.T) {
+	gn := New([]*gene.Gene{gene.New("/.d0.d1")}, "+")
+	tests := []struct {
+		in   []float64
+		want float64
+	}{
+		{in: []float64{1, 2}, want: 0.5}, // within range
+		{in: []float64{6, 2}, want: 1},   // above range
+		{in: []float64{-6, 2}, want: -1}, // below range
+		{in: []float64{0, 0}, want: -1},  // NaN
+		{in: []float64{1, 0}, want: 1},   // +Inf
+		{in: []float64{-1, 0}, want: -1}, // -Inf
+	}
+	for _, test := range tests {
+		if got := gn.EvalMathClamped(test.in, -1, 1); got != test.want {
+			t.Errorf("EvalMathClamped(%v, -1, 1) = %v, want %v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestNonCodingFraction(t *testing.T) {
+	tests := []struct {
+		g         *Genome
+		nonCoding int
+		want      float64
+	}{
+		{g: newGenome("+", "+.d0.d1.d0.d1"), nonCoding: 2, want: 0.4},                          // 3 of 5 coding
+		{g: newGenome("+", "d0.d1.d0.d1", "*.+.d0.d1.d0.d1.d0"), nonCoding: 5, want: 5.0 / 11}, // 1 + 5 of 11 coding
+		{g: newGenome("+", "*.d0.d1"), nonCoding: 0, want: 0},
+		{g: New(nil, "+"), nonCoding: 0, want: 0},
+	}
+	for _, test := range tests {
+		if got := test.g.NonCodingLength(); got != test.nonCoding {
+			t.Errorf("Genome %q NonCodingLength = %v, want %v", test.g, got, test.nonCoding)
+		}
+		if got := test.g.NonCodingFraction(); got != test.want {
+			t.Errorf("Genome %q NonCodingFraction = %v, want %v", test.g, got, test.want)
+		}
+	}
+}
+
+func TestSplitGenes(t *testing.T) {
+	withConstant := gene.New("*.c0.d1")
+	withConstant.Constants = []float64{2.5}
+	g := New([]*gene.Gene{gene.New("+.d0.d1"), withConstant, gene.New("Sqrt.d0")}, "-")
+	g.MissingPolicy = &MissingPolicy{Defaults: []float64{0, 4}}
+	homeotic := NewHomeotic([]*gene.Gene{
+		gene.New("*.d0.d1.d0.d0"),
+		gene.New("-.d0.d1.d0.d0"),
+	}, gene.New("+.d0./.d0.d1.d0.d0"))
+	for _, gn := range []*Genome{g, homeotic} {
+		split := gn.SplitGenes()
+		if len(split) != len(gn.Genes) {
+			t.Fatalf("%v SplitGenes = %v genomes, want %v", gn, len(split), len(gn.Genes))
+		}
+		for _, in := range [][]float64{{9, 2}, {4, -1}, {16}} {
+			want := gn.EvalMathPerGene(in)
+			for i, v := range split {
+				if got := v.EvalMath(in); got != want[i] {
+					t.Errorf("%v SplitGenes[%v].EvalMath(%v) = %v, want %v", gn, i, in, got, want[i])
+				}
+			}
+		}
+		for i, v := range split {
+			if len(v.Genes) != 1 || v.Genes[0] == gn.Genes[i] {
+				t.Errorf("%v SplitGenes[%v] = %v, want a single copied gene", gn, i, v)
+			}
+		}
+	}
+	split := g.SplitGenes()
+	split[1].Genes[0].Constants[0] = 7
+	if got := g.Genes[1].Constants[0]; got != 2.5 {
+		t.Errorf("altering a split genome altered the original constant: %v", got)
+	}
+	if got, want := g.EvalMathPerGene([]float64{9}), []float64{13, 10, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("EvalMathPerGene with missing input = %v, want %v", got, want)
+	}
+}
+
+func TestCombine(t *testing.T) {
+	a, b := newGenome("+", "*.d0.d1.d0"), newGenome("*", "-.d1.d0.d0")
+	for _, link := range []string{"+", "*", "Max2"} {
+		g, err := Combine([]*Genome{a, b}, link)
+		if err != nil {
+			t.Fatalf("Combine(%v) = %v", link, err)
+		}
+		lf := mn.Math[link]
+		for _, in := range [][]float64{{3, 5}, {-2, 1}} {
+			want := lf.Float64Function(a.EvalMath(in), b.EvalMath(in), 0, 0)
+			if got := g.EvalMath(in); got != want {
+				t.Errorf("Combine(%v).EvalMath(%v) = %v, want %v", link, in, got, want)
+			}
+		}
+		if g.Genes[0] == a.Genes[0] || g.Genes[1] == b.Genes[0] {
+			t.Errorf("Combine(%v) shares genes with its inputs", link)
+		}
+	}
+
+	// Combining the split genes restores the genome.
+	orig := newGenome("-", "*.d0.d1.d0", "+.d1.d1.d0", "Sqrt.d0.d0.d1")
+	g, err := Combine(orig.SplitGenes(), orig.LinkFunc)
+	if err != nil || g.String() != orig.String() {
+		t.Errorf("Combine(SplitGenes(%q)) = (%q, %v), want %q", orig, g, err, orig)
+	}
+
+	tests := []struct {
+		genomes []*Genome
+		link    string
+		want    error
+	}{
+		{genomes: nil, link: "+", want: ErrInvalidGenome},
+		{genomes: []*Genome{a, orig}, link: "+", want: ErrInvalidGenome},
+		{genomes: []*Genome{a, newGenome("+", "d0")}, link: "+", want: ErrInvalidGenome},
+		{genomes: []*Genome{a, b}, link: "Bogus", want: ErrMissingLinkFunc},
+	}
+	for i, test := range tests {
+		if g, err := Combine(test.genomes, test.link); !errors.Is(err, test.want) {
+			t.Errorf("%v: Combine = (%v, %v), want error matching %v", i, g, err, test.want)
+		}
+	}
+}
+
+func TestEvaluatedNodes(t *testing.T) {
+	tests := []struct {
+		g    *Genome
+		want int
+	}{
+		// Coding lengths 5, 3, and 1, plus 2 applications of the linking function.
+		{g: newGenome("+", "*.+.d0.d1.d0.d1.d0", "-.d0.d1.d0", "d0.d1.d0"), want: 5 + 3 + 1 + 2},
+		{g: newGenome("+", "Sqrt.d0.d1"), want: 2},
+		{
+			// Two ADFs of 3 nodes each, called by a homeotic gene of 5 nodes.
+			g: NewHomeotic([]*gene.Gene{
+				gene.New("*.d0.d1.d0.d0"),
+				gene.New("-.d0.d1.d0.d0"),
+			}, gene.New("+.d0./.d0.d1.d0.d0")),
+			want: 3 + 3 + 5,
+		},
+	}
+	for _, test := range tests {
+		if got := test.g.EvaluatedNodes(); got != test.want {
+			t.Errorf("Genome %q EvaluatedNodes = %v, want %v", test.g, got, test.want)
+		}
+		if got := test.g.EvalCost(); got != test.want {
+			t.Errorf("Genome %q EvalCost = %v, want %v", test.g, got, test.want)
+		}
+	}
+}
+
+func TestConcurrentCaches(t *testing.T) {
+	// Run with -race: the lazily built caches of a shared genome (and of its
+	// genes) must be safe to populate concurrently.
+	for n := 0; n < 20; n++ {
+		g := newGenome("+", "*.+.d0.d1.d0.d1.d0", "Sqrt.d1.d0", "-.d0.d1.d0")
+		want := (3.0+4)*3 + 2 + (3 - 4.0)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				switch i % 4 {
+				case 0:
+					if got := g.EvalMath([]float64{3, 4}); got != want {
+						t.Errorf("EvalMath = %v, want %v", got, want)
+					}
+				case 1:
+					if got := g.SymbolCount("+"); got != 3 { // Once in gene #0, and twice as the linking function.
+						t.Errorf("SymbolCount(+) = %v, want 3", got)
+					}
+				case 2:
+					if got := g.Genes[1].SymbolCount("Sqrt"); got != 1 {
+						t.Errorf("gene SymbolCount(Sqrt) = %v, want 1", got)
+					}
+				case 3:
+					if got := g.Genes[0].EvalMath([]float64{3, 4}); got != 21 {
+						t.Errorf("gene EvalMath = %v, want 21", got)
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	// The caches are rebuilt after a mutation.
+	g := newGenome("+", "d0.d1.d0", "d1.d0.d0")
+	if got := g.SymbolCount("+"); got != 1 {
+		t.Errorf("SymbolCount(+) = %v, want 1", got)
+	}
+	if got := g.EvalMath([]float64{1, 2}); got != 3 {
+		t.Errorf("EvalMath = %v, want 3", got)
+	}
+	if err := g.Genes[0].ReplaceSubtree(0, []string{"*", "d0", "d1"}); err != nil {
+		t.Fatalf("ReplaceSubtree = %v", err)
+	}
+	if got := g.Genes[0].EvalMath([]float64{3, 2}); got != 6 {
+		t.Errorf("gene EvalMath after ReplaceSubtree = %v, want 6", got)
+	}
+	g.RemoveGene()
+	if got := g.SymbolCount("+"); got != 0 {
+		t.Errorf("SymbolCount(+) after RemoveGene = %v, want 0", got)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	g := newGenome("+", "*.+.d0.d1.d0.d1.d0", "Sqrt.d1.d0", "-.d0.d1.d0")
+	h := NewHomeotic([]*gene.Gene{gene.New("*.d0.d1.d0.d0"), gene.New("-.d0.d1.d0.d0")}, gene.New("+.d0.d1.d0"))
+	in := []float64{3, 4}
+	for _, v := range []*Genome{g, h} {
+		v.Compile()
+		want, err := v.EvalMathSafe(in)
+		if err != nil {
+			t.Fatalf("EvalMathSafe = %v", err)
+		}
+		if got := v.EvalMath(in); got != want {
+			t.Errorf("compiled %q EvalMath = %v, want %v", v, got, want)
+		}
+		// Altering a gene discards its compiled expression.
+		if err := v.Genes[0].ReplaceSubtree(0, []string{"/", "d0", "d1"}); err != nil {
+			t.Fatalf("ReplaceSubtree = %v", err)
+		}
+		want, err = v.EvalMathSafe(in)
+		if err != nil {
+			t.Fatalf("EvalMathSafe after ReplaceSubtree = %v", err)
+		}
+		if got := v.EvalMath(in); got != want {
+			t.Errorf("EvalMath of %q after ReplaceSubtree = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestComplexity(t *testing.T) {
+	weights := map[string]float64{"+": 1, "Sin": 10, "Cos": 10, "d0": 0.5, "d1": 0.5}
+	additions := newGenome("+", "+.+.d0.d1.d0.d1.d0", "d1.d0")
+	trig := newGenome("+", "Sin.Cos.d0.d1.d0.d1.d0", "d1.d0")
+	tests := []struct {
+		g       *Genome
+		weights map[string]float64
+		want    float64
+	}{
+		{g: additions, weights: weights, want: 2 + 3*0.5 + 0.5},
+		{g: trig, weights: weights, want: 20 + 0.5 + 0.5},
+		{g: trig, weights: nil, want: 4}, // Unweighted, like CodingLength.
+		{g: newGenome("+", "*.d0.d2"), weights: weights, want: 1 + 0.5 + 1},
+	}
+	for _, test := range tests {
+		if got := test.g.Complexity(test.weights); got != test.want {
+			t.Errorf("Genome %q Complexity(%v) = %v, want %v", test.g, test.weights, got, test.want)
+		}
+	}
+	if a, b := additions.Complexity(weights), trig.Complexity(weights); b <= a {
+		t.Errorf("trig Complexity = %v, want more than additions Complexity = %v", b, a)
+	}
+}
+
+func TestEvalMathRounded(t *testing.T) {
+	gn := New([]*gene.Gene{gene.New("/.d0.d1")}, "+")
+	tests := []struct {
+		in   []float64
+		step float64
+		want float64
+	}{
+		{in: []float64{7, 2}, step: 1, want: 4}, // 3.5, halfway
+		{in: []float64{8, 3}, step: 1, want: 3}, // 2.667
+		{in: []float64{-7, 3}, step: 1, want: -2},
+		{in: []float64{7, 3}, step: 0.5, want: 2.5}, // 2.333
+		{in: []float64{8, 3}, step: 0.5, want: 2.5}, // 2.667
+		{in: []float64{9, 5}, step: 0.5, want: 2},   // 1.8
+		{in: []float64{1, 3}, step: 0, want: 1.0 / 3},
+		{in: []float64{1, 0}, step: 1, want: math.Inf(1)},
+	}
+	for _, test := range tests {
+		if got := gn.EvalMathRounded(test.in, test.step); got != test.want {
+			t.Errorf("EvalMathRounded(%v, %v) = %v, want %v", test.in, test.step, got, test.want)
+		}
+	}
+	if got := gn.EvalMathRounded([]float64{0, 0}, 1); !math.IsNaN(got) {
+		t.Errorf("EvalMathRounded(0/0) = %v, want NaN", got)
+	}
+}
+
+func TestEvalProbability(t *testing.T) {
+	gn := New([]*gene.Gene{gene.New("/.d0.d1")}, "+")
+	tests := []struct {
+		in        []float64
+		want, eps float64
+	}{
+		{in: []float64{0, 1}, want: 0.5},
+		{in: []float64{100, 1}, want: 1, eps: 1e-12},
+		{in: []float64{-100, 1}, want: 0, eps: 1e-12},
+		{in: []float64{0, 0}, want: 0.5}, // NaN
+		{in: []float64{1, 0}, want: 0.5}, // +Inf
+	}
+	for _, test := range tests {
+		if got := gn.EvalProbability(test.in); math.Abs(got-test.want) > test.eps {
+			t.Errorf("EvalProbability(%v) = %v, want %v", test.in, got, test.want)
+		}
+	}
+	if got := gn.EvalProbability([]float64{3, 1}); got <= 0.5 || got >= 1 {
+		t.Errorf("EvalProbability(3) = %v, want in (0.5, 1)", got)
+	}
+}
+
+func TestOnePointRecombination(t *testing.T) {
 	headSize := 7
 	maxArity := 2
 	tailSize := headSize*(maxArity-1) + 1
 	numTerminals := 5
-	numConstants := 5
+	funcs := []gene.FuncWeight{
+		{"Not", 1},
+		{"And", 5},
+		{"Or", 5},
+	}
+	newGenome := func() *Genome {
+		return New([]*gene.Gene{
+			gene.RandomNew(headSize, tailSize, numTerminals, 0, funcs),
+			gene.RandomNew(headSize, tailSize, numTerminals, 0, funcs),
+			gene.RandomNew(headSize, tailSize, numTerminals, 0, funcs),
+		},
+			"And")
+	}
+	g1, g2 := newGenome(), newGenome()
+	before := g1.String() + g2.String()
+	count := func(g *Genome) map[string]int {
+		m := map[string]int{}
+		for _, v := range g.Genes {
+			for _, sym := range v.Symbols {
+				m[sym]++
+			}
+		}
+		return m
+	}
+	c1, c2 := count(g1), count(g2)
+	for k, v := range c2 {
+		c1[k] += v
+	}
+	OnePointRecombination(g1, g2)
+	if got := g1.String() + g2.String(); len(got) != len(before) {
+		t.Errorf("OnePointRecombination changed total length: got %v, want %v", len(got), len(before))
+	}
+	a1, a2 := count(g1), count(g2)
+	for k, v := range a2 {
+		a1[k] += v
+	}
+	if !reflect.DeepEqual(a1, c1) {
+		t.Errorf("OnePointRecombination symbol counts = %v, want %v", a1, c1)
+	}
+	for i := range g1.Genes {
+		if len(g1.Genes[i].Symbols) != headSize+tailSize || len(g2.Genes[i].Symbols) != headSize+tailSize {
+			t.Errorf("OnePointRecombination gene #%v has wrong length", i)
+		}
+	}
+}
+
+func TestFrozen(t *testing.T) {
 	funcs := []gene.FuncWeight{
 		{"+", 1},
-		{"-", 5},
-		{"*", 5},
+		{"*", 1},
 	}
-	g1 := gene.RandomNew(headSize, tailSize, numTerminals, numConstants, funcs)
-	g2 := gene.RandomNew(headSize, tailSize, numTerminals, numConstants, funcs)
-	g3 := gene.RandomNew(headSize, tailSize, numTerminals, numConstants, funcs)
-	g4 := gene.RandomNew(headSize, tailSize, numTerminals, numConstants, funcs)
-	g := New([]*gene.Gene{g1, g2, g3, g4}, "+")
-	for i := 0; i < b.N; i++ {
-		g.Mutate(1)
+	newRandom := func() *Genome {
+		return New([]*gene.Gene{
+			gene.RandomNew(5, 6, 2, 0, funcs),
+			gene.RandomNew(5, 6, 2, 0, funcs),
+		}, "+")
+	}
+	gn := newRandom()
+	gn.Frozen = true
+	want := gn.String()
+	other := newRandom()
+	for i := 0; i < 100; i++ {
+		gn.Mutate(3)
+		gn.AddGene(mn.Math)
+		gn.RemoveGene()
+		OnePointRecombination(gn, other)
+		OnePointRecombination(other, gn)
+	}
+	if got := gn.String(); got != want {
+		t.Errorf("frozen genome changed from %q to %q", want, got)
+	}
+	dup := gn.Dup()
+	if dup.Frozen {
+		t.Errorf("Dup of frozen genome is frozen")
+	}
+	dup.Mutate(3)
+	if dup.String() == want {
+		t.Errorf("Dup of frozen genome unchanged after Mutate")
 	}
 }
 
-var result *Genome
+func TestLinkPresets(t *testing.T) {
+	genes := func() []*gene.Gene {
+		return []*gene.Gene{gene.New("+.d0.d1"), gene.New("*.d0.d1"), gene.New("d1")}
+	}
+	in := []float64{2, 3}
+	if got, want := NewAdditive(genes()).EvalMath(in), 5.0+6+3; got != want {
+		t.Errorf("NewAdditive EvalMath = %v, want %v", got, want)
+	}
+	if got, want := NewMultiplicative(genes()).EvalMath(in), 5.0*6*3; got != want {
+		t.Errorf("NewMultiplicative EvalMath = %v, want %v", got, want)
+	}
+	for _, gn := range []*Genome{NewAdditive(genes()), NewMultiplicative(genes())} {
+		if err := gn.Validate(mn.Math); err != nil {
+			t.Errorf("Genome %q Validate = %v", gn, err)
+		}
+	}
+}
 
-func BenchmarkDup(b *testing.B) {
-	headSize := 7
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(l functions.Logger) { functions.Log = l }(functions.Log)
+	functions.Log = log.New(&buf, "", 0)
+	gn := New([]*gene.Gene{gene.New("+.d0.d1")}, "Bogus")
+	if got := gn.EvalMath([]float64{1, 2}); got != 0 {
+		t.Errorf("EvalMath with missing linking function = %v, want 0", got)
+	}
+	if want := "unable to find linking function"; !strings.Contains(strings.ToLower(buf.String()), want) {
+		t.Errorf("custom logger captured %q, want message containing %q", buf.String(), want)
+	}
+}
+
+func TestEvalMathSafe(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(l functions.Logger) { functions.Log = l }(functions.Log)
+	functions.Log = log.New(&buf, "", 0)
+
+	in := []float64{1.5, -2, 3, 0.25, 7}
+	for i := 0; i < 50; i++ {
+		gn := benchGenome(8)
+		got, err := gn.EvalMathSafe(in)
+		if err != nil {
+			t.Fatalf("Genome %q EvalMathSafe = %v", gn, err)
+		}
+		if want := gn.EvalMath(in); got != want {
+			t.Errorf("Genome %q EvalMathSafe = %v, want %v", gn, got, want)
+		}
+	}
+	buf.Reset()
+
+	tests := []*Genome{
+		New([]*gene.Gene{gene.New("+.d0.d1")}, "Bogus"), // missing linking function
+		New(nil, "+"),
+		New([]*gene.Gene{gene.New("+.d0.d9")}, "+"), // input out of range
+		New([]*gene.Gene{gene.New("+.d0")}, "+"),    // incomplete expression
+		New([]*gene.Gene{gene.New("d0"), {Symbols: []string{"*", "d0", "Bogus"}}}, "+"),
+	}
+	for i, gn := range tests {
+		if got, err := gn.EvalMathSafe(in); err == nil {
+			t.Errorf("%v: Genome %q EvalMathSafe = %v, want error", i, gn, got)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("EvalMathSafe logged %q, want no log output", buf.String())
+	}
+
+	gn := benchGenome(8)
+	if allocs := testing.AllocsPerRun(100, func() { gn.EvalMathSafe(in) }); allocs != 0 {
+		t.Errorf("EvalMathSafe allocs = %v, want 0", allocs)
+	}
+}
+
+func TestOutOfRangeInputs(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(l functions.Logger) { functions.Log = l }(functions.Log)
+	functions.Log = log.New(&buf, "", 0)
+
+	// The genome was trained on four inputs, but only two are supplied.
+	gn := New([]*gene.Gene{gene.New("+.d0.d3"), gene.New("*.d1.d1")}, "+")
+	in := []float64{2, 3}
+	if err := gn.CheckInputs(len(in)); err == nil {
+		t.Errorf("CheckInputs(%v) = nil, want error", len(in))
+	}
+	if err := gn.CheckInputs(4); err != nil {
+		t.Errorf("CheckInputs(4) = %v, want nil", err)
+	}
+	if got := gn.EvalMath(in); got != 2+0+3*3 {
+		t.Errorf("EvalMath(%v) = %v, want %v", in, got, 2+0+3*3)
+	}
+	if !strings.Contains(buf.String(), "index 3") {
+		t.Errorf("EvalMath logged %q, want message about index 3", buf.String())
+	}
+	if got, err := gn.EvalMathSafe(in); err == nil {
+		t.Errorf("EvalMathSafe(%v) = %v, want error", in, got)
+	}
+	if got := gn.EvalMathBatchMemo([][]float64{in}); got[0] != 2+0+3*3 {
+		t.Errorf("EvalMathBatchMemo(%v) = %v, want %v", in, got, 2+0+3*3)
+	}
+	if got, err := EvalIR(gn.ToIR(), in); !errors.Is(err, ErrInvalidIR) {
+		t.Errorf("EvalIR(%v) = (%v, %v), want ErrInvalidIR", in, got, err)
+	}
+
+	// A MissingPolicy may supply the absent inputs instead.
+	gn.MissingPolicy = &MissingPolicy{Defaults: []float64{0, 0, math.NaN(), 10}}
+	if err := gn.CheckInputs(len(in)); err != nil {
+		t.Errorf("CheckInputs(%v) with defaults = %v, want nil", len(in), err)
+	}
+	want := 2 + 10 + 3*3.0
+	if got, err := gn.EvalMathSafe(in); err != nil || got != want {
+		t.Errorf("EvalMathSafe(%v) with defaults = (%v, %v), want %v", in, got, err, want)
+	}
+	if got := gn.EvalMathBatchMemo([][]float64{in}); got[0] != want {
+		t.Errorf("EvalMathBatchMemo(%v) with defaults = %v, want %v", in, got, want)
+	}
+	gn.MissingPolicy.Defaults = gn.MissingPolicy.Defaults[:3]
+	if got, err := gn.EvalMathSafe(in); err == nil {
+		t.Errorf("EvalMathSafe(%v) without default for d3 = %v, want error", in, got)
+	}
+}
+
+// benchGenome returns a random four-gene math genome of the given head size.
+func benchGenome(headSize int) *Genome {
 	maxArity := 2
 	tailSize := headSize*(maxArity-1) + 1
 	numTerminals := 5
@@ -952,10 +1593,146 @@ func BenchmarkDup(b *testing.B) {
 	g2 := gene.RandomNew(headSize, tailSize, numTerminals, numConstants, funcs)
 	g3 := gene.RandomNew(headSize, tailSize, numTerminals, numConstants, funcs)
 	g4 := gene.RandomNew(headSize, tailSize, numTerminals, numConstants, funcs)
-	g := New([]*gene.Gene{g1, g2, g3, g4}, "+")
-	var v *Genome
-	for i := 0; i < b.N; i++ {
-		v = g.Dup()
+	return New([]*gene.Gene{g1, g2, g3, g4}, "+")
+}
+
+var benchSizes = []struct {
+	name     string
+	headSize int
+}{
+	{"head8", 8},
+	{"head40", 40},
+}
+
+func BenchmarkMutate(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			g := benchGenome(size.headSize)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				g.Mutate(1)
+			}
+		})
+	}
+}
+
+var result *Genome
+
+func BenchmarkDup(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			g := benchGenome(size.headSize)
+			b.ReportAllocs()
+			var v *Genome
+			for i := 0; i < b.N; i++ {
+				v = g.Dup()
+			}
+			result = v
+		})
+	}
+}
+
+var evalResult float64
+
+func BenchmarkEvalMath(b *testing.B) {
+	in := []float64{1.5, -2.25, 3, 0.5, 4}
+	for _, size := range benchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			g := benchGenome(size.headSize)
+			g.Compile() // Build the cached expression trees before timing.
+			b.ReportAllocs()
+			b.ResetTimer()
+			var v float64
+			for i := 0; i < b.N; i++ {
+				v = g.EvalMath(in)
+			}
+			evalResult = v
+		})
+	}
+}
+
+func BenchmarkRecombination(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			g1, g2 := benchGenome(size.headSize), benchGenome(size.headSize)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				OnePointRecombination(g1, g2)
+			}
+		})
+	}
+}
+
+func TestOperatorsKeepLinkFunc(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		g1, g2 := benchGenome(8), benchGenome(8)
+		g1.LinkFunc, g2.LinkFunc = "*", "-"
+		g1.Mutate(5)
+		g1.AddGene(mn.Math)
+		g1.RemoveGene()
+		OnePointRecombination(g1, g2)
+		dup := g1.Dup()
+		if g1.LinkFunc != "*" || g2.LinkFunc != "-" || dup.LinkFunc != "*" {
+			t.Fatalf("operators altered LinkFunc: %q, %q, Dup %q; want *, -, *", g1.LinkFunc, g2.LinkFunc, dup.LinkFunc)
+		}
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	g := newGenome("+", "+.d0.d1.d1.d0", "*.-.d2.d0.d1.d0.d2", "d1")
+	want := [][]string{
+		{"+", "d0", "d1", "d1", "d0"},
+		{"*", "-", "d2", "d0", "d1", "d0", "d2"},
+		{"d1"},
+	}
+	if got := g.Symbols(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Symbols = %v, want %v", got, want)
+	}
+	linear := []string{"+", "d0", "d1", "d1", "d0", "*", "-", "d2", "d0", "d1", "d0", "d2", "d1"}
+	if got := g.LinearSymbols(); !reflect.DeepEqual(got, linear) {
+		t.Errorf("LinearSymbols = %v, want %v", got, linear)
+	}
+
+	// The results are copies that reflect later changes to the genome.
+	g.Symbols()[0][0] = "Bogus"
+	g.LinearSymbols()[1] = "Bogus"
+	g.Genes[1].Symbols[1] = "+"
+	want[1][1], linear[6] = "+", "+"
+	if got := g.Symbols(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Symbols after change = %v, want %v", got, want)
+	}
+	if got := g.LinearSymbols(); !reflect.DeepEqual(got, linear) {
+		t.Errorf("LinearSymbols after change = %v, want %v", got, linear)
+	}
+}
+
+func TestFitness(t *testing.T) {
+	g := newConstGene("+.*.c1.c0.d0", 2, 1) // 2*x + 1
+	g.Score = 123
+	holdout := &Dataset{Inputs: [][]float64{{10}, {20}}, Targets: []float64{21, 41}}
+	if got, want := g.Fitness(RMSE(holdout)), 1000.0; got != want {
+		t.Errorf("Fitness = %v, want %v", got, want)
+	}
+	if g.Score != 123 {
+		t.Errorf("Fitness changed Score to %v, want 123", g.Score)
+	}
+}
+
+func TestGeneStrings(t *testing.T) {
+	g := benchGenome(8)
+	for i := 0; i < 3; i++ {
+		got := g.GeneStrings()
+		if len(got) != len(g.Genes) {
+			t.Fatalf("GeneStrings returned %v strings, want %v", len(got), len(g.Genes))
+		}
+		for j, v := range g.Genes {
+			if got[j] != v.String() {
+				t.Errorf("GeneStrings()[%v] = %q, want %q", j, got[j], v)
+			}
+		}
+		if want := strings.Join(got, "|+|"); g.String() != want {
+			t.Errorf("GeneStrings joined = %q, want String %q", want, g)
+		}
+		g.Mutate(3)
 	}
-	result = v
 }
@@ -0,0 +1,47 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import "github.com/gmlewis/gep/functions"
+
+// maxGridPoints is the largest number of points that EvalGrid will evaluate.
+const maxGridPoints = 1 << 20
+
+// EvalGrid evaluates the genome (with EvalMathBatch) over a regular grid of
+// inputs, as used for contour or surface plots of the evolved function.
+// Input i takes steps[i] evenly spaced values from ranges[i][0] to ranges[i][1]
+// inclusive (or just ranges[i][0] if steps[i] is 1). It returns the points of
+// the grid, with the last input varying fastest, and the output at each point.
+// If ranges and steps differ in length, a step is not positive, or the grid
+// would have more than 1<<20 points, the error is logged and nil is returned.
+func (g *Genome) EvalGrid(ranges [][2]float64, steps []int) ([][]float64, []float64) {
+	if len(ranges) != len(steps) {
+		functions.Log.Printf("genome.EvalGrid error: %v ranges and %v steps must match", len(ranges), len(steps))
+		return nil, nil
+	}
+	n := 1
+	for _, s := range steps {
+		if s <= 0 || n > maxGridPoints/s {
+			functions.Log.Printf("genome.EvalGrid error: steps %v must be positive and give at most %v points", steps, maxGridPoints)
+			return nil, nil
+		}
+		n *= s
+	}
+	points := make([][]float64, n)
+	for p := range points {
+		points[p] = make([]float64, len(steps))
+		k := p
+		for i := len(steps) - 1; i >= 0; i-- {
+			j := k % steps[i]
+			k /= steps[i]
+			lo, hi := ranges[i][0], ranges[i][1]
+			points[p][i] = lo
+			if steps[i] > 1 {
+				points[p][i] = lo + (hi-lo)*float64(j)/float64(steps[i]-1)
+			}
+		}
+	}
+	return points, g.EvalMathBatch(points)
+}
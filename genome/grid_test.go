@@ -0,0 +1,48 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvalGrid(t *testing.T) {
+	g := newConstGene("+.*.c1.c0.d0", 2, 1) // 2*x + 1
+	points, outputs := g.EvalGrid([][2]float64{{-1, 1}}, []int{5})
+	if want := [][]float64{{-1}, {-0.5}, {0}, {0.5}, {1}}; !reflect.DeepEqual(points, want) {
+		t.Errorf("EvalGrid points = %v, want %v", points, want)
+	}
+	if want := []float64{-1, 0, 1, 2, 3}; !reflect.DeepEqual(outputs, want) {
+		t.Errorf("EvalGrid outputs = %v, want %v", outputs, want)
+	}
+
+	// The last input varies fastest.
+	g = newGenome("+", "-.d0.d1")
+	points, outputs = g.EvalGrid([][2]float64{{0, 1}, {10, 30}}, []int{2, 3})
+	if want := [][]float64{{0, 10}, {0, 20}, {0, 30}, {1, 10}, {1, 20}, {1, 30}}; !reflect.DeepEqual(points, want) {
+		t.Errorf("EvalGrid 2-D points = %v, want %v", points, want)
+	}
+	if want := []float64{-10, -20, -30, -9, -19, -29}; !reflect.DeepEqual(outputs, want) {
+		t.Errorf("EvalGrid 2-D outputs = %v, want %v", outputs, want)
+	}
+	if points, _ := g.EvalGrid([][2]float64{{3, 5}}, []int{1}); !reflect.DeepEqual(points, [][]float64{{3}}) {
+		t.Errorf("EvalGrid with one step = %v, want [[3]]", points)
+	}
+
+	errTests := []struct {
+		ranges [][2]float64
+		steps  []int
+	}{
+		{ranges: [][2]float64{{0, 1}}, steps: []int{2, 2}},
+		{ranges: [][2]float64{{0, 1}}, steps: []int{0}},
+		{ranges: [][2]float64{{0, 1}, {0, 1}, {0, 1}}, steps: []int{1000, 1000, 1000}},
+	}
+	for i, test := range errTests {
+		if points, outputs := g.EvalGrid(test.ranges, test.steps); points != nil || outputs != nil {
+			t.Errorf("%v: EvalGrid(%v, %v) = %v points, want nil", i, test.ranges, test.steps, len(points))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/gmlewis/gep/gene"
+)
+
+// NewHomeotic creates a new genome whose genes are automatically defined
+// functions (ADFs) called by the homeotic gene. Each ADF is evaluated with the
+// inputs of the genome, and the homeotic gene is then evaluated with the
+// results of the ADFs as its inputs: its terminal d0 is the result of adfs[0],
+// d1 that of adfs[1], and so on. An ADF may therefore be called any number of
+// times by the homeotic gene. The genome has no linking function, since the
+// homeotic gene determines how the ADFs are combined.
+//
+// Only one level of ADF calls is supported: the ADFs themselves refer only to
+// the inputs of the genome, not to each other.
+func NewHomeotic(adfs []*gene.Gene, homeotic *gene.Gene) *Genome {
+	return &Genome{Genes: adfs, Homeotic: homeotic, symbolsOnce: &sync.Once{}}
+}
+
+// adfResults evaluates the ADFs of a homeotic genome with the inputs in.
+func (g *Genome) adfResults(in []float64) []float64 {
+	result := make([]float64, len(g.Genes))
+	for i, v := range g.Genes {
+		result[i] = v.EvalMath(in)
+	}
+	return result
+}
+
+// evalHomeoticSafe is like EvalMathSafe for a homeotic genome.
+func (g *Genome) evalHomeoticSafe(in []float64) (float64, error) {
+	adfs := make([]float64, len(g.Genes))
+	for i, v := range g.Genes {
+		r, err := v.EvalMathSafe(in)
+		if err != nil {
+			return 0, invalidGene(fmt.Sprintf("ADF #%v", i), err)
+		}
+		adfs[i] = r
+	}
+	result, err := g.Homeotic.EvalMathSafe(adfs)
+	if err != nil {
+		return 0, invalidGene("homeotic gene", err)
+	}
+	return result, nil
+}
+
+// LinkTree returns the expression tree by which the genome combines the
+// results of its genes, whose leaves "d0", "d1", ... stand for the results of
+// genes #0, #1, and so on (as the terminals of a homeotic gene do).
+// For a homeotic genome it is the expression tree of the homeotic gene; for
+// any other genome it is the left-leaning tree of the linking function, so
+// that the genes of a 3-gene additive genome are combined by (d0 + d1) + d2.
+// It returns nil if the genome has no genes.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) LinkTree() *gene.ExprNode {
+	if g.Homeotic != nil {
+		return g.Homeotic.Tree(g.mathNodes())
+	}
+	if len(g.Genes) == 0 {
+		return nil
+	}
+	result := node("d0")
+	for i := 1; i < len(g.Genes); i++ {
+		result = node(g.LinkFunc, result, node("d"+strconv.Itoa(i)))
+	}
+	return result
+}
+
+// homeoticDepth returns the depth of the subtree n of the link tree of a
+// homeotic genome once the ADFs (the genes) are substituted for its leaves.
+func (g *Genome) homeoticDepth(n *gene.ExprNode) int {
+	if n == nil {
+		return 0
+	}
+	if len(n.Args) == 0 {
+		if kind, index, err := gene.ParseTerminal(n.Symbol); err == nil && kind == "d" && index < len(g.Genes) {
+			return g.Genes[index].Depth()
+		}
+		return 1
+	}
+	max := 0
+	for _, v := range n.Args {
+		if d := g.homeoticDepth(v); d > max {
+			max = d
+		}
+	}
+	return max + 1
+}
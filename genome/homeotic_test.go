@@ -0,0 +1,187 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"reflect"
+	"testing"
+
+	bn "github.com/gmlewis/gep/functions/bool_nodes"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+func TestHomeotic(t *testing.T) {
+	// ADF0 = d0*d1 and ADF1 = d0-d1; the homeotic gene computes
+	// ADF0 + ADF0/ADF1, calling ADF0 twice.
+	g := NewHomeotic([]*gene.Gene{
+		gene.New("*.d0.d1.d0.d0"),
+		gene.New("-.d0.d1.d0.d0"),
+	}, gene.New("+.d0./.d0.d1.d0.d0"))
+	tests := []struct {
+		in   []float64
+		want float64
+	}{
+		{in: []float64{3, 1}, want: 3 + 3.0/2},
+		{in: []float64{4, 2}, want: 8 + 8.0/2},
+		{in: []float64{-1, 5}, want: -5 + -5.0/-6},
+	}
+	for _, test := range tests {
+		if got := g.EvalMath(test.in); got != test.want {
+			t.Errorf("EvalMath(%v) = %v, want %v", test.in, got, test.want)
+		}
+		if got, err := g.EvalMathSafe(test.in); err != nil || got != test.want {
+			t.Errorf("EvalMathSafe(%v) = (%v, %v), want %v", test.in, got, err, test.want)
+		}
+	}
+	if got, want := g.String(), "*.d0.d1.d0.d0|-.d0.d1.d0.d0|H|+.d0./.d0.d1.d0.d0"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+
+	dup := g.Dup()
+	if dup.Homeotic == g.Homeotic {
+		t.Fatal("Dup shares the homeotic gene of the original")
+	}
+	if got, want := dup.EvalMath(tests[0].in), tests[0].want; got != want {
+		t.Errorf("Dup EvalMath(%v) = %v, want %v", tests[0].in, got, want)
+	}
+
+	bad := NewHomeotic([]*gene.Gene{gene.New("*.d0.d1.d0.d0")}, gene.New("+.d0"))
+	if _, err := bad.EvalMathSafe([]float64{1, 2}); err == nil {
+		t.Errorf("EvalMathSafe of incomplete homeotic gene = nil, want error")
+	}
+}
+
+func TestHomeoticSymbolCount(t *testing.T) {
+	// The homeotic gene ADF0 + ADF0/ADF1*c0 counts its own functions and
+	// constant, while d0 and d1 are counted only as the inputs of the ADFs.
+	homeotic := gene.New("+.d0.*./.c0.d0.d1.d0.d0")
+	homeotic.Constants = []float64{2}
+	g := NewHomeotic([]*gene.Gene{
+		gene.New("*.d0.d1.d0.d0"),
+		gene.New("-.d0.d1.d0.d0"),
+	}, homeotic)
+	want := map[string]int{"*": 2, "-": 1, "+": 1, "/": 1, "c0": 1, "d0": 2, "d1": 2}
+	for sym, n := range want {
+		if got := g.SymbolCount(sym); got != n {
+			t.Errorf("SymbolCount(%q) = %v, want %v", sym, got, n)
+		}
+	}
+	if !reflect.DeepEqual(g.SymbolMap, want) {
+		t.Errorf("SymbolMap = %v, want %v", g.SymbolMap, want)
+	}
+	if got := g.SymbolCount(g.LinkFunc); got != 0 {
+		t.Errorf("SymbolCount(LinkFunc %q) = %v, want 0", g.LinkFunc, got)
+	}
+	// An additive genome counts the linking function once per join.
+	if got := newGenome("+", "*.d0.d1", "-.d0.d1", "Sin.d0").SymbolCount("+"); got != 2 {
+		t.Errorf("additive SymbolCount(+) = %v, want 2", got)
+	}
+}
+
+func TestHomeoticStructure(t *testing.T) {
+	// ADF0 = d0*d1 and ADF1 = d0-d2; the homeotic gene computes ADF0 + ADF0/ADF0.
+	g := NewHomeotic([]*gene.Gene{
+		gene.New("*.d0.d1.d0.d0"),
+		gene.New("-.d0.d2.d0.d0"),
+	}, gene.New("+.d0./.d0.d0.d1.d0"))
+	if err := g.Validate(mn.Math); err != nil {
+		t.Errorf("Validate = %v", err)
+	}
+	if got, want := g.CodingLength(), 3+3+5; got != want {
+		t.Errorf("CodingLength = %v, want %v", got, want)
+	}
+	if got, want := g.EvaluatedNodes(), 3+3+5; got != want {
+		t.Errorf("EvaluatedNodes = %v, want %v", got, want)
+	}
+	// The ADF at depth 3 of the homeotic gene adds a level of its own.
+	if got, want := g.MaxDepth(), 4; got != want {
+		t.Errorf("MaxDepth = %v, want %v", got, want)
+	}
+	// ADF1 (which uses d2) is never called.
+	if got, want := g.UsedInputs(), []int{0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UsedInputs = %v, want %v", got, want)
+	}
+
+	bad := NewHomeotic(g.Genes, gene.New("+.d0.d2.d0"))
+	if err := bad.Validate(mn.Math); err == nil {
+		t.Error("Validate of homeotic gene calling a missing ADF = nil, want error")
+	}
+	bad = NewHomeotic(g.Genes, gene.New("+.d0.Bogus"))
+	if err := bad.Validate(mn.Math); err == nil {
+		t.Error("Validate of invalid homeotic gene = nil, want error")
+	}
+
+	// Or(d0, d1) And Not(And(d0, d1)) is Xor.
+	xor := NewHomeotic([]*gene.Gene{gene.New("And.d0.d1"), gene.New("Or.d0.d1")}, gene.New("And.d1.Not.d0"))
+	for _, in := range [][]bool{{false, false}, {false, true}, {true, false}, {true, true}} {
+		if got, want := xor.EvalBool(in, bn.BoolAllGates), in[0] != in[1]; got != want {
+			t.Errorf("EvalBool(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestLinkTree(t *testing.T) {
+	g := NewAdditive([]*gene.Gene{
+		gene.New("*.d0.d1.d0.d0"),
+		gene.New("-.d0.d1.d0.d0"),
+		gene.New("+.d0.d1.d0.d0"),
+	})
+	d := func(s string) *gene.ExprNode { return &gene.ExprNode{Symbol: s} }
+	want := &gene.ExprNode{Symbol: "+", Args: []*gene.ExprNode{
+		{Symbol: "+", Args: []*gene.ExprNode{d("d0"), d("d1")}},
+		d("d2"),
+	}}
+	if got := g.LinkTree(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LinkTree = %v, want %v", got, want)
+	}
+
+	h := NewHomeotic(g.Genes[:2], gene.New("*.d1.d0.d0"))
+	want = &gene.ExprNode{Symbol: "*", Args: []*gene.ExprNode{d("d1"), d("d0")}}
+	if got := h.LinkTree(); !reflect.DeepEqual(got, want) {
+		t.Errorf("homeotic LinkTree = %v, want %v", got, want)
+	}
+
+	if got := New(nil, "+").LinkTree(); got != nil {
+		t.Errorf("LinkTree of genome without genes = %v, want nil", got)
+	}
+}
+
+func TestHomeoticCanonical(t *testing.T) {
+	// The genomes share their ADFs (ADF0 = d0 and ADF1 = d1) and differ only in
+	// their homeotic genes, ADF0 - ADF1 and ADF1 + ADF1.
+	adfs := []*gene.Gene{gene.New("d0"), gene.New("d1")}
+	sub := NewHomeotic(adfs, gene.New("-.d0.d1"))
+	add := NewHomeotic(adfs, gene.New("+.d1.d1"))
+	if sub.CanonicalHash() == add.CanonicalHash() {
+		t.Errorf("CanonicalHash of %v == that of %v, want different", sub.Homeotic, add.Homeotic)
+	}
+	// The ADFs are called by index, so their order matters.
+	swapped := NewHomeotic([]*gene.Gene{adfs[1], adfs[0]}, gene.New("-.d0.d1"))
+	if sub.CanonicalHash() == swapped.CanonicalHash() {
+		t.Errorf("CanonicalHash ignores the order of the ADFs")
+	}
+	if again := NewHomeotic(adfs, gene.New("-.d0.d1.d0")); sub.CanonicalHash() != again.CanonicalHash() {
+		t.Errorf("CanonicalHash depends upon the non-coding region of the homeotic gene")
+	}
+
+	in := []float64{5, 6}
+	sf := NewEvalCache().Wrap(func(g *Genome) float64 { return g.EvalMath(in) })
+	if got := sf(sub); got != -1 {
+		t.Errorf("cached score of %v = %v, want -1", sub.Homeotic, got)
+	}
+	if got := sf(add); got != 12 {
+		t.Errorf("cached score of %v = %v, want 12", add.Homeotic, got)
+	}
+
+	// The subtrees are d0, d1, and those of the homeotic gene: d0, d1, and
+	// the root, or d1 and the root.
+	if got, want := sub.DistinctSubtrees(), 2+3; got != want {
+		t.Errorf("DistinctSubtrees of %v = %v, want %v", sub.Homeotic, got, want)
+	}
+	if got, want := add.DistinctSubtrees(), 2+2; got != want {
+		t.Errorf("DistinctSubtrees of %v = %v, want %v", add.Homeotic, got, want)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gmlewis/gep/gene"
+)
+
+// infix lists the binary function symbols that are rendered between their arguments.
+var infix = map[string]bool{"+": true, "-": true, "*": true, "/": true}
+
+// Infix returns a human-readable rendering of the expressed expression of
+// the genome in infix notation, such as "((d0 * d0) + 1.5)". The basic
+// arithmetic functions are written between their (parenthesized) arguments,
+// other functions are written as calls such as "Sqrt(d0)", constants are
+// replaced by their values, and the genes are combined by the linking
+// function (or, for a homeotic genome, substituted into the homeotic gene).
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) Infix() string {
+	nodes := g.mathNodes()
+	genes := make([]string, len(g.Genes))
+	for i, v := range g.Genes {
+		genes[i] = infixString(v, v.Tree(nodes), nil)
+	}
+	return infixString(g.Homeotic, g.LinkTree(), genes)
+}
+
+// infixString renders the expression tree n of gene g (which may be nil if n
+// holds no constants) in infix notation, replacing each input terminal "dN"
+// with terms[N] if terms is non-nil.
+func infixString(g *gene.Gene, n *gene.ExprNode, terms []string) string {
+	if n == nil {
+		return ""
+	}
+	if len(n.Args) == 0 {
+		if _, ok := canonicalNodes[n.Symbol]; !ok && len(n.Symbol) > 1 {
+			index, err := strconv.Atoi(n.Symbol[1:])
+			switch {
+			case err != nil:
+			case n.Symbol[0:1] == "c" && g != nil && index < len(g.Constants):
+				return strconv.FormatFloat(g.Constants[index], 'g', -1, 64)
+			case n.Symbol[0:1] == "d" && terms != nil && index < len(terms):
+				return terms[index]
+			}
+		}
+		return n.Symbol
+	}
+	args := make([]string, len(n.Args))
+	for i, v := range n.Args {
+		args[i] = infixString(g, v, terms)
+	}
+	return infixCall(n.Symbol, args)
+}
+
+// infixCall renders the application of the function sym to args.
+func infixCall(sym string, args []string) string {
+	if infix[sym] && len(args) == 2 {
+		return "(" + args[0] + " " + sym + " " + args[1] + ")"
+	}
+	return sym + "(" + strings.Join(args, ", ") + ")"
+}
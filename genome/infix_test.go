@@ -0,0 +1,36 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"testing"
+
+	"github.com/gmlewis/gep/gene"
+)
+
+func TestInfix(t *testing.T) {
+	withConstant := gene.New("+.*.c0.d0.d0.d0.d0")
+	withConstant.Constants = []float64{1.5}
+	tests := []struct {
+		g    *Genome
+		want string
+	}{
+		{g: New([]*gene.Gene{withConstant}, "+"), want: "((d0 * d0) + 1.5)"},
+		{g: New([]*gene.Gene{gene.New("Sqrt.d1.d0"), gene.New("d0.d1")}, "-"), want: "(Sqrt(d1) - d0)"},
+		{g: New([]*gene.Gene{gene.New("d0"), gene.New("d1"), gene.New("d2")}, "Max2"), want: "Max2(Max2(d0, d1), d2)"},
+		{
+			g: NewHomeotic([]*gene.Gene{
+				gene.New("*.d0.d1.d0.d0"),
+				gene.New("-.d0.d1.d0.d0"),
+			}, gene.New("+.d0./.d0.d1.d0.d0")),
+			want: "((d0 * d1) + ((d0 * d1) / (d0 - d1)))",
+		},
+	}
+	for i, test := range tests {
+		if got := test.g.Infix(); got != test.want {
+			t.Errorf("%v: Infix = %q, want %q", i, got, test.want)
+		}
+	}
+}
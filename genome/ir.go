@@ -0,0 +1,151 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"fmt"
+
+	"github.com/gmlewis/gep/functions"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+// IR operations that are not function symbols.
+const (
+	IRInput = "input" // the input in[Index]
+	IRConst = "const" // the constant Value
+)
+
+// IRNode is a single operation of the intermediate representation (IR) of a
+// floating-point genome, as returned by ToIR. The IR is a list of operations
+// in topological order: the operands of each node precede it, and the final
+// node produces the result of the whole (linked, multigenic) expression.
+type IRNode struct {
+	// Op is IRInput, IRConst, or the symbol of a function in mn.Math
+	// (including the linking function).
+	Op string
+	// Index is the index of the input for IRInput.
+	Index int
+	// Value is the value of the constant for IRConst.
+	Value float64
+	// Args holds the positions within the IR of the operands of a function,
+	// in order.
+	Args []int
+}
+
+// ToIR returns the intermediate representation of the expressed, floating-point
+// expression of the genome: each gene in turn, combined with the linking function
+// exactly as in EvalMath. Downstream code may translate the IR into other forms,
+// such as a compute graph. It returns nil if the genome has no genes.
+// A gene referring to a constant it lacks yields a zero constant (and a log),
+// as in EvalMath.
+func (g *Genome) ToIR() []IRNode {
+	if len(g.Genes) == 0 {
+		functions.Log.Printf("genome.ToIR error: genome has no genes")
+		return nil
+	}
+	var ir []IRNode
+	result := -1
+	for _, v := range g.Genes {
+		n := appendIR(&ir, v, v.Tree(mn.Math))
+		if result >= 0 {
+			ir = append(ir, IRNode{Op: g.LinkFunc, Args: []int{result, n}})
+			n = len(ir) - 1
+		}
+		result = n
+	}
+	return ir
+}
+
+// appendIR appends the operations of the expression tree n of gene g to ir
+// in post-order, and returns the position of the operation producing its result.
+// A constant missing from the constants of g becomes a zero constant.
+func appendIR(ir *[]IRNode, g *gene.Gene, n *gene.ExprNode) int {
+	if _, ok := mn.Math[n.Symbol]; !ok {
+		if kind, index, err := gene.ParseTerminal(n.Symbol); err == nil {
+			switch {
+			case kind == "d":
+				*ir = append(*ir, IRNode{Op: IRInput, Index: index})
+			case index < len(g.Constants):
+				*ir = append(*ir, IRNode{Op: IRConst, Value: g.Constants[index]})
+			default:
+				functions.Log.Printf("genome.ToIR error: constant %v exceeds the %v constants of gene %q", n.Symbol, len(g.Constants), g)
+				*ir = append(*ir, IRNode{Op: IRConst, Value: 0})
+			}
+			return len(*ir) - 1
+		}
+	}
+	var args []int
+	for _, v := range n.Args {
+		args = append(args, appendIR(ir, g, v))
+	}
+	*ir = append(*ir, IRNode{Op: n.Symbol, Args: args})
+	return len(*ir) - 1
+}
+
+// ValidateIR checks that ir is well formed for evaluation with numInputs
+// inputs: it must be non-empty, every operation must be IRInput (with an index
+// within range), IRConst, or a function of mn.Math with the correct number of
+// operands, and every operand must refer to an earlier node. The errors wrap
+// ErrInvalidIR.
+func ValidateIR(ir []IRNode, numInputs int) error {
+	if len(ir) == 0 {
+		return fmt.Errorf("%w: no nodes", ErrInvalidIR)
+	}
+	for i, n := range ir {
+		switch n.Op {
+		case IRInput:
+			if n.Index < 0 || n.Index >= numInputs {
+				return fmt.Errorf("%w: node #%v: input index %v out of range (%v inputs)", ErrInvalidIR, i, n.Index, numInputs)
+			}
+			continue
+		case IRConst:
+			continue
+		}
+		f, ok := mn.Math[n.Op]
+		if !ok {
+			return fmt.Errorf("%w: node #%v: unknown operation %q", ErrInvalidIR, i, n.Op)
+		}
+		if len(n.Args) != f.Terminals() {
+			return fmt.Errorf("%w: node #%v: %q takes %v operands, got %v", ErrInvalidIR, i, n.Op, f.Terminals(), len(n.Args))
+		}
+		for _, a := range n.Args {
+			if a < 0 || a >= i {
+				return fmt.Errorf("%w: node #%v: operand %v does not refer to an earlier node", ErrInvalidIR, i, a)
+			}
+		}
+	}
+	return nil
+}
+
+// EvalIR evaluates the intermediate representation ir (as returned by ToIR)
+// with the inputs in, without requiring a genome, and returns the result of
+// its final node. If ir is malformed, it returns the error of ValidateIR.
+func EvalIR(ir []IRNode, in []float64) (float64, error) {
+	if err := ValidateIR(ir, len(in)); err != nil {
+		return 0, err
+	}
+	return evalIR(ir, in, make([]float64, len(ir))), nil
+}
+
+// evalIR evaluates the valid ir with the inputs in, using values (which must be
+// as long as ir) to hold the result of each node, and returns that of the final node.
+func evalIR(ir []IRNode, in, values []float64) float64 {
+	for i, n := range ir {
+		switch n.Op {
+		case IRInput:
+			values[i] = in[n.Index]
+		case IRConst:
+			values[i] = n.Value
+		default:
+			var x [4]float64
+			for j, a := range n.Args {
+				x[j] = values[a]
+			}
+			values[i] = mn.Math[n.Op].Float64Function(x[0], x[1], x[2], x[3])
+		}
+	}
+	return values[len(values)-1]
+}
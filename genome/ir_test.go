@@ -0,0 +1,90 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestToIR(t *testing.T) {
+	g, err := Build().Gene("+ * c0 d0 d1", 2.5).Gene("Sub3 d1 Sqrt d0 d2").Gene("d2").Link("*").Genome()
+	if err != nil {
+		t.Fatalf("Build = %v", err)
+	}
+	ir := g.ToIR()
+	for i, n := range ir {
+		for _, a := range n.Args {
+			if a >= i {
+				t.Errorf("IR node #%v %+v refers to later node #%v", i, n, a)
+			}
+		}
+	}
+	if got, want := ir[len(ir)-1].Op, "*"; got != want {
+		t.Errorf("final IR op = %q, want linking function %q", got, want)
+	}
+	for _, in := range [][]float64{{1, 2, 3}, {-1.5, 4, 0.25}, {0, 0, 0}, {7, -3, 9}} {
+		want := g.EvalMath(in)
+		if got, err := EvalIR(ir, in); err != nil || got != want && !(math.IsNaN(got) && math.IsNaN(want)) {
+			t.Errorf("EvalIR(%v) = (%v, %v), want EvalMath = %v", in, got, err, want)
+		}
+	}
+
+	missing := newConstGene("+.c0.c1", 3)
+	missing.Genes[0].Constants = missing.Genes[0].Constants[:1]
+	ir = missing.ToIR()
+	if err := ValidateIR(ir, 0); err != nil || len(ir) != 3 || ir[1].Op != IRConst || ir[1].Value != 0 {
+		t.Errorf("ToIR with a missing constant = %+v (ValidateIR %v), want a zero constant", ir, err)
+	}
+	if got, err := EvalIR(ir, nil); err != nil || got != missing.EvalMath(nil) {
+		t.Errorf("EvalIR with a missing constant = (%v, %v), want EvalMath = %v", got, err, missing.EvalMath(nil))
+	}
+
+	single := newConstGene("c0", 3)
+	if ir := single.ToIR(); len(ir) != 1 || ir[0].Op != IRConst || ir[0].Value != 3 {
+		t.Errorf("single-constant ToIR = %+v, want one constant 3", ir)
+	}
+}
+
+func TestEvalIR(t *testing.T) {
+	genomes := []*Genome{
+		newConstGene("+.*.c1.c0.d0", 2, 1),
+		newGenome("-", "/.d0.d1", "Pow.d1.d0", "Ln.Abs.d0"),
+		newGenome("Max2", "Avg3.d0.d1.Inv.d1", "Mod.d1.d0"),
+		newGenome("+", "d1"),
+	}
+	inputs := [][]float64{{1, 2}, {-3, 0.5}, {4, 0}, {0.1, 10}}
+	for _, g := range genomes {
+		ir := g.ToIR()
+		if err := ValidateIR(ir, 2); err != nil {
+			t.Errorf("%v ValidateIR = %v", g, err)
+		}
+		for _, in := range inputs {
+			want := g.EvalMath(in)
+			if got, err := EvalIR(ir, in); err != nil || got != want && !(math.IsNaN(got) && math.IsNaN(want)) {
+				t.Errorf("%v EvalIR(%v) = (%v, %v), want EvalMath = %v", g, in, got, err, want)
+			}
+		}
+	}
+
+	malformed := [][]IRNode{
+		nil,
+		{{Op: IRInput, Index: 2}},
+		{{Op: IRInput, Index: -1}},
+		{{Op: "Bogus"}},
+		{{Op: IRConst, Value: 1}, {Op: "+", Args: []int{0}}},
+		{{Op: IRConst, Value: 1}, {Op: "+", Args: []int{0, 1}}},
+		{{Op: IRConst, Value: 1}, {Op: "Sqrt", Args: []int{5}}},
+	}
+	for i, ir := range malformed {
+		if err := ValidateIR(ir, 2); err == nil {
+			t.Errorf("%v: ValidateIR(%+v) = nil, want error", i, ir)
+		}
+		if got, err := EvalIR(ir, []float64{1, 2}); !errors.Is(err, ErrInvalidIR) {
+			t.Errorf("%v: EvalIR(%+v) = (%v, %v), want ErrInvalidIR", i, ir, got, err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"fmt"
+
+	"github.com/gmlewis/gep/gene"
+)
+
+// EvalMathLimit is like EvalMathSafe, but evaluates at most maxNodes nodes
+// (if positive) and returns an error wrapping ErrTooManyNodes as soon as the
+// evaluation would exceed them, without completing it. Unlike EvalMath, which
+// evaluates each ADF of a homeotic genome only once, it counts an ADF each
+// time the homeotic gene calls it, as if the calls were expanded, so that a
+// homeotic gene calling large ADFs over and over is caught as well as a genome
+// that is simply too large.
+func (g *Genome) EvalMathLimit(in []float64, maxNodes int) (float64, error) {
+	if maxNodes <= 0 {
+		return g.EvalMathSafe(in)
+	}
+	if len(g.Genes) == 0 {
+		return 0, errNoGenes
+	}
+	left := maxNodes
+	spend := func(n int) error {
+		if left -= n; left < 0 {
+			return fmt.Errorf("%w: more than %v nodes evaluated", ErrTooManyNodes, maxNodes)
+		}
+		return nil
+	}
+	in = g.MissingPolicy.apply(in)
+	if g.Homeotic != nil {
+		return g.evalHomeoticLimit(g.Homeotic.Tree(g.mathNodes()), in, spend)
+	}
+	if err := spend(g.EvaluatedNodes() - g.CodingLength()); err != nil { // The linking function.
+		return 0, err
+	}
+	lf, ok := g.mathNodes()[g.LinkFunc]
+	if !ok {
+		return 0, missingLinkFunc(g.LinkFunc)
+	}
+	result := 0.0
+	for i, v := range g.Genes {
+		if err := spend(v.CodingLength()); err != nil {
+			return 0, err
+		}
+		r, err := v.EvalMathSafe(in)
+		if err != nil {
+			return 0, invalidGene(fmt.Sprintf("gene #%v", i), err)
+		}
+		if i == 0 {
+			result = r
+		} else {
+			result = lf.Float64Function(result, r, 0.0, 0.0)
+		}
+	}
+	return result, nil
+}
+
+// evalHomeoticLimit evaluates the subtree n of the homeotic gene of g with the
+// inputs in, calling spend with the number of nodes of each node or ADF call
+// before it is evaluated.
+func (g *Genome) evalHomeoticLimit(n *gene.ExprNode, in []float64, spend func(int) error) (float64, error) {
+	if len(n.Args) == 0 {
+		kind, index, err := gene.ParseTerminal(n.Symbol)
+		switch {
+		case err != nil:
+			return 0, invalidGene("homeotic gene", err)
+		case kind == "c":
+			if index >= len(g.Homeotic.Constants) {
+				return 0, invalidGene("homeotic gene", fmt.Errorf("constant %q exceeds length of constant slice (%v)", n.Symbol, len(g.Homeotic.Constants)))
+			}
+			return g.Homeotic.Constants[index], spend(1)
+		case index >= len(g.Genes):
+			return 0, invalidGene("homeotic gene", fmt.Errorf("ADF %q exceeds number of ADFs (%v)", n.Symbol, len(g.Genes)))
+		}
+		adf := g.Genes[index]
+		if err := spend(adf.CodingLength()); err != nil {
+			return 0, err
+		}
+		r, err := adf.EvalMathSafe(in)
+		if err != nil {
+			return 0, invalidGene(fmt.Sprintf("ADF #%v", index), err)
+		}
+		return r, nil
+	}
+	f, ok := g.mathNodes()[n.Symbol]
+	if !ok || len(n.Args) != f.Terminals() || len(n.Args) > 4 {
+		return 0, invalidGene("homeotic gene", fmt.Errorf("gene %q is not a complete expression", g.Homeotic))
+	}
+	if err := spend(1); err != nil {
+		return 0, err
+	}
+	var args [4]float64
+	for i, v := range n.Args {
+		r, err := g.evalHomeoticLimit(v, in, spend)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = r
+	}
+	return f.Float64Function(args[0], args[1], args[2], args[3]), nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"sort"
+
+	"github.com/gmlewis/gep/functions"
+)
+
+// associative lists the (non-degenerate) binary function symbols whose results
+// do not depend on the grouping of their operands, and so are suitable for
+// linking any number of genes.
+var associative = map[string]bool{
+	"+": true, "*": true, "Min2": true, "Max2": true,
+	"And": true, "Or": true, "Xor": true, "Nxor": true,
+}
+
+// LinkFuncs returns the sorted symbols of the binary functions of fm that are
+// associative (such as +, *, And, and Or), which are those used by MutateLink.
+func LinkFuncs(fm functions.FuncMap) []string {
+	var result []string
+	for sym, f := range fm {
+		if associative[sym] && f.Terminals() == 2 {
+			result = append(result, sym)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// MutateLink mutates the genotype of the genome by replacing its linking
+// function with a different one chosen at random from the LinkFuncs of fm,
+// making the linking of the genes evolvable. The genome is left unchanged if
+// fm holds no other linking function, if it is frozen, or if it is homeotic
+// (since its homeotic gene links its genes instead).
+func (g *Genome) MutateLink(fm functions.FuncMap) {
+	g.MutateLinkWith(functions.DefaultRNG, fm)
+}
+
+// MutateLinkWith is like MutateLink, but draws its randomness from rng.
+func (g *Genome) MutateLinkWith(rng functions.RNG, fm functions.FuncMap) {
+	if g.Frozen || g.Homeotic != nil {
+		return
+	}
+	var choices []string
+	for _, sym := range LinkFuncs(fm) {
+		if sym != g.LinkFunc {
+			choices = append(choices, sym)
+		}
+	}
+	if len(choices) == 0 {
+		return
+	}
+	g.LinkFunc = choices[rng.Intn(len(choices))]
+	g.invalidate()
+}
@@ -0,0 +1,60 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gmlewis/gep/functions"
+	bn "github.com/gmlewis/gep/functions/bool_nodes"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+func TestLinkFuncs(t *testing.T) {
+	if got, want := LinkFuncs(mn.Math), []string{"*", "+", "Max2", "Min2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LinkFuncs(mn.Math) = %v, want %v", got, want)
+	}
+	if got, want := LinkFuncs(bn.BoolAllGates), []string{"And", "Nxor", "Or", "Xor"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LinkFuncs(BoolAllGates) = %v, want %v", got, want)
+	}
+}
+
+func TestMutateLink(t *testing.T) {
+	fm := functions.FuncMap{}
+	for _, sym := range []string{"+", "-", "*", "/", "Max2", "Sqrt"} {
+		fm[sym] = mn.Math[sym]
+	}
+	valid := map[string]bool{"+": true, "*": true, "Max2": true}
+	seen := map[string]int{}
+	g := newGenome("+", "*.d0.d1.d0", "-.d1.d0.d1")
+	for i := 0; i < 300; i++ {
+		before := g.LinkFunc
+		g.MutateLink(fm)
+		if g.LinkFunc == before || !valid[g.LinkFunc] {
+			t.Fatalf("MutateLink changed %q to %q, want another of %v", before, g.LinkFunc, LinkFuncs(fm))
+		}
+		seen[g.LinkFunc]++
+	}
+	if len(seen) != len(valid) {
+		t.Errorf("MutateLink chose %v, want each of %v", seen, LinkFuncs(fm))
+	}
+
+	g.Frozen = true
+	before := g.LinkFunc
+	if g.MutateLink(fm); g.LinkFunc != before {
+		t.Errorf("MutateLink altered frozen genome's link to %q", g.LinkFunc)
+	}
+	g = newGenome("+", "d0")
+	if g.MutateLink(functions.FuncMap{"+": mn.Math["+"], "-": mn.Math["-"]}); g.LinkFunc != "+" {
+		t.Errorf("MutateLink without alternatives changed link to %q", g.LinkFunc)
+	}
+	h := NewHomeotic([]*gene.Gene{gene.New("d0")}, gene.New("d0"))
+	before = h.LinkFunc
+	if h.MutateLink(fm); h.LinkFunc != before {
+		t.Errorf("MutateLink altered homeotic genome's link to %q", h.LinkFunc)
+	}
+}
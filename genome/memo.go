@@ -0,0 +1,75 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"fmt"
+	"math"
+
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+)
+
+// EvalMathBatchMemo is like EvalMathBatch, but memoizes the evaluation of
+// each row: every distinct subtree of the genome (whether repeated within a
+// gene or across genes) is evaluated only once per row, and its result is
+// reused wherever else it appears. The results are identical to those of
+// EvalMathBatch. This pays off for genomes with many repeated subexpressions
+// built from expensive functions; for others, the cost of preparing the
+// memoized evaluation may outweigh the savings.
+func (g *Genome) EvalMathBatchMemo(inputs [][]float64) []float64 {
+	if _, ok := mn.Math[g.LinkFunc]; !ok || g.Homeotic != nil || g.funcs != nil || len(g.Genes) == 0 {
+		return g.EvalMathBatch(inputs)
+	}
+	ir, numInputs := memoIR(g.ToIR())
+	if err := ValidateIR(ir, numInputs); err != nil {
+		return g.EvalMathBatch(inputs)
+	}
+	result := make([]float64, len(inputs))
+	values := make([]float64, len(ir))
+	for i, in := range inputs {
+		in = g.MissingPolicy.apply(in)
+		if len(in) < numInputs {
+			result[i] = g.EvalMath(in) // Let EvalMath handle (and report) the missing inputs.
+			continue
+		}
+		result[i] = evalIR(ir, in, values)
+	}
+	return result
+}
+
+// memoIR returns ir with its duplicate nodes (those having the same operation
+// applied to the same operands) merged, along with the number of inputs it requires.
+// The final node of the result still produces the result of the final node of ir.
+func memoIR(ir []IRNode) ([]IRNode, int) {
+	var result []IRNode
+	numInputs := 0
+	seen := map[string]int{}
+	remap := make([]int, len(ir))
+	for i, n := range ir {
+		args := make([]int, len(n.Args))
+		for j, a := range n.Args {
+			args[j] = -1
+			if a >= 0 && a < i {
+				args[j] = remap[a]
+			}
+		}
+		key := fmt.Sprintf("%v|%v|%v|%v", n.Op, n.Index, math.Float64bits(n.Value), args)
+		if pos, ok := seen[key]; ok {
+			remap[i] = pos
+			continue
+		}
+		if n.Op == IRInput && n.Index >= numInputs {
+			numInputs = n.Index + 1
+		}
+		seen[key] = len(result)
+		remap[i] = len(result)
+		result = append(result, IRNode{Op: n.Op, Index: n.Index, Value: n.Value, Args: args})
+	}
+	if last := remap[len(ir)-1]; last != len(result)-1 {
+		// The final node duplicates an earlier one; repeat it so it comes last.
+		result = append(result, result[last])
+	}
+	return result, numInputs
+}
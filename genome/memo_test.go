@@ -0,0 +1,75 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// redundantGene is Pow(Exp(Sin(d0)), Sin(Sin(d1))) * Pow(Exp(Sin(d0)), Sin(Sin(d1))).
+const redundantGene = "*.Pow.Pow.Exp.Sin.Exp.Sin.Sin.Sin.Sin.Sin.d0.d1.d0.d1.d0.d0.d0.d0.d0.d0.d0.d0.d0.d0"
+
+func redundantGenome() *Genome {
+	return newGenome("+", redundantGene, redundantGene, redundantGene, "-.d1.d0.d0.d0.d0")
+}
+
+func TestEvalMathBatchMemo(t *testing.T) {
+	var inputs [][]float64
+	for i := 0; i < 50; i++ {
+		inputs = append(inputs, []float64{4*rand.Float64() - 2, 4*rand.Float64() - 2, 4*rand.Float64() - 2, 4*rand.Float64() - 2, 4*rand.Float64() - 2})
+	}
+	withConsts := newGenome("*", "+.c0.c1.d0.d0", "-.c0.c1.d0.d0", "+.c1.c0.d0.d0")
+	for _, v := range withConsts.Genes {
+		v.Constants = []float64{1.5, 1.5}
+	}
+	// The second gene refers to c1 but has only c0, so c1 evaluates to 0.
+	missingConsts := newGenome("+", "*.c0.d0", "-.c1.d1")
+	missingConsts.Genes[0].Constants = []float64{2.5}
+	missingConsts.Genes[1].Constants = []float64{0.5}
+	genomes := []*Genome{
+		redundantGenome(),
+		withConsts,
+		missingConsts,
+		newGenome("+", "d2.d0.d1"), // single terminal
+		newGenome("Max2", "+.d0.d1.d0.d0", "+.d0.d1.d0.d0"),
+		benchGenome(8),
+		benchGenome(40),
+	}
+	for _, g := range genomes {
+		want := g.EvalMathBatch(inputs)
+		got := g.EvalMathBatchMemo(inputs)
+		for i := range want {
+			if got[i] != want[i] && !(math.IsNaN(got[i]) && math.IsNaN(want[i])) {
+				t.Errorf("EvalMathBatchMemo(%v) row %v = %v, want %v", g, i, got[i], want[i])
+			}
+		}
+	}
+
+	ir, _ := memoIR(redundantGenome().ToIR())
+	if got, want := len(ir), 12; got != want { // down from 51
+		t.Errorf("memoized IR has %v nodes, want %v: %#v", got, want, ir)
+	}
+}
+
+func BenchmarkEvalMathBatchMemo(b *testing.B) {
+	var inputs [][]float64
+	for i := 0; i < 100; i++ {
+		inputs = append(inputs, []float64{rand.Float64(), rand.Float64()})
+	}
+	g := redundantGenome()
+	g.EvalMathBatch(inputs) // Build the cached expression trees before timing.
+	b.Run("plain", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			g.EvalMathBatch(inputs)
+		}
+	})
+	b.Run("memo", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			g.EvalMathBatchMemo(inputs)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import "math"
+
+// MissingPolicy determines how the floating-point evaluation of a genome
+// treats missing (NaN) inputs. Without a policy, NaN inputs propagate through
+// the expression, typically making the result NaN.
+type MissingPolicy struct {
+	// Defaults holds the value substituted for each missing input:
+	// a NaN in[i] is replaced by Defaults[i] before evaluation, as is an
+	// input absent altogether because in is too short. Missing inputs beyond
+	// the end of Defaults (or whose default is NaN) propagate.
+	Defaults []float64
+}
+
+// MeanImputation returns a MissingPolicy that substitutes the mean of the
+// non-missing values of each input column of the dataset for a missing input.
+// A column with no values has a NaN default, so its missing inputs propagate.
+func MeanImputation(ds *Dataset) *MissingPolicy {
+	var sums []float64
+	var counts []int
+	for _, in := range ds.Inputs {
+		for i, v := range in {
+			if i >= len(sums) {
+				sums, counts = append(sums, 0), append(counts, 0)
+			}
+			if !math.IsNaN(v) {
+				sums[i] += v
+				counts[i]++
+			}
+		}
+	}
+	r := &MissingPolicy{Defaults: make([]float64, len(sums))}
+	for i := range sums {
+		r.Defaults[i] = math.NaN()
+		if counts[i] > 0 {
+			r.Defaults[i] = sums[i] / float64(counts[i])
+		}
+	}
+	return r
+}
+
+// apply returns in with its missing inputs substituted according to the
+// policy. in itself is never altered; it is returned as-is if nothing is
+// substituted. A nil policy substitutes nothing.
+func (p *MissingPolicy) apply(in []float64) []float64 {
+	if p == nil {
+		return in
+	}
+	var r []float64
+	for i, v := range in {
+		if !math.IsNaN(v) || i >= len(p.Defaults) || math.IsNaN(p.Defaults[i]) {
+			continue
+		}
+		if r == nil {
+			r = append([]float64(nil), in...)
+		}
+		r[i] = p.Defaults[i]
+	}
+	n := len(p.Defaults)
+	for n > len(in) && math.IsNaN(p.Defaults[n-1]) {
+		n--
+	}
+	if n > len(in) {
+		if r == nil {
+			r = append([]float64(nil), in...)
+		}
+		r = append(r, p.Defaults[len(in):n]...)
+	}
+	if r == nil {
+		return in
+	}
+	return r
+}
@@ -0,0 +1,54 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMissingPolicy(t *testing.T) {
+	nan := math.NaN()
+	ds := &Dataset{
+		Inputs:  [][]float64{{1, nan, nan}, {3, 10, nan}, {nan, 20, nan}},
+		Targets: []float64{0, 0, 0},
+	}
+	p := MeanImputation(ds)
+	if got := p.Defaults; len(got) != 3 || got[0] != 2 || got[1] != 15 || !math.IsNaN(got[2]) {
+		t.Errorf("MeanImputation = %v, want [2 15 NaN]", got)
+	}
+
+	g := newGenome("+", "-.d0.d1.d0.d0")
+	in := []float64{1, nan}
+	if got := g.EvalMath(in); !math.IsNaN(got) {
+		t.Errorf("EvalMath(%v) without a policy = %v, want NaN (propagated)", in, got)
+	}
+	g.MissingPolicy = p
+	if got := g.EvalMath(in); got != 1-15 {
+		t.Errorf("EvalMath(%v) with mean imputation = %v, want %v", in, got, 1-15)
+	}
+	if got, err := g.EvalMathSafe(in); err != nil || got != 1-15 {
+		t.Errorf("EvalMathSafe(%v) with mean imputation = (%v, %v), want %v", in, got, err, 1-15)
+	}
+	if got := g.EvalMathBatchMemo([][]float64{in}); got[0] != 1-15 {
+		t.Errorf("EvalMathBatchMemo(%v) with mean imputation = %v, want %v", in, got, 1-15)
+	}
+	if got := g.Dup().EvalMath(in); got != 1-15 {
+		t.Errorf("Dup EvalMath(%v) with mean imputation = %v, want %v", in, got, 1-15)
+	}
+	if !math.IsNaN(in[1]) {
+		t.Errorf("EvalMath altered its inputs: %v", in)
+	}
+	// A column without any values has no default, so its missing inputs propagate.
+	g2 := newGenome("+", "d2")
+	g2.MissingPolicy = p
+	if got := g2.EvalMath([]float64{1, 2, nan}); !math.IsNaN(got) {
+		t.Errorf("EvalMath with no default = %v, want NaN", got)
+	}
+	if got := p.apply([]float64{5, 6}); !reflect.DeepEqual(got, []float64{5, 6}) {
+		t.Errorf("apply of complete inputs = %v, want unchanged", got)
+	}
+}
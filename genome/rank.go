@@ -0,0 +1,82 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"math"
+	"sort"
+)
+
+// rankKey holds the properties by which genomes are ranked.
+type rankKey struct {
+	score  float64
+	length int
+	hash   uint64
+}
+
+func (g *Genome) rankKey() rankKey {
+	hash, size := g.canonical()
+	return rankKey{score: g.Score, length: size, hash: hash}
+}
+
+// better reports whether a ranks ahead of b.
+func (a rankKey) better(b rankKey) bool {
+	if aNaN, bNaN := math.IsNaN(a.score), math.IsNaN(b.score); aNaN != bNaN {
+		return bNaN
+	}
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	if a.length != b.length {
+		return a.length < b.length
+	}
+	return a.hash < b.hash
+}
+
+// Better reports whether genome a ranks ahead of genome b. Genomes are ranked
+// by descending Score (with NaN last). Ties are broken by preferring the genome
+// with the smaller expression (the fewer expressed nodes, as a light parsimony
+// pressure, which for math genomes is the shorter CodingLength) and then the
+// smaller CanonicalHash, so that the ranking of a population never depends upon
+// the order in which its genomes happen to be listed or evaluated.
+// Each call decodes both genomes, so to rank a whole population use Best,
+// Ranking, or Sort, which decode each genome only once.
+func Better(a, b *Genome) bool {
+	return a.rankKey().better(b.rankKey())
+}
+
+// Best returns the best genome of pop as ranked by Better, or nil if pop is
+// empty.
+func Best(pop []*Genome) *Genome {
+	var best *Genome
+	var key rankKey
+	for _, v := range pop {
+		if k := v.rankKey(); best == nil || k.better(key) {
+			best, key = v, k
+		}
+	}
+	return best
+}
+
+// Ranking returns the indices of the genomes of pop from best to worst, as
+// ranked by Better, leaving pop unaltered.
+func Ranking(pop []*Genome) []int {
+	keys := make([]rankKey, len(pop))
+	order := make([]int, len(pop))
+	for i, v := range pop {
+		keys[i], order[i] = v.rankKey(), i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return keys[order[a]].better(keys[order[b]]) })
+	return order
+}
+
+// Sort sorts the genomes in place from best to worst, as ranked by Better.
+func Sort(pop []*Genome) {
+	sorted := make([]*Genome, len(pop))
+	for i, j := range Ranking(pop) {
+		sorted[i] = pop[j]
+	}
+	copy(pop, sorted)
+}
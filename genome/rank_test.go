@@ -0,0 +1,75 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	scored := func(score float64, genes ...string) *Genome {
+		g := newGenome("+", genes...)
+		g.Score = score
+		return g
+	}
+	pop := []*Genome{
+		scored(500, "+.*.d0.d1.d2"),
+		scored(500, "d0"),
+		scored(math.NaN(), "d0"),
+		scored(900, "*.d0.d1.d1"),
+		scored(500, "+.d0.d1"),
+		scored(500, "+.d1.d0"), // Same expression as the previous one.
+		scored(500, "-.d0.d1"),
+		scored(0, "d1"),
+	}
+	want := make([]*Genome, len(pop))
+	copy(want, pop)
+	Sort(want)
+	if want[0] != pop[3] || want[1] != pop[1] || want[len(want)-2] != pop[7] || want[len(want)-1] != pop[2] {
+		t.Errorf("Sort = %v, want 900 first, then the shortest 500, with 0 and NaN last", want)
+	}
+	for i := 1; i < len(want); i++ {
+		if a, b := want[i-1], want[i]; a.Score == b.Score && a.CodingLength() > b.CodingLength() {
+			t.Errorf("Sort placed %v (length %v) ahead of %v (length %v) with tied scores", a, a.CodingLength(), b, b.CodingLength())
+		}
+		if Better(want[i], want[i-1]) {
+			t.Errorf("Sort placed %v ahead of better %v", want[i-1], want[i])
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 20; n++ {
+		got := make([]*Genome, len(pop))
+		for i, j := range r.Perm(len(pop)) {
+			got[i] = pop[j]
+		}
+		Sort(got)
+		for i := range got {
+			// Canonically identical genomes may appear in either order.
+			if got[i].CanonicalHash() != want[i].CanonicalHash() || got[i].Score != want[i].Score && !math.IsNaN(got[i].Score) {
+				t.Fatalf("Sort of shuffled population = %v, want %v", got, want)
+			}
+		}
+	}
+}
+
+func TestRankBoolSize(t *testing.T) {
+	// CodingLength is 1 for every one of these boolean genomes, since it only
+	// knows the math functions, but their ranks are by their real sizes.
+	big, small := newGenome("Or", "And.Not.d0.d1"), newGenome("Or", "Not.d0")
+	if !Better(small, big) || Better(big, small) {
+		t.Errorf("Better(%v, %v) = false, want the smaller boolean genome first", small, big)
+	}
+	pop := []*Genome{big, newGenome("Or", "And.Not.Not.d0.d1", "d1"), small}
+	if got := Best(pop); got != small {
+		t.Errorf("Best = %v, want %v", got, small)
+	}
+	if got, want := Ranking(pop), []int{2, 0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Ranking = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"fmt"
+
+	"github.com/gmlewis/gep/functions"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+)
+
+// mathNodes returns the map of floating-point functions to which g is bound.
+func (g *Genome) mathNodes() functions.FuncMap {
+	if g.funcs == nil {
+		return mn.Math
+	}
+	return g.funcs
+}
+
+// Rebind returns a copy of the genome whose floating-point evaluation (EvalMath
+// and EvalMathSafe) resolves its function symbols, including its linking
+// function, against fm instead of mn.Math. This migrates a genome between
+// compatible function sets, such as one with protected division registered
+// as "/" in place of plain division. It returns an error if any gene cannot
+// be rebound (see gene.Gene.Rebind) or if fm lacks the linking function.
+// Other analyses of the genome (such as ToIR and Derivative) still assume
+// the functions of mn.Math.
+func (g *Genome) Rebind(fm functions.FuncMap) (*Genome, error) {
+	if _, ok := fm[g.LinkFunc]; !ok && g.Homeotic == nil {
+		return nil, missingLinkFunc(g.LinkFunc)
+	}
+	r := g.Dup()
+	r.funcs = fm
+	r.invalidate()
+	for i, v := range g.Genes {
+		gn, err := v.Rebind(fm)
+		if err != nil {
+			return nil, invalidGene(fmt.Sprintf("gene #%v", i), err)
+		}
+		r.Genes[i] = gn
+	}
+	if g.Homeotic != nil {
+		h, err := g.Homeotic.Rebind(fm)
+		if err != nil {
+			return nil, invalidGene("homeotic gene", err)
+		}
+		r.Homeotic = h
+	}
+	return r, nil
+}
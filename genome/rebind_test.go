@@ -0,0 +1,87 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/gmlewis/gep/functions"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+// protectedDiv is division that returns 1 for a zero divisor.
+type protectedDiv struct{ terminals int }
+
+func (protectedDiv) Symbol() string                    { return "/" }
+func (n protectedDiv) Terminals() int                  { return n.terminals }
+func (protectedDiv) BoolFunction(a, b, c, d bool) bool { return false }
+func (protectedDiv) Float64Function(a, b, c, d float64) float64 {
+	if b == 0 {
+		return 1
+	}
+	return a / b
+}
+
+// withDiv returns a copy of mn.Math with "/" replaced by div.
+func withDiv(div functions.FuncNode) functions.FuncMap {
+	fm := functions.FuncMap{}
+	for k, v := range mn.Math {
+		fm[k] = v
+	}
+	fm["/"] = div
+	return fm
+}
+
+func TestRebind(t *testing.T) {
+	g := newGenome("+", "/.d0.d1.d0", "*.d0.d1.d1")
+	in := []float64{3, 0}
+	if got := g.EvalMath(in); !math.IsInf(got, 1) {
+		t.Fatalf("EvalMath(%v) = %v, want +Inf", in, got)
+	}
+	r, err := g.Rebind(withDiv(protectedDiv{terminals: 2}))
+	if err != nil {
+		t.Fatalf("Rebind = %v", err)
+	}
+	if got := r.EvalMath(in); got != 1 {
+		t.Errorf("rebound EvalMath(%v) = %v, want 1", in, got)
+	}
+	if got, err := r.EvalMathSafe(in); err != nil || got != 1 {
+		t.Errorf("rebound EvalMathSafe(%v) = (%v, %v), want 1", in, got, err)
+	}
+	if got := r.EvalMathBatchMemo([][]float64{in}); got[0] != 1 {
+		t.Errorf("rebound EvalMathBatchMemo(%v) = %v, want 1", in, got)
+	}
+	if got := r.Dup().EvalMath(in); got != 1 {
+		t.Errorf("rebound Dup EvalMath(%v) = %v, want 1", in, got)
+	}
+	if got := g.EvalMath(in); !math.IsInf(got, 1) {
+		t.Errorf("Rebind altered the original: EvalMath(%v) = %v, want +Inf", in, got)
+	}
+	if got, want := r.EvalMath([]float64{3, 2}), 3.0/2+3*2; got != want {
+		t.Errorf("rebound EvalMath(3, 2) = %v, want %v", got, want)
+	}
+
+	errTests := []struct {
+		name string
+		fm   functions.FuncMap
+		want error
+	}{
+		{name: "incomplete with a larger arity", fm: withDiv(protectedDiv{terminals: 4}), want: ErrInvalidGenome},
+		{name: "missing function", fm: functions.FuncMap{"+": mn.Math["+"], "/": protectedDiv{terminals: 2}}, want: ErrInvalidGenome},
+		{name: "missing linking function", fm: functions.FuncMap{"*": mn.Math["*"], "/": protectedDiv{terminals: 2}}, want: ErrMissingLinkFunc},
+	}
+	for _, test := range errTests {
+		if _, err := g.Rebind(test.fm); !errors.Is(err, test.want) {
+			t.Errorf("%v: Rebind = %v, want error matching %v", test.name, err, test.want)
+		}
+	}
+	homeotic := NewHomeotic([]*gene.Gene{gene.New("*.d0.d1")}, gene.New("/.d0.d0"))
+	if _, err := homeotic.Rebind(withDiv(protectedDiv{terminals: 3})); !errors.Is(err, ErrInvalidGenome) {
+		t.Errorf("homeotic gene incomplete with a larger arity: Rebind = %v, want error matching %v", err, ErrInvalidGenome)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gmlewis/gep/functions"
+	"github.com/gmlewis/gep/gene"
+)
+
+// GradientRefine returns a duplicate of g whose constants have been refined
+// by iters steps of gradient descent, with learning rate lr, on the (weighted)
+// mean squared error of the genome over the dataset. The structure of the
+// genome is unchanged. The gradients are found by symbolic differentiation
+// (see Derivative) with respect to each constant of the expression. A step
+// that fails to reduce the error is undone and the learning rate is halved.
+// If the expression has no constants or cannot be differentiated, the
+// duplicate is returned unrefined.
+func GradientRefine(g *Genome, ds *Dataset, lr float64, iters int) *Genome {
+	r := g.Dup()
+	d := &deriv{params: []param{}}
+	expr, err := d.expression(r)
+	if err != nil || len(d.params) == 0 {
+		return r
+	}
+	numParams := len(d.params)
+	var partials []*gene.ExprNode
+	for i := 0; i < numParams; i++ {
+		d.input = "c" + strconv.Itoa(i)
+		dp, err := d.derive(expr)
+		if err != nil {
+			return r
+		}
+		if dp == nil {
+			dp = d.constant(0)
+		}
+		partials = append(partials, dp)
+	}
+	// The partial derivatives share the constants of d, whose parameters are
+	// kept up to date with the constants of r.
+	grads := make([]*gene.Gene, numParams)
+	for i, dp := range partials {
+		grads[i] = gene.New(strings.Join(dp.Karva(), "."))
+		grads[i].Constants = d.constants
+	}
+	setParams := func(values []float64) {
+		for i, p := range d.params {
+			d.constants[i] = values[i]
+			p.g.Constants[p.index] = values[i]
+		}
+	}
+
+	values := append([]float64{}, d.constants[:numParams]...)
+	loss := mse(r, ds)
+	grad := make([]float64, numParams)
+	for n := 0; n < iters && lr > 0 && isFinite(loss); n++ {
+		for i := range grad {
+			grad[i] = 0
+		}
+		total := 0.0
+		for j, in := range ds.Inputs {
+			w := ds.weight(j)
+			e := r.EvalMath(in) - ds.Targets[j]
+			for i, dg := range grads {
+				grad[i] += w * 2 * e * dg.EvalMath(r.MissingPolicy.apply(in))
+			}
+			total += w
+		}
+		next := make([]float64, numParams)
+		for i := range next {
+			next[i] = values[i] - lr*grad[i]/total
+		}
+		setParams(next)
+		if l := mse(r, ds); isFinite(l) && l < loss {
+			values, loss = next, l
+			continue
+		}
+		setParams(values)
+		lr /= 2
+	}
+	return r
+}
+
+// HillClimb returns the result of a structural local search from a duplicate
+// of g: each of iters times, a single point mutation (see Mutate) is made to a
+// duplicate of the best genome so far, which is kept only if it scores higher
+// with sf. The returned genome is scored, and never scores lower than g itself
+// (as rescored by sf), which is unchanged. A frozen genome is returned unimproved.
+// This is a cheap memetic refinement step for the best genomes of a population.
+func HillClimb(g *Genome, sf ScoringFunc, iters int) *Genome {
+	return HillClimbWith(functions.DefaultRNG, g, sf, iters)
+}
+
+// HillClimbWith is like HillClimb, but draws its randomness from rng.
+func HillClimbWith(rng functions.RNG, g *Genome, sf ScoringFunc, iters int) *Genome {
+	best := g.Dup()
+	best.Score = sf(best)
+	for n := 0; n < iters && !g.Frozen; n++ {
+		c := best.Dup()
+		c.MutateWith(rng, 1)
+		c.Score = sf(c)
+		if c.Score > best.Score || (math.IsNaN(best.Score) && !math.IsNaN(c.Score)) {
+			best = c
+		}
+	}
+	return best
+}
+
+// mse returns the weighted mean squared error of the genome over the dataset.
+func mse(g *Genome, ds *Dataset) float64 {
+	sum, total := 0.0, 0.0
+	for i, in := range ds.Inputs {
+		w := ds.weight(i)
+		e := g.EvalMath(in) - ds.Targets[i]
+		sum += w * e * e
+		total += w
+	}
+	if total == 0 {
+		return math.NaN()
+	}
+	return sum / total
+}
@@ -0,0 +1,106 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/gmlewis/gep/functions"
+	"github.com/gmlewis/gep/gene"
+)
+
+func TestGradientRefine(t *testing.T) {
+	ds := &Dataset{}
+	for x := -2.0; x <= 2; x += 0.5 {
+		ds.Inputs = append(ds.Inputs, []float64{x})
+		ds.Targets = append(ds.Targets, 3*x+2)
+	}
+	g := newConstGene("+.*.c1.c0.d0", 0.5, 0.1) // c0*x + c1
+	r := GradientRefine(g, ds, 0.1, 200)
+	if got := r.Genes[0].Constants; math.Abs(got[0]-3) > 1e-6 || math.Abs(got[1]-2) > 1e-6 {
+		t.Errorf("GradientRefine constants = %v, want [3 2]", got)
+	}
+	if got := RMSE(ds)(r); got < 999.99 {
+		t.Errorf("GradientRefine RMSE score = %v, want nearly 1000", got)
+	}
+	if got := g.Genes[0].Constants; !reflect.DeepEqual(got, []float64{0.5, 0.1}) {
+		t.Errorf("GradientRefine altered the original constants: %v", got)
+	}
+	if r.String() != g.String() {
+		t.Errorf("GradientRefine altered the structure: %q, want %q", r, g)
+	}
+
+	// A constant used in several places, and in several genes.
+	// exp(c0*x) * c0 + c0 with c0 = 0.3 fits its own output for c0 = 0.7.
+	shared := newGenome("+", "*.Exp.c0.*.d0.c0", "c0")
+	for _, v := range shared.Genes {
+		v.Constants = []float64{0.7}
+	}
+	fit := &Dataset{Inputs: ds.Inputs, Targets: shared.EvalMathBatch(ds.Inputs)}
+	for _, v := range shared.Genes {
+		v.Constants = []float64{0.3}
+	}
+	r = GradientRefine(shared, fit, 0.05, 500)
+	for i, v := range r.Genes {
+		if math.Abs(v.Constants[0]-0.7) > 1e-3 {
+			t.Errorf("GradientRefine of shared constants: gene #%v constant = %v, want 0.7", i, v.Constants[0])
+		}
+	}
+}
+
+func TestGradientRefineFallback(t *testing.T) {
+	ds := &Dataset{Inputs: [][]float64{{1}, {2}}, Targets: []float64{1, 2}}
+	for _, g := range []*Genome{
+		newConstGene("Abs.*.c0.d0", 0.5), // not differentiable
+		newConstGene("*.d0.d0"),          // no constants
+	} {
+		r := GradientRefine(g, ds, 0.1, 10)
+		if r == g || r.String() != g.String() || len(r.Genes[0].Constants) != len(g.Genes[0].Constants) || (len(g.Genes[0].Constants) > 0 && r.Genes[0].Constants[0] != g.Genes[0].Constants[0]) {
+			t.Errorf("GradientRefine(%v) = %v with constants %v, want unrefined duplicate", g, r, r.Genes[0].Constants)
+		}
+	}
+}
+
+func TestHillClimb(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	sf := RMSE(regressionDataset)
+	improved := 0
+	for seed := int64(0); seed < 20; seed++ {
+		rng := functions.NewRNG(seed)
+		g := New([]*gene.Gene{
+			gene.RandomNewWith(rng, 4, 5, 1, 0, funcs, nil),
+			gene.RandomNewWith(rng, 4, 5, 1, 0, funcs, nil),
+		}, "+")
+		want, before := sf(g), g.String()
+		r := HillClimbWith(rng, g, sf, 50)
+		if r.Score < want {
+			t.Errorf("seed %v: HillClimb score = %v, want at least %v", seed, r.Score, want)
+		}
+		if got := sf(r); got != r.Score {
+			t.Errorf("seed %v: HillClimb Score = %v, want its rescored %v", seed, r.Score, got)
+		}
+		if r == g || g.String() != before {
+			t.Errorf("seed %v: HillClimb altered its input %q: %q", seed, before, g)
+		}
+		if r.Score > want {
+			improved++
+		}
+	}
+	if improved == 0 {
+		t.Errorf("HillClimb never improved a random genome")
+	}
+
+	frozen := New([]*gene.Gene{gene.RandomNew(4, 5, 1, 0, funcs)}, "+")
+	frozen.Frozen = true
+	if r := HillClimb(frozen, sf, 10); r.String() != frozen.String() {
+		t.Errorf("HillClimb of frozen genome = %q, want %q", r, frozen)
+	}
+}
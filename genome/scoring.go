@@ -0,0 +1,291 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"math"
+
+	"github.com/gmlewis/gep/functions"
+)
+
+// probEpsilon keeps probabilities away from exactly 0 or 1 so that the log-loss stays finite.
+const probEpsilon = 1e-15
+
+// RMSE returns a scoring function based on the (weighted) root mean squared error
+// of the genome over the dataset, normalized as 1000/(1+rmse) so that
+// a perfect fit scores 1000 and higher is better.
+// Non-finite predictions and oversized genomes are treated as configured by ds.Config.
+func RMSE(ds *Dataset) ScoringFunc {
+	c := ds.config()
+	return func(g *Genome) float64 {
+		if c.CheckNodes(g) != nil {
+			return 0.0
+		}
+		sum, total := 0.0, 0.0
+		for i, t := range ds.Targets {
+			w := ds.weight(i)
+			r, err := c.eval(g, ds.Inputs[i])
+			if err != nil {
+				return 0.0
+			}
+			e := c.error(r, t)
+			sum += w * e * e
+			total += w
+		}
+		if total == 0 {
+			return 0.0
+		}
+		return 1000.0 / (1.0 + math.Sqrt(sum/total))
+	}
+}
+
+// RSquared returns a scoring function based on the (weighted) coefficient of
+// determination (R²) of the genome over the dataset: 1 minus the ratio of the
+// squared errors of the genome to those of predicting the mean target. It is
+// scaled as 1000*R², with a negative R² (a fit worse than the mean) scoring 0,
+// so that a perfect fit scores 1000. If the targets are all equal, only a
+// perfect fit scores above 0.
+// Non-finite predictions and oversized genomes are treated as configured by ds.Config.
+func RSquared(ds *Dataset) ScoringFunc {
+	c := ds.config()
+	mean, total := 0.0, 0.0
+	for i, t := range ds.Targets {
+		w := ds.weight(i)
+		mean += w * t
+		total += w
+	}
+	if total > 0 {
+		mean /= total
+	}
+	variance := 0.0
+	for i, t := range ds.Targets {
+		variance += ds.weight(i) * (t - mean) * (t - mean)
+	}
+	return func(g *Genome) float64 {
+		if c.CheckNodes(g) != nil || total == 0 {
+			return 0.0
+		}
+		sum := 0.0
+		for i, t := range ds.Targets {
+			r, err := c.eval(g, ds.Inputs[i])
+			if err != nil {
+				return 0.0
+			}
+			e := c.error(r, t)
+			sum += ds.weight(i) * e * e
+		}
+		switch {
+		case math.IsNaN(sum):
+			return math.NaN()
+		case sum == 0:
+			return 1000.0
+		case variance == 0:
+			return 0.0
+		}
+		return 1000.0 * math.Max(0, 1-sum/variance)
+	}
+}
+
+// CaseErrors evaluates g over the inputs of the dataset (in one batch) and
+// returns the absolute error of its prediction for each row (training case),
+// in the order of the rows, unweighted. These are the per-case errors consumed
+// by lexicase selection (see model.LexicaseSelection), which the aggregate
+// score cannot support; the root mean square of the errors is the RMSE.
+// A non-finite prediction has the error NonFinitePenalty unless ds.Config
+// sets AllowNonFinite, and otherwise an infinite error,
+// so that it ranks below every finite prediction either way; every error of
+// an oversized genome is infinite.
+func CaseErrors(g *Genome, ds *Dataset) []float64 {
+	c := ds.config()
+	result := make([]float64, len(ds.Targets))
+	if c.CheckNodes(g) != nil {
+		return oversized(result)
+	}
+	var outputs []float64
+	if c.MaxNodes > 0 {
+		outputs = make([]float64, len(ds.Inputs))
+		for i, in := range ds.Inputs {
+			var err error
+			if outputs[i], err = c.eval(g, in); err != nil {
+				return oversized(result)
+			}
+		}
+	} else {
+		outputs = g.EvalMathBatch(ds.Inputs)
+	}
+	for i, v := range outputs {
+		if !isFinite(v) && c.AllowNonFinite {
+			result[i] = math.Inf(1)
+			continue
+		}
+		result[i] = math.Abs(c.error(v, ds.Targets[i]))
+	}
+	return result
+}
+
+// oversized sets every case error of an oversized genome to +Inf.
+func oversized(result []float64) []float64 {
+	for i := range result {
+		result[i] = math.Inf(1)
+	}
+	return result
+}
+
+// Accuracy returns a scoring function for binary classifiers based on the
+// (weighted) fraction of rows classified correctly, scaled from 0 to 1000.
+// A row is predicted to be in class 1 when EvalProbability is at least 0.5
+// and its target is in class 1 when it is at least 0.5.
+// Non-finite predictions and oversized genomes are treated as configured by ds.Config.
+func Accuracy(ds *Dataset) ScoringFunc {
+	c := ds.config()
+	return func(g *Genome) float64 {
+		if c.CheckNodes(g) != nil {
+			return 0.0
+		}
+		correct, total := 0.0, 0.0
+		for i, t := range ds.Targets {
+			w := ds.weight(i)
+			r, err := c.eval(g, ds.Inputs[i])
+			if err != nil {
+				return 0.0
+			}
+			if !c.penalized(r) && (probability(r) >= 0.5) == (t >= 0.5) {
+				correct += w
+			}
+			total += w
+		}
+		if total == 0 {
+			return 0.0
+		}
+		return 1000.0 * correct / total
+	}
+}
+
+// BoolAccuracy returns a scoring function for boolean genomes based on the
+// fraction of the rows of inputs for which EvalBool (with the functions fm)
+// matches targets, scaled from 0 to 1000. Rows marked true in dontCare
+// (which may be nil) have don't-care targets and are excluded from the score.
+// targets and dontCare (if non-nil) must have the same length as inputs;
+// otherwise the error is logged and every genome scores 0.
+func BoolAccuracy(inputs [][]bool, targets []bool, dontCare []bool, fm functions.FuncMap) ScoringFunc {
+	if len(targets) != len(inputs) || (dontCare != nil && len(dontCare) != len(inputs)) {
+		functions.Log.Printf("genome.BoolAccuracy error: %v inputs, %v targets, and %v don't-care flags must match", len(inputs), len(targets), len(dontCare))
+		return func(g *Genome) float64 { return 0.0 }
+	}
+	return func(g *Genome) float64 {
+		correct, total := 0, 0
+		for i, in := range inputs {
+			if dontCare != nil && dontCare[i] {
+				continue
+			}
+			if g.EvalBool(in, fm) == targets[i] {
+				correct++
+			}
+			total++
+		}
+		if total == 0 {
+			return 0.0
+		}
+		return 1000.0 * float64(correct) / float64(total)
+	}
+}
+
+// ConfusionMatrix evaluates the boolean genome (with the functions fm) for each
+// row of inputs and counts its true positives, false positives, true negatives,
+// and false negatives against labels, from which precision, recall, and the
+// F1 score of a classifier follow. labels must have the same length as inputs;
+// otherwise the error is logged and all the counts are 0.
+func (g *Genome) ConfusionMatrix(inputs [][]bool, labels []bool, fm functions.FuncMap) (tp, fp, tn, fn int) {
+	if len(labels) != len(inputs) {
+		functions.Log.Printf("genome.ConfusionMatrix error: %v inputs and %v labels must match", len(inputs), len(labels))
+		return 0, 0, 0, 0
+	}
+	for i, in := range inputs {
+		switch p := g.EvalBool(in, fm); {
+		case p && labels[i]:
+			tp++
+		case p:
+			fp++
+		case labels[i]:
+			fn++
+		default:
+			tn++
+		}
+	}
+	return tp, fp, tn, fn
+}
+
+// F1Score returns a scoring function for boolean classifiers based on the
+// F1 score (the harmonic mean of precision and recall) of the genome's
+// ConfusionMatrix over inputs and labels, scaled from 0 to 1000. Unlike
+// BoolAccuracy, it is not dominated by the majority class of imbalanced
+// problems. A genome that predicts no true positives scores 0.
+// labels must have the same length as inputs; otherwise the error is logged
+// and every genome scores 0.
+func F1Score(inputs [][]bool, labels []bool, fm functions.FuncMap) ScoringFunc {
+	if len(labels) != len(inputs) {
+		functions.Log.Printf("genome.F1Score error: %v inputs and %v labels must match", len(inputs), len(labels))
+		return func(g *Genome) float64 { return 0.0 }
+	}
+	return func(g *Genome) float64 {
+		tp, fp, _, fn := g.ConfusionMatrix(inputs, labels, fm)
+		if tp == 0 {
+			return 0.0
+		}
+		return 1000.0 * float64(2*tp) / float64(2*tp+fp+fn)
+	}
+}
+
+// LogLoss returns a scoring function for probabilistic binary classifiers.
+// Each row is evaluated with EvalProbability and the (weighted) mean negative
+// log-likelihood (cross-entropy) of the targets (0 or 1) is computed.  The score
+// is normalized as 1000/(1+logLoss) so that a perfect classifier approaches 1000
+// and higher is better.
+// Non-finite predictions and oversized genomes are treated as configured by ds.Config.
+func LogLoss(ds *Dataset) ScoringFunc {
+	c := ds.config()
+	return func(g *Genome) float64 {
+		if c.CheckNodes(g) != nil {
+			return 0.0
+		}
+		loss, total := 0.0, 0.0
+		for i, t := range ds.Targets {
+			w := ds.weight(i)
+			r, err := c.eval(g, ds.Inputs[i])
+			if err != nil {
+				return 0.0
+			}
+			total += w
+			if c.penalized(r) {
+				loss += w * c.penalty()
+				continue
+			}
+			p := math.Min(math.Max(probability(r), probEpsilon), 1-probEpsilon)
+			loss -= w * (t*math.Log(p) + (1-t)*math.Log(1-p))
+		}
+		if total == 0 {
+			return 0.0
+		}
+		return 1000.0 / (1.0 + loss/total)
+	}
+}
+
+// error returns the error of prediction v against target t, replacing the
+// error of a non-finite prediction with the penalty if so configured.
+func (c *ScoringConfig) error(v, t float64) float64 {
+	if c.penalized(v) {
+		return c.penalty()
+	}
+	return v - t
+}
+
+// GeneralizationGap returns the score of the genome on the training dataset
+// minus its score on the validation dataset, each scored by the scoring
+// function made by sf (such as RMSE). Since higher scores are better, a large
+// positive gap indicates that the genome overfits the training data, even if
+// its training score is high. The score of the genome is not altered.
+func (g *Genome) GeneralizationGap(train, val *Dataset, sf func(*Dataset) ScoringFunc) float64 {
+	return g.Fitness(sf(train)) - g.Fitness(sf(val))
+}
@@ -0,0 +1,416 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	bn "github.com/gmlewis/gep/functions/bool_nodes"
+	"github.com/gmlewis/gep/gene"
+)
+
+// classifierDataset labels each input as 1 when positive and 0 otherwise.
+var classifierDataset = &Dataset{
+	Inputs:  [][]float64{{-3}, {-2}, {-1}, {-0.5}, {0.5}, {1}, {2}, {3}},
+	Targets: []float64{0, 0, 0, 0, 1, 1, 1, 1},
+}
+
+// newConstGene returns a single-gene genome with the given Karva expression and constants.
+func newConstGene(karva string, constants ...float64) *Genome {
+	g := gene.New(karva)
+	g.Constants = constants
+	return New([]*gene.Gene{g}, "+")
+}
+
+func TestLogLoss(t *testing.T) {
+	sf := LogLoss(classifierDataset)
+	perfect := newConstGene("*.d0.c0", 100)
+	if got := sf(perfect); got < 999 {
+		t.Errorf("LogLoss(perfect) = %v, want near 1000", got)
+	}
+	coinFlip := newConstGene("c0", 0)
+	want := 1000.0 / (1.0 + math.Ln2)
+	if got := sf(coinFlip); math.Abs(got-want) > 1e-9 {
+		t.Errorf("LogLoss(coin flip) = %v, want %v", got, want)
+	}
+	wrong := newConstGene("*.d0.c0", -100)
+	if got := sf(wrong); got <= 0 || got >= sf(coinFlip) {
+		t.Errorf("LogLoss(always wrong) = %v, want in (0, %v)", got, sf(coinFlip))
+	}
+}
+
+// regressionDataset samples the function y = 2*x + 1.
+var regressionDataset = &Dataset{
+	Inputs:  [][]float64{{0}, {1}, {2}, {3}, {4}},
+	Targets: []float64{1, 3, 5, 7, 9},
+}
+
+func TestRMSE(t *testing.T) {
+	sf := RMSE(regressionDataset)
+	exact := newConstGene("+.*.c1.c0.d0", 2, 1)
+	if got := sf(exact); got != 1000 {
+		t.Errorf("RMSE(exact) = %v, want 1000", got)
+	}
+	offByOne := newConstGene("*.c0.d0", 2)
+	if got, want := sf(offByOne), 500.0; got != want {
+		t.Errorf("RMSE(off by one) = %v, want %v", got, want)
+	}
+}
+
+func TestCaseErrors(t *testing.T) {
+	// y = 3*x errs by 1, 0, 1, 2, and 3 on the rows of the dataset.
+	g := newConstGene("*.c0.d0", 3)
+	errs := CaseErrors(g, regressionDataset)
+	sum, squares := 0.0, 0.0
+	for _, e := range errs {
+		sum += e
+		squares += e * e
+	}
+	mean, rmse := sum/float64(len(errs)), math.Sqrt(squares/float64(len(errs)))
+	if mean != 1.4 {
+		t.Errorf("mean case error = %v, want 1.4", mean)
+	}
+	// The RMSE is the root mean square of the case errors, which is at least
+	// their mean.
+	if got, want := RMSE(regressionDataset)(g), 1000/(1+rmse); math.Abs(got-want) > 1e-9 {
+		t.Errorf("RMSE = %v, want %v from the case errors", got, want)
+	}
+	if rmse < mean {
+		t.Errorf("root mean square case error %v < mean case error %v", rmse, mean)
+	}
+
+	if got, want := errs, []float64{1, 0, 1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CaseErrors of 3*x = %v, want %v", got, want)
+	}
+
+	// 1/x is infinite at x = 0, which incurs the penalty, or an infinite
+	// error without it.
+	inv := newConstGene("/.c0.d0", 1)
+	if got := CaseErrors(inv, regressionDataset); got[0] != DefaultScoringConfig.NonFinitePenalty || got[1] != 2 {
+		t.Errorf("CaseErrors of 1/x = %v, want penalty at row 0 and 2 at row 1", got)
+	}
+	off := &Dataset{Inputs: regressionDataset.Inputs, Targets: regressionDataset.Targets, Config: &ScoringConfig{AllowNonFinite: true}}
+	if got := CaseErrors(inv, off); !math.IsInf(got[0], 1) || got[1] != 2 {
+		t.Errorf("CaseErrors of 1/x with AllowNonFinite = %v, want +Inf at row 0 and 2 at row 1", got)
+	}
+
+	ds := &Dataset{Inputs: regressionDataset.Inputs, Targets: regressionDataset.Targets, Config: &ScoringConfig{MaxNodes: 1}}
+	for i, e := range CaseErrors(g, ds) {
+		if !math.IsInf(e, 1) {
+			t.Errorf("CaseErrors of oversized genome [%v] = %v, want +Inf", i, e)
+		}
+	}
+}
+
+func TestAccuracy(t *testing.T) {
+	sf := Accuracy(classifierDataset)
+	if got := sf(newConstGene("*.d0.c0", 100)); got != 1000 {
+		t.Errorf("Accuracy(perfect) = %v, want 1000", got)
+	}
+	if got := sf(newConstGene("c0", 1)); got != 500 {
+		t.Errorf("Accuracy(always 1) = %v, want 500", got)
+	}
+}
+
+func TestWeightsMatchDuplicatedRows(t *testing.T) {
+	g := newConstGene("*.c0.d0", 1.7)
+	c := newConstGene("-.d0.c0", 0.25)
+	weighted := &Dataset{
+		Inputs:  [][]float64{{0}, {1}, {2}, {3}, {-1}},
+		Targets: []float64{1, 3, 5, 7, 0},
+		Weights: []float64{1, 2, 1, 1, 1},
+	}
+	duplicated := &Dataset{
+		Inputs:  [][]float64{{0}, {1}, {1}, {2}, {3}, {-1}},
+		Targets: []float64{1, 3, 3, 5, 7, 0},
+	}
+	for _, factory := range []struct {
+		name string
+		f    func(*Dataset) ScoringFunc
+	}{
+		{"RMSE", RMSE},
+		{"Accuracy", Accuracy},
+		{"LogLoss", LogLoss},
+	} {
+		for _, gn := range []*Genome{g, c} {
+			got, want := factory.f(weighted)(gn), factory.f(duplicated)(gn)
+			if math.Abs(got-want) > 1e-9 {
+				t.Errorf("%v(%q) weighted = %v, duplicated = %v", factory.name, gn, got, want)
+			}
+		}
+	}
+	uniform := &Dataset{Inputs: weighted.Inputs, Targets: weighted.Targets}
+	if got, want := RMSE(weighted)(g), RMSE(uniform)(g); got == want {
+		t.Errorf("RMSE ignores weights: %v == %v", got, want)
+	}
+}
+
+func TestPenalizeNonFinite(t *testing.T) {
+	// Sqrt(d0 - 1) is NaN for x < 1, but exact elsewhere for the rows of regression.
+	regression := &Dataset{
+		Inputs:  [][]float64{{0}, {1}, {2}, {5}, {10}},
+		Targets: []float64{0, 0, 1, 2, 3},
+	}
+	// Sqrt(d0) is NaN for x < 0, but agrees with Abs(d0) elsewhere.
+	classifier := &Dataset{
+		Inputs:  [][]float64{{-1}, {-2}, {1}, {2}},
+		Targets: []float64{1, 1, 1, 1},
+	}
+	tests := []struct {
+		name      string
+		sf        ScoringFunc
+		partlyNaN *Genome
+		finite    *Genome
+	}{
+		{name: "RMSE", sf: RMSE(regression), partlyNaN: newConstGene("Sqrt.-.d0.c0", 1), finite: newConstGene("c0", 100)},
+		{name: "StreamRMSE", sf: StreamRMSE(regression.Rows(), nil), partlyNaN: newConstGene("Sqrt.-.d0.c0", 1), finite: newConstGene("c0", 100)},
+		{name: "RSquared", sf: RSquared(regression), partlyNaN: newConstGene("Sqrt.-.d0.c0", 1), finite: newConstGene("Sqrt.d0")},
+		{name: "LogLoss", sf: LogLoss(classifier), partlyNaN: newConstGene("Sqrt.d0"), finite: newConstGene("Abs.d0")},
+		{name: "Accuracy", sf: Accuracy(classifier), partlyNaN: newConstGene("Sqrt.d0"), finite: newConstGene("Abs.d0")},
+	}
+	for _, test := range tests {
+		if got, other := test.sf(test.partlyNaN), test.sf(test.finite); math.IsNaN(got) || got >= other {
+			t.Errorf("%v(%v) = %v, want < %v (%v)", test.name, test.partlyNaN, got, other, test.finite)
+		}
+	}
+
+	// Without the penalty, NaN poisons RMSE and is silently classified by Accuracy.
+	off := &ScoringConfig{AllowNonFinite: true}
+	regression.Config, classifier.Config = off, off
+	if got := RMSE(regression)(newConstGene("Sqrt.-.d0.c0", 1)); !math.IsNaN(got) {
+		t.Errorf("RMSE(partly NaN) with AllowNonFinite = %v, want NaN", got)
+	}
+	if got := Accuracy(classifier)(newConstGene("Sqrt.d0")); got != 1000 {
+		t.Errorf("Accuracy(partly NaN) with AllowNonFinite = %v, want 1000", got)
+	}
+	if got := RSquared(regression)(newConstGene("Sqrt.-.d0.c0", 1)); !math.IsNaN(got) {
+		t.Errorf("RSquared(partly NaN) with AllowNonFinite = %v, want NaN", got)
+	}
+	if got := StreamRMSE(regression.Rows(), off)(newConstGene("Sqrt.-.d0.c0", 1)); !math.IsNaN(got) {
+		t.Errorf("StreamRMSE(partly NaN) with AllowNonFinite = %v, want NaN", got)
+	}
+
+	// The penalty and the node limit of StreamRMSE are configurable.
+	small := &ScoringConfig{NonFinitePenalty: 1}
+	if got, want := StreamRMSE(regression.Rows(), small)(newConstGene("Sqrt.-.d0.c0", 1)), 1000/(1+math.Sqrt(0.2)); math.Abs(got-want) > 1e-9 {
+		t.Errorf("StreamRMSE(partly NaN) with penalty 1 = %v, want %v", got, want)
+	}
+	if got := StreamRMSE(regression.Rows(), &ScoringConfig{MaxNodes: 1})(newConstGene("Sqrt.-.d0.c0", 1)); got != 0 {
+		t.Errorf("StreamRMSE of oversized genome = %v, want 0", got)
+	}
+
+	// A partially filled config still penalizes NaN, with the default penalty.
+	partial := &ScoringConfig{MaxNodes: 100}
+	regression.Config, classifier.Config = partial, partial
+	if got, want := RMSE(regression)(newConstGene("Sqrt.-.d0.c0", 1)), 1000/(1+math.Sqrt(1e12/5)); math.Abs(got-want) > 1e-9 {
+		t.Errorf("RMSE(partly NaN) with MaxNodes only = %v, want %v", got, want)
+	}
+	if got := Accuracy(classifier)(newConstGene("Sqrt.d0")); got != 500 {
+		t.Errorf("Accuracy(partly NaN) with MaxNodes only = %v, want 500", got)
+	}
+	if got := CaseErrors(newConstGene("Sqrt.-.d0.c0", 1), regression); got[0] != 1e6 || got[1] != 0 {
+		t.Errorf("CaseErrors(partly NaN) with MaxNodes only = %v, want 1e6 at row 0 and 0 at row 1", got)
+	}
+}
+
+func TestRSquared(t *testing.T) {
+	// The targets 2x+1 have mean 5 and variance (sum of squares) 40.
+	sf := RSquared(regressionDataset)
+	tests := []struct {
+		g    *Genome
+		want float64
+	}{
+		{g: newConstGene("+.*.c1.c0.d0", 2, 1), want: 1000},
+		{g: newConstGene("c0", 5), want: 0},                                // the mean
+		{g: newConstGene("+.*.c1.c0.d0", 2, 2), want: 1000 * (1 - 5.0/40)}, // off by 1 on each row
+		{g: newConstGene("c0", 100), want: 0},                              // worse than the mean
+	}
+	for _, test := range tests {
+		if got := sf(test.g); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("RSquared(%v) = %v, want %v", test.g, got, test.want)
+		}
+	}
+}
+
+func TestBoolAccuracy(t *testing.T) {
+	inputs := [][]bool{{false, false}, {false, true}, {true, false}, {true, true}}
+	and := []bool{false, false, false, true}
+	g, err := Build().Funcs(bn.BoolAllGates).Gene("And d0 d1").Link("And").Genome()
+	if err != nil {
+		t.Fatalf("Build = %v", err)
+	}
+	if got := BoolAccuracy(inputs, and, nil, bn.BoolAllGates)(g); got != 1000 {
+		t.Errorf("BoolAccuracy(And) = %v, want 1000", got)
+	}
+	// Or matches And on all but the middle rows, which are marked as don't-care.
+	or := []bool{false, true, true, true}
+	dontCare := []bool{false, true, true, false}
+	want := BoolAccuracy(inputs, and, dontCare, bn.BoolAllGates)(g)
+	if got := BoolAccuracy(inputs, or, dontCare, bn.BoolAllGates)(g); got != want || got != 1000 {
+		t.Errorf("BoolAccuracy with flipped don't-care targets = %v, want %v", got, want)
+	}
+	if got := BoolAccuracy(inputs, or, nil, bn.BoolAllGates)(g); got != 500 {
+		t.Errorf("BoolAccuracy(Or targets) = %v, want 500", got)
+	}
+	if got := BoolAccuracy(inputs, and, []bool{true}, bn.BoolAllGates)(g); got != 0 {
+		t.Errorf("BoolAccuracy with mismatched mask = %v, want 0", got)
+	}
+}
+
+func TestConfusionMatrix(t *testing.T) {
+	inputs := [][]bool{{false, false}, {false, true}, {true, false}, {true, true}, {true, true}}
+	labels := []bool{false, true, false, true, false}
+	g, err := Build().Funcs(bn.BoolAllGates).Gene("Or d0 d1").Link("Or").Genome()
+	if err != nil {
+		t.Fatalf("Build = %v", err)
+	}
+	// Or predicts false, true, true, true, true.
+	if tp, fp, tn, fn := g.ConfusionMatrix(inputs, labels, bn.BoolAllGates); tp != 2 || fp != 2 || tn != 1 || fn != 0 {
+		t.Errorf("ConfusionMatrix(Or) = (%v, %v, %v, %v), want (2, 2, 1, 0)", tp, fp, tn, fn)
+	}
+	g, err = Build().Funcs(bn.BoolAllGates).Gene("And d0 d1").Link("And").Genome()
+	if err != nil {
+		t.Fatalf("Build = %v", err)
+	}
+	// And predicts false, false, false, true, true.
+	if tp, fp, tn, fn := g.ConfusionMatrix(inputs, labels, bn.BoolAllGates); tp != 1 || fp != 1 || tn != 2 || fn != 1 {
+		t.Errorf("ConfusionMatrix(And) = (%v, %v, %v, %v), want (1, 1, 2, 1)", tp, fp, tn, fn)
+	}
+	if tp, fp, tn, fn := g.ConfusionMatrix(inputs, labels[:2], bn.BoolAllGates); tp != 0 || fp != 0 || tn != 0 || fn != 0 {
+		t.Errorf("ConfusionMatrix with mismatched labels = (%v, %v, %v, %v), want all 0", tp, fp, tn, fn)
+	}
+}
+
+func TestF1Score(t *testing.T) {
+	// Only one row in eight is positive, so always-false is 87.5% accurate.
+	var inputs [][]bool
+	var labels []bool
+	for i := 0; i < 8; i++ {
+		inputs = append(inputs, []bool{i&4 != 0, i&2 != 0, i&1 != 0})
+		labels = append(labels, i == 7)
+	}
+	build := func(karva string) *Genome {
+		g, err := Build().Funcs(bn.BoolAllGates).Gene(karva).Link("And").Genome()
+		if err != nil {
+			t.Fatalf("Build(%q) = %v", karva, err)
+		}
+		return g
+	}
+	sf := F1Score(inputs, labels, bn.BoolAllGates)
+	if got := sf(build("And And d0 d1 d2")); got != 1000 {
+		t.Errorf("F1Score(perfect) = %v, want 1000", got)
+	}
+	if got := sf(build("And Not d0 d0")); got != 0 {
+		t.Errorf("F1Score(always false) = %v, want 0", got)
+	}
+	// And d0 d1 has 1 true positive and 1 false positive: F1 = 2/(2+1).
+	if got, want := sf(build("And d0 d1")), 2000.0/3; math.Abs(got-want) > 1e-9 {
+		t.Errorf("F1Score(And d0 d1) = %v, want %v", got, want)
+	}
+	if got := F1Score(inputs, labels[:1], bn.BoolAllGates)(build("And d0 d1")); got != 0 {
+		t.Errorf("F1Score with mismatched labels = %v, want 0", got)
+	}
+}
+
+func TestMaxNodes(t *testing.T) {
+	// Each ADF is a complete binary tree of 127 nodes, called by a homeotic gene.
+	adf := strings.TrimSuffix(strings.Repeat("+.", 63)+strings.Repeat("d0.", 64), ".")
+	var adfs []*gene.Gene
+	for i := 0; i < 100; i++ {
+		adfs = append(adfs, gene.New(adf))
+	}
+	huge := NewHomeotic(adfs, gene.New("+.d0.d99.d0.d0"))
+	if got, want := huge.EvaluatedNodes(), 100*127+3; got != want {
+		t.Errorf("EvaluatedNodes of homeotic genome = %v, want %v", got, want)
+	}
+	small := newConstGene("+.*.c1.c0.d0", 2, 1) // 2*x + 1
+	if got, want := newGenome("*", "+.d0.d1.d0", "d0.d1").EvaluatedNodes(), 3+1+1; got != want {
+		t.Errorf("EvaluatedNodes of multigenic genome = %v, want %v", got, want)
+	}
+
+	ds := &Dataset{Inputs: regressionDataset.Inputs, Targets: regressionDataset.Targets, Config: &ScoringConfig{MaxNodes: 1000}}
+	if err := ds.Config.CheckNodes(huge); err == nil {
+		t.Errorf("CheckNodes of %v-node genome = nil, want error", huge.EvaluatedNodes())
+	}
+	if err := ds.Config.CheckNodes(small); err != nil {
+		t.Errorf("CheckNodes of small genome = %v, want nil", err)
+	}
+	// The homeotic gene of the recursive genome calls its single ADF 64 times,
+	// so it passes CheckNodes but expands to 63 + 64*127 nodes when evaluated.
+	calls := strings.TrimSuffix(strings.Repeat("+.", 63)+strings.Repeat("d0.", 64), ".")
+	recursive := NewHomeotic([]*gene.Gene{gene.New(adf)}, gene.New(calls))
+	if err := ds.Config.CheckNodes(recursive); err != nil {
+		t.Errorf("CheckNodes of recursive genome = %v, want nil", err)
+	}
+	if got, err := recursive.EvalMathLimit([]float64{1}, ds.Config.MaxNodes); !errors.Is(err, ErrTooManyNodes) {
+		t.Errorf("EvalMathLimit of recursive genome = (%v, %v), want ErrTooManyNodes", got, err)
+	}
+	if got, err := recursive.EvalMathLimit([]float64{1}, 63+64*127); err != nil || got != 64*64 {
+		t.Errorf("EvalMathLimit of recursive genome within its size = (%v, %v), want %v", got, err, 64*64)
+	}
+	if got, err := small.EvalMathLimit([]float64{3}, ds.Config.MaxNodes); err != nil || got != 7 {
+		t.Errorf("EvalMathLimit(small) = (%v, %v), want 7", got, err)
+	}
+
+	factories := map[string]func(*Dataset) ScoringFunc{
+		"RMSE":       RMSE,
+		"RSquared":   RSquared,
+		"Accuracy":   Accuracy,
+		"LogLoss":    LogLoss,
+		"StreamRMSE": func(ds *Dataset) ScoringFunc { return StreamRMSE(ds.Rows(), ds.Config) },
+	}
+	for name, sf := range factories {
+		for _, g := range []*Genome{huge, recursive} {
+			if got := sf(ds)(g); got != 0 {
+				t.Errorf("%v of oversized genome %v = %v, want 0", name, g.Homeotic, got)
+			}
+		}
+		if got := sf(ds)(small); got == 0 {
+			t.Errorf("%v of small genome = 0, want non-zero", name)
+		}
+	}
+	for i, e := range CaseErrors(recursive, ds) {
+		if !math.IsInf(e, 1) {
+			t.Errorf("CaseErrors of recursive genome [%v] = %v, want +Inf", i, e)
+		}
+	}
+}
+
+func TestGeneralizationGap(t *testing.T) {
+	// The training targets of 2*x + 1 carry a deterministic "noise" of
+	// 0.5*sin(7*x), which the validation targets lack.
+	train, val := &Dataset{}, &Dataset{}
+	for x := 0.0; x < 4; x += 0.25 {
+		train.Inputs = append(train.Inputs, []float64{x})
+		train.Targets = append(train.Targets, 2*x+1+0.5*math.Sin(7*x))
+		v := x + 0.125
+		val.Inputs = append(val.Inputs, []float64{v})
+		val.Targets = append(val.Targets, 2*v+1)
+	}
+	simple := newConstGene("+.*.c1.c0.d0", 2, 1)
+	// The overfit genome also fits the noise, scoring perfectly in training.
+	noise := gene.New("*.c0.Sin.*.c1.d0")
+	noise.Constants = []float64{0.5, 7}
+	overfit := New([]*gene.Gene{simple.Genes[0].Dup(), noise}, "+")
+	if got := RMSE(train)(overfit); got < 999.99 {
+		t.Fatalf("overfit genome training score = %v, want nearly 1000", got)
+	}
+
+	simpleGap := simple.GeneralizationGap(train, val, RMSE)
+	overfitGap := overfit.GeneralizationGap(train, val, RMSE)
+	if overfitGap <= simpleGap || overfitGap <= 0 {
+		t.Errorf("GeneralizationGap of overfit genome = %v, want positive and larger than that of simple genome = %v", overfitGap, simpleGap)
+	}
+	if want := RMSE(train)(simple) - RMSE(val)(simple); simpleGap != want {
+		t.Errorf("GeneralizationGap of simple genome = %v, want %v", simpleGap, want)
+	}
+	if simple.Score != 0 || overfit.Score != 0 {
+		t.Errorf("GeneralizationGap altered the scores: %v, %v", simple.Score, overfit.Score)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import "math"
+
+// OutputStats evaluates the genome over the inputs of the dataset and returns
+// the minimum, maximum, mean, and (population) standard deviation of its finite
+// predictions, along with the number of predictions that were NaN or infinite
+// (which are excluded from the statistics). A degenerate genome is revealed by
+// a zero standard deviation (constant output) or by non-finite predictions.
+// If no prediction is finite, all the statistics are zero.
+func (g *Genome) OutputStats(ds *Dataset) (min, max, mean, stddev float64, nonFinite int) {
+	n := 0
+	min, max = math.Inf(1), math.Inf(-1)
+	var values []float64
+	for _, v := range g.EvalMathBatch(ds.Inputs) {
+		if !isFinite(v) {
+			nonFinite++
+			continue
+		}
+		values = append(values, v)
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+		mean += v
+		n++
+	}
+	if n == 0 {
+		return 0, 0, 0, 0, nonFinite
+	}
+	mean /= float64(n)
+	for _, v := range values {
+		d := v - mean
+		stddev += d * d
+	}
+	return min, max, mean, math.Sqrt(stddev / float64(n)), nonFinite
+}
+
+// constantTolerance is the standard deviation of the output, relative to the
+// magnitude of its mean (or absolute, for means smaller than 1), below which
+// IsConstant considers the output constant.
+const constantTolerance = 1e-9
+
+// IsConstant reports whether the output of the genome is (practically) the
+// same for every row of the dataset, as is the case for degenerate genomes that
+// ignore their inputs. As in OutputStats, NaN and infinite outputs are ignored.
+func (g *Genome) IsConstant(ds *Dataset) bool {
+	_, _, mean, stddev, _ := g.OutputStats(ds)
+	return stddev <= constantTolerance*math.Max(1, math.Abs(mean))
+}
+
+// Residuals evaluates the genome over the inputs of the dataset and returns
+// the residual (target minus prediction) of each row, in the order of the rows.
+// A row whose prediction is NaN or infinite has a NaN residual; use
+// ResidualsFlagged to tell these rows apart from those with NaN targets.
+// Residual plots reveal systematic biases that aggregate scores hide.
+func (g *Genome) Residuals(ds *Dataset) []float64 {
+	r, _ := g.ResidualsFlagged(ds)
+	return r
+}
+
+// ResidualsFlagged is like Residuals, but also returns the indices of the
+// rows whose predictions are NaN or infinite.
+func (g *Genome) ResidualsFlagged(ds *Dataset) (residuals []float64, nonFinite []int) {
+	residuals = g.EvalMathBatch(ds.Inputs)
+	for i, v := range residuals {
+		if !isFinite(v) {
+			residuals[i] = math.NaN()
+			nonFinite = append(nonFinite, i)
+			continue
+		}
+		residuals[i] = ds.Targets[i] - v
+	}
+	return residuals, nonFinite
+}
+
+// RedundantGenePairs returns the index pairs (i, j), with i < j and in order,
+// of the genes of the genome whose outputs are identical (within a relative
+// tolerance of 1e-9, with NaNs equal to each other) for every row of the
+// dataset. Such genes compute the same function, wasting capacity, and are
+// candidates for pruning.
+func (g *Genome) RedundantGenePairs(ds *Dataset) [][2]int {
+	outputs := g.geneOutputs(ds)
+	var result [][2]int
+	for i := range outputs {
+		for j := i + 1; j < len(outputs); j++ {
+			if sameOutputs(outputs[i], outputs[j]) {
+				result = append(result, [2]int{i, j})
+			}
+		}
+	}
+	return result
+}
+
+// MatchesTarget reports whether the genome is semantically equivalent to
+// target over the dataset: whether their outputs agree within the absolute
+// tolerance tol for every row (with NaNs matching each other and infinities
+// matching only themselves). The dataset targets are not used. A dataset
+// with no rows matches nothing, since it cannot establish equivalence.
+// Like SymbolCount, this currently only works for Math expressions.
+func (g *Genome) MatchesTarget(target *Genome, ds *Dataset, tol float64) bool {
+	if ds == nil || len(ds.Inputs) == 0 {
+		return false
+	}
+	for _, in := range ds.Inputs {
+		x, y := g.EvalMath(in), target.EvalMath(in)
+		switch {
+		case math.IsNaN(x) || math.IsNaN(y):
+			if math.IsNaN(x) != math.IsNaN(y) {
+				return false
+			}
+		case math.IsInf(x, 0) || math.IsInf(y, 0):
+			if x != y {
+				return false
+			}
+		case !(math.Abs(x-y) <= tol):
+			return false
+		}
+	}
+	return true
+}
+
+// sameOutputs reports whether the outputs a and b are identical within
+// constantTolerance (relative to their magnitude, or absolute below 1).
+func sameOutputs(a, b []float64) bool {
+	for i, x := range a {
+		y := b[i]
+		switch {
+		case math.IsNaN(x) || math.IsNaN(y):
+			if math.IsNaN(x) != math.IsNaN(y) {
+				return false
+			}
+		case math.IsInf(x, 0) || math.IsInf(y, 0):
+			if x != y {
+				return false
+			}
+		case math.Abs(x-y) > constantTolerance*math.Max(1, math.Max(math.Abs(x), math.Abs(y))):
+			return false
+		}
+	}
+	return true
+}
+
+// GeneCorrelations returns the matrix of the Pearson correlations between the
+// outputs of each pair of genes of the genome over the rows of the dataset,
+// ignoring the rows for which either output is NaN or infinite. Highly
+// correlated genes signal redundancy, while anti-correlated genes may be
+// complementary. The correlation is NaN for a gene whose output is constant
+// (including on the diagonal).
+func (g *Genome) GeneCorrelations(ds *Dataset) [][]float64 {
+	outputs := g.geneOutputs(ds)
+	result := make([][]float64, len(outputs))
+	for i := range result {
+		result[i] = make([]float64, len(outputs))
+	}
+	for i := range outputs {
+		for j := i; j < len(outputs); j++ {
+			r := correlation(outputs[i], outputs[j])
+			result[i][j], result[j][i] = r, r
+		}
+	}
+	return result
+}
+
+// correlation returns the Pearson correlation of the finite pairs of a and b,
+// or NaN if either has no variance.
+func correlation(a, b []float64) float64 {
+	var sx, sy, sxx, syy, sxy, n float64
+	for i, x := range a {
+		y := b[i]
+		if !isFinite(x) || !isFinite(y) {
+			continue
+		}
+		sx, sy, sxx, syy, sxy, n = sx+x, sy+y, sxx+x*x, syy+y*y, sxy+x*y, n+1
+	}
+	vx, vy := sxx-sx*sx/n, syy-sy*sy/n
+	if n == 0 || vx <= 0 || vy <= 0 {
+		return math.NaN()
+	}
+	return (sxy - sx*sy/n) / math.Sqrt(vx*vy)
+}
+
+// geneOutputs evaluates each gene of the genome (applying its MissingPolicy)
+// over the inputs of the dataset and returns the outputs of each gene.
+func (g *Genome) geneOutputs(ds *Dataset) [][]float64 {
+	outputs := make([][]float64, len(g.Genes))
+	for i := range g.Genes {
+		outputs[i] = make([]float64, len(ds.Inputs))
+	}
+	for j, in := range ds.Inputs {
+		for i, v := range g.EvalMathPerGene(in) {
+			outputs[i][j] = v
+		}
+	}
+	return outputs
+}
@@ -0,0 +1,148 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestOutputStats(t *testing.T) {
+	tests := []struct {
+		name                   string
+		g                      *Genome
+		min, max, mean, stddev float64
+		nonFinite              int
+	}{
+		{name: "constant", g: newConstGene("c0", 4.5), min: 4.5, max: 4.5, mean: 4.5},
+		// y = 2*x + 1 over x = 0..4 gives 1, 3, 5, 7, 9.
+		{name: "linear", g: newConstGene("+.*.c1.c0.d0", 2, 1), min: 1, max: 9, mean: 5, stddev: math.Sqrt(8)},
+		// 1/x is infinite at x = 0, leaving 1, 1/2, 1/3, 1/4.
+		{name: "exploding", g: newConstGene("/.c0.d0", 1), min: 0.25, max: 1, mean: 25.0 / 48, stddev: math.Sqrt((1+0.25+1.0/9+1.0/16)/4 - 25.0*25/48/48), nonFinite: 1},
+		{name: "all non-finite", g: newConstGene("/.c0.c1", 1, 0), nonFinite: 5},
+	}
+	for _, test := range tests {
+		min, max, mean, stddev, nonFinite := test.g.OutputStats(regressionDataset)
+		if math.Abs(min-test.min) > 1e-12 || math.Abs(max-test.max) > 1e-12 || math.Abs(mean-test.mean) > 1e-12 || math.Abs(stddev-test.stddev) > 1e-12 || nonFinite != test.nonFinite {
+			t.Errorf("%v: OutputStats = (%v, %v, %v, %v, %v), want (%v, %v, %v, %v, %v)", test.name, min, max, mean, stddev, nonFinite, test.min, test.max, test.mean, test.stddev, test.nonFinite)
+		}
+	}
+}
+
+func TestIsConstant(t *testing.T) {
+	tests := []struct {
+		name string
+		g    *Genome
+		want bool
+	}{
+		{name: "constant", g: newConstGene("c0", 4.5), want: true},
+		// (c0*c1)-c0 never reads an input.
+		{name: "constant expression", g: newConstGene("-.*.c0.c0.c1", 3, 7), want: true},
+		// d0-d0 reads an input but cancels it out.
+		{name: "cancelling input", g: newConstGene("-.d0.d0"), want: true},
+		{name: "large constant", g: newConstGene("*.c0.c0", 1e8), want: true},
+		{name: "input", g: newConstGene("d0"), want: false},
+		{name: "expression of input", g: newConstGene("+.*.c1.c0.d0", 2, 1), want: false},
+	}
+	for _, test := range tests {
+		if got := test.g.IsConstant(regressionDataset); got != test.want {
+			t.Errorf("%v: IsConstant(%v) = %v, want %v", test.name, test.g, got, test.want)
+		}
+	}
+}
+
+func TestResiduals(t *testing.T) {
+	// y = 2*x + 1.5 overestimates every target by 0.5.
+	g := newConstGene("+.*.c1.c0.d0", 2, 1.5)
+	got := g.Residuals(regressionDataset)
+	if len(got) != len(regressionDataset.Targets) {
+		t.Fatalf("Residuals = %v, want %v residuals", got, len(regressionDataset.Targets))
+	}
+	for i, v := range got {
+		if v != -0.5 {
+			t.Errorf("Residuals[%v] = %v, want -0.5", i, v)
+		}
+	}
+
+	// 1/x is infinite at x = 0.
+	residuals, nonFinite := newConstGene("/.c0.d0", 1).ResidualsFlagged(regressionDataset)
+	if !math.IsNaN(residuals[0]) || len(nonFinite) != 1 || nonFinite[0] != 0 {
+		t.Errorf("ResidualsFlagged of 1/x = (%v, %v), want NaN residual flagged at row 0", residuals, nonFinite)
+	}
+	if want := 3 - 1.0; residuals[1] != want {
+		t.Errorf("ResidualsFlagged of 1/x residual[1] = %v, want %v", residuals[1], want)
+	}
+}
+
+func TestRedundantGenePairs(t *testing.T) {
+	// Genes #0 and #2 are identical, #1 and #3 differ in form but not in
+	// function, and #4 is unique.
+	g := newGenome("+", "*.d0.d0.d0", "+.d0.d0.d0", "*.d0.d0.d1", "*.c0.d0.d0", "-.d0.d0.d0")
+	g.Genes[3].Constants = []float64{2}
+	got := g.RedundantGenePairs(regressionDataset)
+	if want := [][2]int{{0, 2}, {1, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RedundantGenePairs = %v, want %v", got, want)
+	}
+	if got := newGenome("+", "d0", "Inv.d0").RedundantGenePairs(regressionDataset); got != nil {
+		t.Errorf("RedundantGenePairs of distinct genes = %v, want nil", got)
+	}
+}
+
+func TestMatchesTarget(t *testing.T) {
+	target := newGenome("+", "*.d0.d0.d0", "d0") // x*x + x
+	tests := []struct {
+		g    *Genome
+		tol  float64
+		want bool
+	}{
+		{g: newGenome("*", "d0", "+.d0.c0"), tol: 1e-9, want: true}, // x*(x+1)
+		{g: newGenome("+", "d0", "*.d0.d0.d0"), tol: 0, want: true}, // x + x*x
+		{g: newGenome("+", "*.d0.d0.d0", "d0", "c0"), tol: 0.5, want: true},
+		{g: newGenome("+", "*.d0.d0.d0", "d0", "c0"), tol: 0.05},
+		{g: newGenome("*", "d0", "d0"), tol: 1e-9},
+	}
+	tests[0].g.Genes[1].Constants = []float64{1}
+	tests[2].g.Genes[2].Constants = []float64{0.1}
+	tests[3].g.Genes[2].Constants = []float64{0.1}
+	for i, test := range tests {
+		if got := test.g.MatchesTarget(target, regressionDataset, test.tol); got != test.want {
+			t.Errorf("%v: %v MatchesTarget(%v, %v) = %v, want %v", i, test.g, target, test.tol, got, test.want)
+		}
+	}
+	if target.MatchesTarget(target, &Dataset{}, 1) {
+		t.Errorf("MatchesTarget on an empty dataset = true, want false")
+	}
+}
+
+func TestGeneCorrelations(t *testing.T) {
+	// Over x = 0..4: x, 3*x (scaled), -x (anti-correlated), x*x, and 1/x
+	// (infinite at x = 0, which is ignored).
+	g := newGenome("+", "d0", "*.c0.d0", "Neg.d0", "*.d0.d0", "Inv.d0")
+	g.Genes[1].Constants = []float64{3}
+	got := g.GeneCorrelations(regressionDataset)
+	if len(got) != len(g.Genes) {
+		t.Fatalf("GeneCorrelations = %v, want %v rows", got, len(g.Genes))
+	}
+	// Correlation of x with x*x over 0..4: cov = 8, var(x) = 2, var(x*x) = 34.8 (population).
+	xx := 8 / math.Sqrt(2*34.8)
+	for _, test := range []struct {
+		i, j int
+		want float64
+	}{
+		{0, 0, 1}, {1, 1, 1}, {2, 2, 1}, {3, 3, 1}, {4, 4, 1},
+		{0, 1, 1}, {1, 0, 1}, {0, 2, -1}, {1, 2, -1}, {0, 3, xx}, {3, 0, xx},
+	} {
+		if math.Abs(got[test.i][test.j]-test.want) > 1e-12 {
+			t.Errorf("GeneCorrelations[%v][%v] = %v, want %v", test.i, test.j, got[test.i][test.j], test.want)
+		}
+	}
+	if r := got[0][4]; !(r < 0 && r > -1) {
+		t.Errorf("GeneCorrelations of x and 1/x = %v, want negative", r)
+	}
+	if got := newGenome("+", "d0", "c0").GeneCorrelations(regressionDataset); !math.IsNaN(got[1][1]) || !math.IsNaN(got[0][1]) {
+		t.Errorf("GeneCorrelations with constant gene = %v, want NaN", got)
+	}
+}
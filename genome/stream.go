@@ -0,0 +1,59 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import "math"
+
+// RowIterator returns the inputs and target of the next row of a dataset.
+// ok is false once the rows are exhausted.
+type RowIterator func() (in []float64, target float64, ok bool)
+
+// RowSource returns a fresh RowIterator positioned at the first row of a
+// dataset. Scoring functions built from a RowSource obtain a new iterator
+// for each genome they score, so the rows need never be held in memory.
+type RowSource func() RowIterator
+
+// Rows returns a RowSource over the rows of the dataset.
+// Weights are ignored.
+func (d *Dataset) Rows() RowSource {
+	return func() RowIterator {
+		i := 0
+		return func() ([]float64, float64, bool) {
+			if i >= len(d.Targets) {
+				return nil, 0, false
+			}
+			i++
+			return d.Inputs[i-1], d.Targets[i-1], true
+		}
+	}
+}
+
+// StreamRMSE is like RMSE, but reads the rows from src in a single pass
+// per genome rather than from an in-memory Dataset. All rows are weighted equally,
+// and non-finite predictions and oversized genomes are treated as configured by
+// cfg (as by Dataset.Config, so nil means DefaultScoringConfig).
+func StreamRMSE(src RowSource, cfg *ScoringConfig) ScoringFunc {
+	c := (&Dataset{Config: cfg}).config()
+	return func(g *Genome) float64 {
+		if c.CheckNodes(g) != nil {
+			return 0.0
+		}
+		sum, n := 0.0, 0
+		next := src()
+		for in, t, ok := next(); ok; in, t, ok = next() {
+			r, err := c.eval(g, in)
+			if err != nil {
+				return 0.0
+			}
+			e := c.error(r, t)
+			sum += e * e
+			n++
+		}
+		if n == 0 {
+			return 0.0
+		}
+		return 1000.0 / (1.0 + math.Sqrt(sum/float64(n)))
+	}
+}
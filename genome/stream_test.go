@@ -0,0 +1,49 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import "testing"
+
+func TestStreamRMSE(t *testing.T) {
+	// generated produces the rows of y = x*x lazily, without a backing slice.
+	const numRows = 1000
+	generated := func() RowIterator {
+		i := 0
+		return func() ([]float64, float64, bool) {
+			if i >= numRows {
+				return nil, 0, false
+			}
+			x := float64(i) / 100
+			i++
+			return []float64{x}, x * x, true
+		}
+	}
+	ds := &Dataset{}
+	next := generated()
+	for in, y, ok := next(); ok; in, y, ok = next() {
+		ds.Inputs = append(ds.Inputs, in)
+		ds.Targets = append(ds.Targets, y)
+	}
+
+	genomes := []*Genome{
+		newConstGene("*.d0.d0"),
+		newConstGene("*.c0.d0", 2),
+		newConstGene("+.*.c0.d0.d0", 0.5),
+	}
+	for _, g := range genomes {
+		want := RMSE(ds)(g)
+		if got := StreamRMSE(generated, nil)(g); got != want {
+			t.Errorf("StreamRMSE(%v) = %v, want %v", g, got, want)
+		}
+		sf := StreamRMSE(ds.Rows(), nil)
+		sf(g) // The iterator must be re-obtained for each evaluation.
+		if got := sf(g); got != want {
+			t.Errorf("StreamRMSE(ds.Rows())(%v) = %v, want %v", g, got, want)
+		}
+	}
+	if got := StreamRMSE((&Dataset{}).Rows(), nil)(genomes[0]); got != 0 {
+		t.Errorf("StreamRMSE(empty) = %v, want 0", got)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import "github.com/gmlewis/gep/functions"
+
+// maxTruthTableInputs limits the size of a truth table to 2^20 rows.
+const maxTruthTableInputs = 20
+
+// TruthTable evaluates the boolean genome with every one of the 2^numInputs
+// combinations of its inputs and returns one row per combination, holding the
+// inputs (d0 through d<numInputs-1>) followed by the output of EvalBool.
+// The rows are in counting order with d0 as the most significant bit, so the
+// first row is all false and the last is all true.
+// fm is the map of available boolean functions to the genome.
+// numInputs may be at most 20; otherwise nil is returned.
+func (g *Genome) TruthTable(numInputs int, fm functions.FuncMap) [][]bool {
+	if numInputs < 0 || numInputs > maxTruthTableInputs {
+		functions.Log.Printf("genome.TruthTable error: numInputs %v must be between 0 and %v", numInputs, maxTruthTableInputs)
+		return nil
+	}
+	result := make([][]bool, 1<<uint(numInputs))
+	for i := range result {
+		row := make([]bool, numInputs+1)
+		for j := 0; j < numInputs; j++ {
+			row[j] = i&(1<<uint(numInputs-1-j)) != 0
+		}
+		row[numInputs] = g.EvalBool(row[:numInputs], fm)
+		result[i] = row
+	}
+	return result
+}
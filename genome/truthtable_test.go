@@ -0,0 +1,34 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+	"reflect"
+	"testing"
+
+	bn "github.com/gmlewis/gep/functions/bool_nodes"
+)
+
+func TestTruthTable(t *testing.T) {
+	g, err := Build().Funcs(bn.BoolAllGates).Gene("Xor d0 d1").Link("And").Genome()
+	if err != nil {
+		t.Fatalf("Build = %v", err)
+	}
+	want := [][]bool{
+		{false, false, false},
+		{false, true, true},
+		{true, false, true},
+		{true, true, false},
+	}
+	if got := g.TruthTable(2, bn.BoolAllGates); !reflect.DeepEqual(got, want) {
+		t.Errorf("XOR TruthTable = %v, want %v", got, want)
+	}
+	if got := g.TruthTable(3, bn.BoolAllGates); len(got) != 8 || len(got[0]) != 4 {
+		t.Errorf("TruthTable(3) has %v rows, want 8 rows of 4", len(got))
+	}
+	if got := g.TruthTable(21, bn.BoolAllGates); got != nil {
+		t.Errorf("TruthTable(21) returned %v rows, want nil", len(got))
+	}
+}
@@ -9,7 +9,6 @@ package grammars
 import (
 	"encoding/xml"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -254,13 +253,13 @@ func loadGrammar(path string) (*Grammar, error) {
 
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		log.Printf("unable to read file %q: %q", path, err)
+		functions.Log.Printf("unable to read file %q: %q", path, err)
 		return nil, err
 	}
 
 	err = xml.Unmarshal(data, &v)
 	if err != nil {
-		log.Printf("error unmarshaling %q: %q", path, err)
+		functions.Log.Printf("error unmarshaling %q: %q", path, err)
 		return nil, err
 	}
 
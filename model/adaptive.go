@@ -0,0 +1,82 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import "math"
+
+// AdaptiveRate configures an adaptive mutation rate for Train, after the 1/5th
+// success rule: whenever fewer than a fifth of the recent generations improved
+// upon the score of the best genome ever seen, training is stalling and the rate is raised
+// to explore more widely; whenever more than a fifth did, the rate is lowered
+// to exploit the progress. The rate replaces TrainConfig.MutateRate, and the
+// current rate is reported to TrainConfig.OnGeneration.
+type AdaptiveRate struct {
+	// Initial is the starting rate. If zero, TrainConfig.MutateRate is used,
+	// or 0.05 if that is zero as well.
+	Initial float64
+	// Min and Max bound the rate. If zero, they are 0.001 and 0.5.
+	Min, Max float64
+	// Factor is the multiplier (greater than 1) by which the rate is raised
+	// or lowered each generation. If zero, it is 1.2.
+	Factor float64
+	// Window is the number of recent generations whose success is considered.
+	// If zero, it is 10.
+	Window int
+}
+
+// adaptiveRate is the state of an AdaptiveRate during training.
+type adaptiveRate struct {
+	cfg       *AdaptiveRate
+	rate      float64
+	successes []bool
+}
+
+// newAdaptiveRate returns the state of a, starting from the mutation rate of
+// TrainConfig.MutateRate (if a has no initial rate).
+func newAdaptiveRate(a *AdaptiveRate, mutateRate float64) *adaptiveRate {
+	r := &adaptiveRate{cfg: a, rate: a.Initial}
+	if r.rate <= 0 {
+		r.rate = mutateRate
+	}
+	if r.rate <= 0 {
+		r.rate = 0.05
+	}
+	return r
+}
+
+// update records whether the latest generation improved upon the score of the
+// best genome ever seen, and adjusts and returns the rate accordingly.
+func (r *adaptiveRate) update(improved bool) float64 {
+	window, factor, lo, hi := r.cfg.Window, r.cfg.Factor, r.cfg.Min, r.cfg.Max
+	if window <= 0 {
+		window = 10
+	}
+	if factor <= 1 {
+		factor = 1.2
+	}
+	if lo <= 0 {
+		lo = 0.001
+	}
+	if hi <= 0 {
+		hi = 0.5
+	}
+	if r.successes = append(r.successes, improved); len(r.successes) > window {
+		r.successes = r.successes[1:]
+	}
+	n := 0
+	for _, v := range r.successes {
+		if v {
+			n++
+		}
+	}
+	switch p := float64(n) / float64(len(r.successes)); {
+	case p < 0.2:
+		r.rate *= factor
+	case p > 0.2:
+		r.rate /= factor
+	}
+	r.rate = math.Min(math.Max(r.rate, lo), hi)
+	return r.rate
+}
@@ -0,0 +1,84 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"sync"
+	"testing"
+
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+	"github.com/gmlewis/gep/genome"
+)
+
+func TestTrainAdaptiveRate(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	const generations, initial = 10, 0.05
+	rates := func(sf genome.ScoringFunc) []float64 {
+		var r []float64
+		e := New(funcs, mn.Math, 10, 8, 2, 1, 0, "+", sf)
+		e.Train(TrainConfig{
+			Generations:  generations,
+			AdaptiveRate: &AdaptiveRate{Initial: initial},
+			OnGeneration: func(p Progress) { r = append(r, p.MutateRate) },
+		})
+		if len(r) != generations {
+			t.Fatalf("OnGeneration called %v times, want %v", len(r), generations)
+		}
+		return r
+	}
+
+	// A stalling run never improves upon its first genome.
+	stalling := rates(func(g *genome.Genome) float64 { return 1 })
+	if stalling[0] != initial {
+		t.Errorf("stalling run rate[0] = %v, want %v", stalling[0], initial)
+	}
+	for i := 1; i < generations; i++ {
+		if stalling[i] <= stalling[i-1] {
+			t.Errorf("stalling run rates = %v, want increasing", stalling)
+			break
+		}
+	}
+
+	// Each genome scores the number of genomes scored before it, so every
+	// generation improves.
+	var mu sync.Mutex
+	calls := 0
+	improving := rates(func(g *genome.Genome) float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return float64(calls)
+	})
+	for i := 1; i < generations; i++ {
+		if improving[i] >= improving[i-1] {
+			t.Errorf("improving run rates = %v, want decreasing", improving)
+			break
+		}
+	}
+}
+
+func TestAdaptiveRateBounds(t *testing.T) {
+	r := newAdaptiveRate(&AdaptiveRate{Min: 0.01, Max: 0.1, Factor: 2}, 0.04)
+	if r.rate != 0.04 {
+		t.Errorf("initial rate = %v, want TrainConfig.MutateRate 0.04", r.rate)
+	}
+	for i := 0; i < 10; i++ {
+		r.update(false)
+	}
+	if r.rate != 0.1 {
+		t.Errorf("rate after stalling = %v, want Max 0.1", r.rate)
+	}
+	for i := 0; i < 20; i++ {
+		r.update(true)
+	}
+	if r.rate != 0.01 {
+		t.Errorf("rate after improving = %v, want Min 0.01", r.rate)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"sort"
+)
+
+// Report summarizes the comparison of two configurations by CompareRuns.
+type Report struct {
+	// MeanA, MeanB, VarA, and VarB are the means and (sample) variances of the
+	// final best scores of the runs of configurations a and b.
+	MeanA, MeanB float64
+	VarA, VarB   float64
+	// U is the Mann-Whitney U statistic of a: the number of pairs of runs
+	// (one from each configuration) in which a scored higher, counting ties as half.
+	U float64
+	// PValue is the two-sided p-value of the Mann-Whitney U test (using the
+	// normal approximation with tie and continuity corrections) of the
+	// hypothesis that neither configuration tends to score higher.
+	PValue float64
+	// Direction is 1 if a tends to score higher than b, -1 if b tends to score
+	// higher than a, and 0 if neither does. Check PValue for its significance.
+	Direction int
+}
+
+// CompareRuns compares two configurations of the model from the results of
+// several runs of each (for example, with different random seeds). Each run is
+// the series of best scores of its generations, and is represented by its
+// final score. Runs with no scores are ignored.
+// If either configuration has no runs, the p-value is NaN.
+func CompareRuns(a, b [][]float64) Report {
+	x, y := finalScores(a), finalScores(b)
+	r := Report{
+		MeanA:  Mean(x),
+		MeanB:  Mean(y),
+		VarA:   variance(x),
+		VarB:   variance(y),
+		PValue: math.NaN(),
+	}
+	if len(x) == 0 || len(y) == 0 {
+		return r
+	}
+	r.U = mannWhitneyU(x, y)
+	n1, n2 := float64(len(x)), float64(len(y))
+	mu := n1 * n2 / 2
+	switch {
+	case r.U > mu:
+		r.Direction = 1
+	case r.U < mu:
+		r.Direction = -1
+	}
+	sigma := math.Sqrt(n1 * n2 / 12 * (n1 + n2 + 1 - tieCorrection(append(append([]float64{}, x...), y...))))
+	if sigma == 0 {
+		r.PValue = 1
+		return r
+	}
+	z := math.Max(math.Abs(r.U-mu)-0.5, 0) / sigma
+	r.PValue = math.Erfc(z / math.Sqrt2)
+	return r
+}
+
+// finalScores returns the last score of each non-empty run.
+func finalScores(runs [][]float64) []float64 {
+	var r []float64
+	for _, v := range runs {
+		if len(v) > 0 {
+			r = append(r, v[len(v)-1])
+		}
+	}
+	return r
+}
+
+// variance returns the sample variance of the values, or NaN if there are fewer than two.
+func variance(values []float64) float64 {
+	if len(values) < 2 {
+		return math.NaN()
+	}
+	mean := Mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return sum / float64(len(values)-1)
+}
+
+// mannWhitneyU returns the number of pairs (one value from each of x and y)
+// in which the value from x is the greater, counting ties as half.
+func mannWhitneyU(x, y []float64) float64 {
+	u := 0.0
+	for _, a := range x {
+		for _, b := range y {
+			switch {
+			case a > b:
+				u++
+			case a == b:
+				u += 0.5
+			}
+		}
+	}
+	return u
+}
+
+// tieCorrection returns the correction to the variance of the U statistic for
+// the ties among the pooled values: the sum of t^3-t over each group of t tied
+// values, divided by n(n-1) for n values.
+func tieCorrection(pooled []float64) float64 {
+	n := float64(len(pooled))
+	if n < 2 {
+		return 0
+	}
+	sort.Float64s(pooled)
+	sum := 0.0
+	for i := 0; i < len(pooled); {
+		j := i + 1
+		for j < len(pooled) && pooled[j] == pooled[i] {
+			j++
+		}
+		t := float64(j - i)
+		sum += t*t*t - t
+		i = j
+	}
+	return sum / (n * (n - 1))
+}
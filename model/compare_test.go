@@ -0,0 +1,48 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompareRuns(t *testing.T) {
+	// Each run improves over three generations; only the final scores matter.
+	runs := func(finals ...float64) [][]float64 {
+		var r [][]float64
+		for _, v := range finals {
+			r = append(r, []float64{v / 4, v / 2, v})
+		}
+		return r
+	}
+	low := runs(100, 120, 110, 130, 105, 125, 115, 135, 90, 140)
+	high := runs(800, 820, 810, 830, 805, 825, 815, 835, 790, 840)
+
+	r := CompareRuns(high, low)
+	if r.Direction != 1 || r.U != 100 || r.PValue > 0.001 {
+		t.Errorf("CompareRuns(high, low) = %+v, want Direction 1, U 100, PValue < 0.001", r)
+	}
+	if math.Abs(r.MeanA-817) > 1e-9 || math.Abs(r.MeanB-117) > 1e-9 {
+		t.Errorf("CompareRuns(high, low) means = %v, %v, want 817, 117", r.MeanA, r.MeanB)
+	}
+	if want := 2310.0 / 9; math.Abs(r.VarA-want) > 1e-9 || math.Abs(r.VarB-want) > 1e-9 {
+		t.Errorf("CompareRuns(high, low) variances = %v, %v, want %v", r.VarA, r.VarB, want)
+	}
+
+	if r := CompareRuns(low, high); r.Direction != -1 || r.U != 0 || r.PValue > 0.001 {
+		t.Errorf("CompareRuns(low, high) = %+v, want Direction -1, U 0, PValue < 0.001", r)
+	}
+	if r := CompareRuns(low, low); r.Direction != 0 || r.PValue < 0.9 {
+		t.Errorf("CompareRuns(low, low) = %+v, want Direction 0, PValue near 1", r)
+	}
+	// Overlapping distributions are not significantly different.
+	if r := CompareRuns(runs(100, 110, 120, 130), runs(105, 115, 125, 135)); r.PValue < 0.05 {
+		t.Errorf("CompareRuns of overlapping runs = %+v, want PValue >= 0.05", r)
+	}
+	if r := CompareRuns(nil, [][]float64{{}, {1}}); !math.IsNaN(r.PValue) || r.MeanB != 1 {
+		t.Errorf("CompareRuns(nil, ...) = %+v, want NaN PValue and MeanB 1", r)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"github.com/gmlewis/gep/functions"
+	"github.com/gmlewis/gep/genome"
+)
+
+// Degenerate configures the replacement of degenerate genomes by Train: each
+// generation, after reproduction, genomes whose output is constant over a
+// dataset (see genome.IsConstant), and optionally duplicate genomes, are
+// replaced by new, random genomes of the same structure. This keeps wasted
+// population slots to a minimum and maintains diversity. Frozen genomes are
+// never replaced, although later duplicates of them are.
+type Degenerate struct {
+	// Dataset is used to detect genomes with constant output. If nil, only
+	// duplicates are replaced.
+	Dataset *genome.Dataset
+	// Duplicates also replaces each genome that is a duplicate (by
+	// CanonicalHash) of an earlier genome in the population.
+	Duplicates bool
+	// Rate is the probability that each degenerate genome is replaced.
+	// If zero, every degenerate genome is replaced.
+	Rate float64
+}
+
+// degenerate reports whether gn is degenerate, given the canonical hashes of
+// the genomes preceding it, which it updates.
+func (d *Degenerate) degenerate(gn *genome.Genome, seen map[uint64]bool) bool {
+	if d.Duplicates {
+		h := gn.CanonicalHash()
+		if seen[h] {
+			return true
+		}
+		seen[h] = true
+	}
+	return d.Dataset != nil && gn.IsConstant(d.Dataset)
+}
+
+// replaceDegenerate replaces the degenerate genomes of the generation as
+// configured by d (drawing its randomness from rng), and returns the number replaced.
+func (g *Generation) replaceDegenerate(rng functions.RNG, d *Degenerate) int {
+	n := 0
+	seen := map[uint64]bool{}
+	for i, gn := range g.Genomes {
+		if !d.degenerate(gn, seen) || gn.Frozen || (d.Rate > 0 && rng.Float64() >= d.Rate) {
+			continue
+		}
+		if r := randomLike(rng, gn); r != nil {
+			g.Genomes[i] = r
+			n++
+		}
+	}
+	return n
+}
+
+// randomLike returns a new, random genome with the same structure as gn
+// (including its homeotic gene and the function map it is bound to),
+// or nil if the structure of its genes is unknown.
+func randomLike(rng functions.RNG, gn *genome.Genome) *genome.Genome {
+	r := gn.Dup()
+	r.Score = 0
+	for i, v := range gn.Genes {
+		if r.Genes[i] = v.RandomLikeWith(rng); r.Genes[i] == nil {
+			return nil
+		}
+	}
+	if gn.Homeotic != nil {
+		if r.Homeotic = gn.Homeotic.RandomLikeWith(rng); r.Homeotic == nil {
+			return nil
+		}
+	}
+	return r
+}
@@ -0,0 +1,44 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+
+	"github.com/gmlewis/gep/genome"
+)
+
+// EvalEnsembleMath evaluates each of the genomes with the inputs in and
+// combines their outputs with combine (or with Mean, if combine is nil),
+// turning several genomes (such as the best of a population) into a single
+// predictor. Genomes producing NaN or infinite outputs are skipped, and if
+// no genome produces a finite output, the result is NaN.
+func EvalEnsembleMath(genomes []*genome.Genome, in []float64, combine func([]float64) float64) float64 {
+	if combine == nil {
+		combine = Mean
+	}
+	outputs := make([]float64, 0, len(genomes))
+	for _, g := range genomes {
+		if v := g.EvalMath(in); !math.IsNaN(v) && !math.IsInf(v, 0) {
+			outputs = append(outputs, v)
+		}
+	}
+	if len(outputs) == 0 {
+		return math.NaN()
+	}
+	return combine(outputs)
+}
+
+// Mean returns the arithmetic mean of the values, or NaN if there are none.
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
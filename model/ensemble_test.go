@@ -0,0 +1,42 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/gmlewis/gep/genome"
+)
+
+func TestEvalEnsembleMath(t *testing.T) {
+	build := func(head string) *genome.Genome {
+		g, err := genome.Build().Gene(head).Genome()
+		if err != nil {
+			t.Fatalf("Build(%q) = %v", head, err)
+		}
+		return g
+	}
+	a, b := build("+ d0 d1"), build("* d0 d1")
+	in := []float64{3, 5}
+	if got, want := EvalEnsembleMath([]*genome.Genome{a, b}, in, nil), (a.EvalMath(in)+b.EvalMath(in))/2; got != want {
+		t.Errorf("mean ensemble = %v, want %v", got, want)
+	}
+	nan := build("Sqrt Neg d0")
+	if got, want := EvalEnsembleMath([]*genome.Genome{a, nan, b}, in, nil), 11.5; got != want {
+		t.Errorf("ensemble with a NaN genome = %v, want %v", got, want)
+	}
+	median := func(v []float64) float64 {
+		sort.Float64s(v)
+		return v[len(v)/2]
+	}
+	if got, want := EvalEnsembleMath([]*genome.Genome{a, b, build("- d1 d0")}, in, median), 8.0; got != want {
+		t.Errorf("median ensemble = %v, want %v", got, want)
+	}
+	if got := EvalEnsembleMath([]*genome.Genome{nan}, in, nil); !math.IsNaN(got) {
+		t.Errorf("ensemble of only NaN genomes = %v, want NaN", got)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+
+	"github.com/gmlewis/gep/functions"
+	"github.com/gmlewis/gep/genome"
+)
+
+// LexicaseSelection selects a parent from pop by lexicase selection, given the
+// errors of each genome on each training case (caseErrors[i][j] is the error
+// of pop[i] on case j, lower being better; see genome.CaseErrors). The cases
+// are shuffled, and the pool of candidates (initially all of pop) is filtered
+// by each case in turn, keeping only those with the lowest error on it, until
+// one candidate remains or the cases run out, in which case one of those
+// remaining is chosen at random. NaN errors are ranked last (as infinite).
+//
+// Since a genome that excels on a few cases may be selected even though its
+// overall score is poor, lexicase selection preserves specialists and so
+// maintains the diversity of the population on multi-modal problems.
+// It returns nil if pop is empty.
+func LexicaseSelection(pop []*genome.Genome, caseErrors [][]float64) *genome.Genome {
+	return LexicaseSelectionWith(functions.DefaultRNG, pop, caseErrors)
+}
+
+// LexicaseSelectionWith is like LexicaseSelection, but draws its randomness
+// from rng.
+func LexicaseSelectionWith(rng functions.RNG, pop []*genome.Genome, caseErrors [][]float64) *genome.Genome {
+	if len(pop) == 0 {
+		return nil
+	}
+	if len(caseErrors) != len(pop) {
+		functions.Log.Printf("model.LexicaseSelection: got %v rows of case errors for %v genomes", len(caseErrors), len(pop))
+		return nil
+	}
+	numCases := len(caseErrors[0])
+	for _, v := range caseErrors[1:] {
+		if len(v) < numCases {
+			numCases = len(v)
+		}
+	}
+	cases := make([]int, numCases)
+	for i := range cases {
+		cases[i] = i
+	}
+	pool := make([]int, len(pop))
+	for i := range pool {
+		pool[i] = i
+	}
+	for n := 0; n < numCases && len(pool) > 1; n++ {
+		// Draw the next case of a Fisher-Yates shuffle.
+		j := n + rng.Intn(numCases-n)
+		cases[n], cases[j] = cases[j], cases[n]
+		c := cases[n]
+		best := math.Inf(1)
+		for _, i := range pool {
+			best = math.Min(best, caseError(caseErrors[i][c]))
+		}
+		survivors := pool[:0]
+		for _, i := range pool {
+			if caseError(caseErrors[i][c]) == best {
+				survivors = append(survivors, i)
+			}
+		}
+		pool = survivors
+	}
+	return pop[pool[rng.Intn(len(pool))]]
+}
+
+// caseError ranks the NaN error e with the infinite errors, which are the worst.
+func caseError(e float64) float64 {
+	if math.IsNaN(e) {
+		return math.Inf(1)
+	}
+	return e
+}
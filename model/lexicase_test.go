@@ -0,0 +1,55 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmlewis/gep/functions"
+	"github.com/gmlewis/gep/gene"
+	"github.com/gmlewis/gep/genome"
+)
+
+func TestLexicaseSelection(t *testing.T) {
+	pop := make([]*genome.Genome, 5)
+	for i := range pop {
+		pop[i] = genome.New([]*gene.Gene{gene.New("d0")}, "+")
+	}
+	// Genomes #0-#2 are specialists, each exact on one case. Genome #3 has
+	// the lowest total error but is best on no case, so it is never selected,
+	// nor is #4, whose errors are all NaN. Each case is won by a single
+	// specialist, so the first case drawn decides the selection.
+	caseErrors := [][]float64{
+		{0, 5, 5},
+		{4, 0, 9},
+		{4, 9, 0},
+		{1, 1, 1},
+		{math.NaN(), math.NaN(), math.NaN()},
+	}
+	counts := map[*genome.Genome]int{}
+	const trials = 3000
+	rng := functions.NewRNG(1)
+	for i := 0; i < trials; i++ {
+		counts[LexicaseSelectionWith(rng, pop, caseErrors)]++
+	}
+	for i, want := range []float64{1.0 / 3, 1.0 / 3, 1.0 / 3, 0, 0} {
+		if got := float64(counts[pop[i]]) / trials; math.Abs(got-want) > 0.05 {
+			t.Errorf("genome #%v selected with frequency %v, want %v", i, got, want)
+		}
+	}
+
+	// A genome best on every case is always selected.
+	caseErrors[3] = []float64{0, 0, 0}
+	for i := 0; i < 10; i++ {
+		if got := LexicaseSelectionWith(rng, pop, caseErrors); got != pop[3] {
+			t.Fatalf("LexicaseSelection = genome %p, want the dominant genome #3", got)
+		}
+	}
+
+	if got := LexicaseSelection(nil, nil); got != nil {
+		t.Errorf("LexicaseSelection of empty population = %p, want nil", got)
+	}
+}
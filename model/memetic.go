@@ -0,0 +1,37 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"github.com/gmlewis/gep/functions"
+	"github.com/gmlewis/gep/genome"
+)
+
+// defaultMemeticIters is the local search budget of TrainConfig.Memetic if
+// TrainConfig.MemeticIters is zero.
+const defaultMemeticIters = 10
+
+// localSearch replaces each of the top elites (at least one) genomes of the
+// scored population with the result of hill climbing from it with sf, using
+// iters mutations (or defaultMemeticIters if zero), and returns the best genome
+// of the population afterwards. Frozen genomes are left unrefined.
+func (g *Generation) localSearch(rng functions.RNG, sf genome.ScoringFunc, elites, iters int) *genome.Genome {
+	if elites < 1 {
+		elites = 1
+	}
+	if iters <= 0 {
+		iters = defaultMemeticIters
+	}
+	for n, i := range genome.Ranking(g.Genomes) {
+		if n >= elites {
+			break
+		}
+		if g.Genomes[i].Frozen {
+			continue
+		}
+		g.Genomes[i] = genome.HillClimbWith(rng, g.Genomes[i], sf, iters)
+	}
+	return genome.Best(g.Genomes)
+}
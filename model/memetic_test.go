@@ -0,0 +1,54 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"runtime"
+	"testing"
+
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+	"github.com/gmlewis/gep/genome"
+)
+
+func TestTrainMemetic(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	// The target is x*x*x + x.
+	sf := func(g *genome.Genome) float64 {
+		err := 0.0
+		for x := -2.0; x <= 2; x += 0.5 {
+			err += math.Abs(g.EvalMath([]float64{x}) - (x*x*x + x))
+		}
+		return 1000 / (1 + err)
+	}
+	solved := func(g *genome.Genome) bool { return g.Score >= 1000 }
+	e := New(funcs, mn.Math, 20, 6, 1, 1, 0, "+", sf)
+	seeds := make([]int64, 20)
+	for i := range seeds {
+		seeds[i] = int64(i + 1)
+	}
+	const generations = 100
+	// Generations to solution, counting failures as taking all of them.
+	mean := func(r RunManyReport) float64 {
+		total := 0
+		for _, n := range r.Generations {
+			if n < 0 {
+				n = generations
+			}
+			total += n
+		}
+		return float64(total) / float64(len(r.Generations))
+	}
+	plain := RunMany(RunManyConfig{Template: e, Train: TrainConfig{Generations: generations, MutateRate: 0.05}, Parallelism: runtime.NumCPU()}, seeds, solved)
+	memetic := RunMany(RunManyConfig{Template: e, Train: TrainConfig{Generations: generations, MutateRate: 0.05, Memetic: true, MemeticElites: 5, MemeticIters: 50}, Parallelism: runtime.NumCPU()}, seeds, solved)
+	if mean(memetic) >= mean(plain) {
+		t.Errorf("memetic mean generations to solution = %v, want fewer than %v without", mean(memetic), mean(plain))
+	}
+}
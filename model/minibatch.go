@@ -0,0 +1,57 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"github.com/gmlewis/gep/functions"
+	"github.com/gmlewis/gep/genome"
+)
+
+// MiniBatch configures mini-batch (stochastic) scoring for Train: rather than
+// scoring the genomes with the ScoringFunc of the generation, each generation
+// is scored on a random batch of the rows of a dataset. Smaller batches make
+// each generation faster but its scores noisier. The best genome of each
+// generation is rescored on the full dataset before Train compares it with the
+// best genome ever seen, so BestEver always holds a full-dataset score.
+type MiniBatch struct {
+	// Dataset holds all of the fitness cases.
+	Dataset *genome.Dataset
+	// Score makes the scoring function for a batch of rows, such as genome.RMSE.
+	Score func(ds *genome.Dataset) genome.ScoringFunc
+	// Size is the number of rows in each batch, chosen at random without
+	// replacement. If Size is zero or at least the number of rows of Dataset,
+	// every row is used.
+	Size int
+	// Every is the number of generations that use each batch before a new one
+	// is chosen. If zero, a new batch is chosen every generation.
+	Every int
+}
+
+// resample reports whether a new batch is needed for generation i.
+func (m *MiniBatch) resample(i int) bool {
+	return m.Every <= 1 || i%m.Every == 0
+}
+
+// batch returns a random batch (drawn from rng) of the rows of m.Dataset.
+func (m *MiniBatch) batch(rng functions.RNG) *genome.Dataset {
+	ds := m.Dataset
+	if m.Size <= 0 || m.Size >= ds.Len() {
+		return ds
+	}
+	r := &genome.Dataset{Config: ds.Config}
+	for _, i := range functions.Perm(rng, ds.Len())[:m.Size] {
+		r.Inputs = append(r.Inputs, ds.Inputs[i])
+		r.Targets = append(r.Targets, ds.Targets[i])
+		if ds.Weights != nil {
+			r.Weights = append(r.Weights, ds.Weights[i])
+		}
+	}
+	return r
+}
+
+// scoringFunc returns the scoring function for a new random batch.
+func (m *MiniBatch) scoringFunc(rng functions.RNG) genome.ScoringFunc {
+	return m.Score(m.batch(rng))
+}
@@ -7,8 +7,6 @@ package model
 
 import (
 	"fmt"
-	"log"
-	"math/rand"
 	"runtime"
 
 	"github.com/gmlewis/gep/functions"
@@ -21,6 +19,118 @@ type Generation struct {
 	Genomes     []*genome.Genome
 	Funcs       []gene.FuncWeight
 	ScoringFunc genome.ScoringFunc
+
+	// BestEver is a copy of the best genome seen by Train across all generations.
+	BestEver *genome.Genome
+
+	// History holds a copy of the best genome of each generation run by the
+	// last call of Train, in order, if TrainConfig.KeepHistory was set.
+	History []*genome.Genome
+}
+
+// TrainConfig contains the settings used by Train.
+type TrainConfig struct {
+	// Generations is the maximum number of generations to run.
+	Generations int
+	// DisableElitism stops the best genome of each generation from being
+	// copied unchanged into the next generation.
+	DisableElitism bool
+	// MutateRate is the probability that each symbol of each genome is mutated
+	// in a generation. If zero, the mutation scheme of Evolve is used.
+	MutateRate float64
+	// CacheEvaluations scores each distinct genome (by CanonicalHash) only
+	// once per generation, which avoids rescoring the duplicates made by
+	// replication. The cache is cleared each generation.
+	CacheEvaluations bool
+	// MiniBatch, if non-nil, scores each generation on a random batch of the
+	// rows of a dataset instead of with the ScoringFunc of the generation.
+	MiniBatch *MiniBatch
+	// ReplaceDegenerate, if non-nil, replaces degenerate genomes (such as
+	// those with constant output) with new, random ones each generation.
+	ReplaceDegenerate *Degenerate
+	// FixedLink asserts that the linking functions of the genomes are never
+	// changed during training: after the operators of each generation have
+	// run, every genome must still use one of the linking functions found in
+	// the initial population, or Train stops with a fatal error.
+	FixedLink bool
+	// LinkMutateRate is the probability that the linking function of each
+	// genome is mutated (see genome.MutateLink) in a generation, making the
+	// linking functions evolvable. It requires LinkFuncs and must not be
+	// combined with FixedLink; Train rejects either misconfiguration with a
+	// fatal error before training starts.
+	LinkMutateRate float64
+	// LinkFuncs is the map of functions from which LinkMutateRate chooses
+	// the linking functions. It should be the function map of the population
+	// (such as mn.Math or bn.Bool), so that the chosen links can be evaluated.
+	LinkFuncs functions.FuncMap
+	// Restart, if non-nil, warm restarts the population whenever training
+	// stagnates (see WarmRestart).
+	Restart *Restart
+	// RNG, if non-nil, is the source of randomness for all of the stochastic
+	// operations of training, whose results are then reproducible (given
+	// deterministic scoring) by an RNG with the same seed.
+	// If nil, functions.DefaultRNG is used.
+	RNG functions.RNG
+	// AdaptiveRate, if non-nil, adapts the mutation rate of each generation
+	// to the progress of training, in place of MutateRate.
+	AdaptiveRate *AdaptiveRate
+	// OnGeneration, if non-nil, is called after each generation is scored,
+	// to report the progress of training.
+	OnGeneration func(p Progress)
+	// BestEvents, if non-nil, receives a copy of each new best genome ever
+	// seen as soon as it is found, for live monitoring: each genome is
+	// observed as soon as it is scored, mid-generation, rather than when the
+	// generation is complete. (With MiniBatch, whose batch scores are not
+	// comparable, only the best genome of each generation is observed,
+	// once it is rescored on the full dataset.) Sends never block:
+	// if the channel is not ready, the event is dropped. Train never closes
+	// the channel.
+	BestEvents chan<- *genome.Genome
+	// Operators, if non-empty, are applied in turn to each genome after
+	// replication, in place of the usual mutation, and the effectiveness of
+	// each is reported to OnGeneration.
+	Operators []Operator
+	// Memetic applies a local search (see genome.HillClimb) to the top
+	// MemeticElites genomes of each generation once it is scored, before the
+	// operators are applied, combining the global search of GEP with local
+	// refinement.
+	Memetic bool
+	// MemeticElites is the number of genomes refined by Memetic each
+	// generation. If zero, only the best genome is refined.
+	MemeticElites int
+	// MemeticIters is the local search budget of each genome refined by
+	// Memetic: the number of mutations tried. If zero, it is 10.
+	MemeticIters int
+	// ParsimonyTieBreak applies lexicographic parsimony pressure to the
+	// selection of survivors: each genome selected competes with a second,
+	// random genome, and when the two tie on score the one with the smaller
+	// expression (see genome.Better) survives. This controls bloat without distorting the
+	// score or collapsing the tied genomes into clones.
+	// (The best genome of each generation, and so the one returned, is always
+	// chosen by genome.Better, which already prefers the shortest among ties.)
+	ParsimonyTieBreak bool
+	// KeepHistory records a copy of the best genome of each generation in
+	// the History of the generation. It is off by default, since the copies
+	// consume memory on long runs.
+	KeepHistory bool
+}
+
+// Progress reports the state of Train after a generation is scored.
+// The genomes must not be altered.
+type Progress struct {
+	// Generation is the index of the generation, from 0.
+	Generation int
+	// Best is the best genome of the generation.
+	Best *genome.Genome
+	// BestEver is the best genome seen so far by Train.
+	BestEver *genome.Genome
+	// MutateRate is the probability with which each symbol of each genome
+	// is about to be mutated (which, with TrainConfig.AdaptiveRate, is the
+	// current adaptive rate), or 0 if the mutation scheme of Evolve is used.
+	MutateRate float64
+	// Operators holds the statistics of each of TrainConfig.Operators, in
+	// order, accumulated over the reproduction of all previous generations.
+	Operators []OperatorStats
 }
 
 // New creates a new random generation of the model.
@@ -34,6 +144,39 @@ type Generation struct {
 // linkFunc is the linking function used to combine the genes within a genome.
 // sf is the scoring (or fitness) function.
 func New(fs []gene.FuncWeight, fm functions.FuncMap, numGenomes, headSize, numGenesPerGenome, numTerminals, numConstants int, linkFunc string, sf genome.ScoringFunc) *Generation {
+	return NewWith(functions.DefaultRNG, fs, fm, numGenomes, headSize, numGenesPerGenome, numTerminals, numConstants, linkFunc, sf)
+}
+
+// NewWith is like New, but draws its randomness from rng.
+func NewWith(rng functions.RNG, fs []gene.FuncWeight, fm functions.FuncMap, numGenomes, headSize, numGenesPerGenome, numTerminals, numConstants int, linkFunc string, sf genome.ScoringFunc) *Generation {
+	return newGeneration(rng, fs, fm, numGenomes, headSize, numGenesPerGenome, numTerminals, numConstants, linkFunc, sf, func(int) *gene.Options { return nil })
+}
+
+// NewRamped creates a new random generation of the model like New, but uses
+// ramped initialization for greater initial diversity: the probability that
+// each head position holds a function (rather than a terminal) is ramped
+// linearly across the population from minFuncDensity up to 1, producing a
+// spread of expression sizes from tiny to as large as the head allows.
+// The arguments are the same as for New.
+func NewRamped(fs []gene.FuncWeight, fm functions.FuncMap, numGenomes, headSize, numGenesPerGenome, numTerminals, numConstants int, linkFunc string, sf genome.ScoringFunc) *Generation {
+	return NewRampedWith(functions.DefaultRNG, fs, fm, numGenomes, headSize, numGenesPerGenome, numTerminals, numConstants, linkFunc, sf)
+}
+
+// NewRampedWith is like NewRamped, but draws its randomness from rng.
+func NewRampedWith(rng functions.RNG, fs []gene.FuncWeight, fm functions.FuncMap, numGenomes, headSize, numGenesPerGenome, numTerminals, numConstants int, linkFunc string, sf genome.ScoringFunc) *Generation {
+	return newGeneration(rng, fs, fm, numGenomes, headSize, numGenesPerGenome, numTerminals, numConstants, linkFunc, sf, func(i int) *gene.Options {
+		ramp := 1.0
+		if numGenomes > 1 {
+			ramp = float64(i) / float64(numGenomes-1)
+		}
+		return &gene.Options{FuncDensity: minFuncDensity + (1-minFuncDensity)*ramp}
+	})
+}
+
+// minFuncDensity is the lowest function density used by NewRamped.
+const minFuncDensity = 0.1
+
+func newGeneration(rng functions.RNG, fs []gene.FuncWeight, fm functions.FuncMap, numGenomes, headSize, numGenesPerGenome, numTerminals, numConstants int, linkFunc string, sf genome.ScoringFunc, opts func(i int) *gene.Options) *Generation {
 	r := &Generation{
 		Genomes:     make([]*genome.Genome, numGenomes, numGenomes),
 		Funcs:       fs,
@@ -42,9 +185,10 @@ func New(fs []gene.FuncWeight, fm functions.FuncMap, numGenomes, headSize, numGe
 	n := maxArity(fs, fm)
 	tailSize := headSize*(n-1) + 1
 	for i := range r.Genomes {
+		o := opts(i)
 		genes := make([]*gene.Gene, numGenesPerGenome, numGenesPerGenome)
 		for j := range genes {
-			genes[j] = gene.RandomNew(headSize, tailSize, numTerminals, numConstants, fs)
+			genes[j] = gene.RandomNewWith(rng, headSize, tailSize, numTerminals, numConstants, fs, o)
 		}
 		r.Genomes[i] = genome.New(genes, linkFunc)
 	}
@@ -63,9 +207,9 @@ func (g *Generation) Evolve(iterations int) *genome.Genome {
 			return bestGenome
 		}
 		// fmt.Printf("Best genome (score %v): %v\n", bestGenome.Score, *bestGenome)
-		saveCopy := bestGenome.Dup()
-		g.replication() // Section 3.3.1, book page 75
-		g.mutation()    // Section 3.3.2, book page 77
+		saveCopy := survivor(bestGenome)
+		g.replication(functions.DefaultRNG) // Section 3.3.1, book page 75
+		g.mutation(functions.DefaultRNG)    // Section 3.3.2, book page 77
 		// g.isTransposition()
 		// g.risTransposition()
 		// g.geneTransposition()
@@ -79,7 +223,230 @@ func (g *Generation) Evolve(iterations int) *genome.Genome {
 	return g.getBest()
 }
 
-func (g *Generation) replication() {
+// Train runs the GEP algorithm as configured by cfg for the given number of
+// generations, or until a score of 1000 (or more) is reached.
+// Unlike Evolve, it keeps track of the best genome ever seen in g.BestEver
+// and returns it, so the result is never worse than any genome evaluated
+// during training, regardless of the state of the final population.
+// With mini-batch scoring, the best genome of each generation is rescored on
+// the full dataset before it is compared with g.BestEver, so that a favorable
+// batch can neither install nor stop training with a score that the full
+// dataset contradicts.
+func (g *Generation) Train(cfg TrainConfig) *genome.Genome {
+	checkConfig(cfg)
+	var cache *genome.EvalCache
+	if cfg.CacheEvaluations {
+		cache = genome.NewEvalCache()
+	}
+	var events *bestEvents
+	if cfg.BestEvents != nil {
+		events = newBestEvents(cfg.BestEvents, g.BestEver, cfg.MiniBatch == nil)
+	}
+	wrap := func(sf genome.ScoringFunc) genome.ScoringFunc {
+		sf = events.wrap(sf)
+		if cache != nil {
+			return cache.Wrap(sf)
+		}
+		return sf
+	}
+	sf := wrap(g.ScoringFunc)
+	var full genome.ScoringFunc
+	if cfg.MiniBatch != nil {
+		full = cfg.MiniBatch.Score(cfg.MiniBatch.Dataset)
+	}
+	rng := cfg.RNG
+	if rng == nil {
+		rng = functions.DefaultRNG
+	}
+	links := map[string]bool{}
+	for _, v := range g.Genomes {
+		links[v.LinkFunc] = true
+	}
+	g.History = nil
+	stagnant := 0
+	rate := cfg.MutateRate
+	var adaptive *adaptiveRate
+	if cfg.AdaptiveRate != nil {
+		adaptive = newAdaptiveRate(cfg.AdaptiveRate, cfg.MutateRate)
+		rate = adaptive.rate
+	}
+	var stats []OperatorStats
+	for _, op := range cfg.Operators {
+		stats = append(stats, OperatorStats{Name: op.Name})
+	}
+	for i := 0; i < cfg.Generations; i++ {
+		if cache != nil {
+			cache.Reset()
+		}
+		if cfg.MiniBatch != nil && cfg.MiniBatch.resample(i) {
+			sf = wrap(cfg.MiniBatch.scoringFunc(rng))
+		}
+		bestGenome := g.getBestWith(sf)
+		if cfg.Memetic {
+			bestGenome = g.localSearch(rng, sf, cfg.MemeticElites, cfg.MemeticIters)
+		}
+		if cfg.KeepHistory {
+			g.History = append(g.History, bestGenome.Dup())
+		}
+		prev := g.BestEver
+		if g.updateBestEver(candidate(bestGenome, full), events) {
+			stagnant = 0
+		} else {
+			stagnant++
+		}
+		if adaptive != nil && prev != nil { // The first genome seen is not an improvement.
+			rate = adaptive.update(g.BestEver.Score > prev.Score)
+		}
+		if cfg.OnGeneration != nil {
+			p := Progress{Generation: i, Best: bestGenome, BestEver: g.BestEver, MutateRate: rate}
+			if stats != nil {
+				p.Operators = append([]OperatorStats(nil), stats...)
+			}
+			cfg.OnGeneration(p)
+		}
+		if g.BestEver.Score >= 1000.0 {
+			return g.BestEver
+		}
+		saveCopy, elites := survivor(bestGenome), 0
+		switch {
+		case cfg.Restart != nil && stagnant >= cfg.Restart.Stagnation:
+			WarmRestartWith(rng, g.Genomes, cfg.Restart.Elites, cfg.Restart.MutationRate)
+			stagnant, elites = 0, cfg.Restart.Elites
+		case len(cfg.Operators) > 0:
+			g.replicationWith(rng, cfg.ParsimonyTieBreak)
+			g.applyOperators(rng, cfg.Operators, sf, stats)
+		case rate > 0:
+			g.replicationWith(rng, cfg.ParsimonyTieBreak)
+			g.mutationRate(rng, rate)
+		default:
+			g.replicationWith(rng, cfg.ParsimonyTieBreak)
+			g.mutation(rng)
+		}
+		if cfg.LinkMutateRate > 0 {
+			g.linkMutation(rng, cfg.LinkMutateRate, cfg.LinkFuncs)
+		}
+		if cfg.ReplaceDegenerate != nil {
+			g.replaceDegenerate(rng, cfg.ReplaceDegenerate)
+		}
+		if cfg.FixedLink {
+			g.checkLinks(links, i)
+		}
+		if !cfg.DisableElitism && elites == 0 { // The elites of a warm restart include the best genome.
+			g.Genomes[0] = saveCopy
+		}
+	}
+	if cache != nil {
+		cache.Reset()
+	}
+	g.updateBestEver(candidate(g.getBestWith(sf), full), events)
+	return g.BestEver
+}
+
+// checkConfig stops with a fatal error if cfg combines settings that Train
+// cannot honor.
+func checkConfig(cfg TrainConfig) {
+	if cfg.LinkMutateRate <= 0 {
+		return
+	}
+	if cfg.FixedLink {
+		functions.Log.Fatalf("model.Train: LinkMutateRate cannot be combined with FixedLink")
+	}
+	if len(genome.LinkFuncs(cfg.LinkFuncs)) == 0 {
+		functions.Log.Fatalf("model.Train: LinkMutateRate requires LinkFuncs with at least one linking function")
+	}
+}
+
+// checkLinks stops with a fatal error if any genome of generation i uses a
+// linking function other than those in links.
+func (g *Generation) checkLinks(links map[string]bool, i int) {
+	for j, v := range g.Genomes {
+		if !links[v.LinkFunc] {
+			functions.Log.Fatalf("model.Train: generation #%v altered the linking function of genome #%v to %q", i, j, v.LinkFunc)
+		}
+	}
+}
+
+// candidate returns gn as a candidate for g.BestEver: with mini-batch scoring
+// (when full, the scoring function of the full dataset, is non-nil), a copy of
+// gn rescored by full, so that every candidate is scored on the same rows.
+func candidate(gn *genome.Genome, full genome.ScoringFunc) *genome.Genome {
+	if full == nil {
+		return gn
+	}
+	r := gn.Dup()
+	r.Score = full(r)
+	return r
+}
+
+// updateBestEver records a copy of gn as g.BestEver if it ranks ahead of it,
+// and reports whether it did so. gn is also observed by events (if non-nil).
+func (g *Generation) updateBestEver(gn *genome.Genome, events *bestEvents) bool {
+	events.observe(gn)
+	if g.BestEver != nil && !genome.Better(gn, g.BestEver) {
+		return false
+	}
+	g.BestEver = gn.Dup()
+	return true
+}
+
+// bestEvents sends each new best genome observed by Train to a channel.
+type bestEvents struct {
+	best genome.BestTracker
+	ch   chan<- *genome.Genome
+	live bool // whether every scored genome is observed
+}
+
+// newBestEvents returns a bestEvents sending to ch the genomes that rank ahead
+// of bestEver (if non-nil). If live, every genome is observed when scored.
+func newBestEvents(ch chan<- *genome.Genome, bestEver *genome.Genome, live bool) *bestEvents {
+	e := &bestEvents{ch: ch, live: live}
+	if bestEver != nil {
+		e.best.Update(bestEver)
+	}
+	return e
+}
+
+// observe sends a copy of gn to the channel (if it is ready to receive) if gn
+// ranks ahead of every genome observed before.
+func (e *bestEvents) observe(gn *genome.Genome) {
+	if e == nil || !e.best.Update(gn) {
+		return
+	}
+	select {
+	case e.ch <- gn.Dup():
+	default:
+	}
+}
+
+// wrap returns sf, altered (if live) to observe each genome once it is scored.
+func (e *bestEvents) wrap(sf genome.ScoringFunc) genome.ScoringFunc {
+	if e == nil || !e.live {
+		return sf
+	}
+	return func(gn *genome.Genome) float64 {
+		gn.Score = sf(gn)
+		e.observe(gn)
+		return gn.Score
+	}
+}
+
+func (g *Generation) replication(rng functions.RNG) {
+	g.replicationWith(rng, false)
+}
+
+// replicationWith performs the replication of the genomes by roulette wheel
+// selection. With parsimony, each genome selected competes with a second,
+// randomly drawn genome: if the two tie on score, the better of them (see
+// genome.Better, which prefers the smaller expression) is replicated
+// instead, as in the lexicographic parsimony tournaments of Luke and Panait.
+func (g *Generation) replicationWith(rng functions.RNG, parsimony bool) {
+	var rank []int // rank[i] is the position of genome i in genome.Ranking.
+	if parsimony {
+		rank = make([]int, len(g.Genomes))
+		for pos, i := range genome.Ranking(g.Genomes) {
+			rank[i] = pos
+		}
+	}
 	// roulette wheel selection - see www.youtube.com/watch?v=aHLslaWO-AQ
 	maxWeight := 0.0
 	for _, v := range g.Genomes {
@@ -88,50 +455,98 @@ func (g *Generation) replication() {
 		}
 	}
 	result := make([]*genome.Genome, 0, len(g.Genomes))
-	index := rand.Intn(len(g.Genomes))
+	index := rng.Intn(len(g.Genomes))
 	beta := 0.0
 	for i := 0; i < len(g.Genomes); i++ {
-		beta += rand.Float64() * 2.0 * maxWeight
+		beta += rng.Float64() * 2.0 * maxWeight
 		for beta > g.Genomes[index].Score {
 			beta -= g.Genomes[index].Score
 			index = (index + 1) % len(g.Genomes)
 		}
-		result = append(result, g.Genomes[index].Dup())
+		selected := g.Genomes[index]
+		if parsimony {
+			if rival := rng.Intn(len(g.Genomes)); g.Genomes[rival].Score == selected.Score && rank[rival] < rank[index] {
+				selected = g.Genomes[rival]
+			}
+		}
+		result = append(result, survivor(selected))
 	}
 	g.Genomes = result
 }
 
-func (g *Generation) mutation() {
+// survivor returns a duplicate of gn for the next generation, which (unlike
+// genome.Genome.Dup) stays frozen if gn is, so that frozen elites are never
+// altered by training.
+func survivor(gn *genome.Genome) *genome.Genome {
+	r := gn.Dup()
+	r.Frozen = gn.Frozen
+	return r
+}
+
+func (g *Generation) mutation(rng functions.RNG) {
 	// Determine the total number of genomes to mutate
-	numGenomes := 1 + rand.Intn(len(g.Genomes)-1)
+	numGenomes := 1 + rng.Intn(len(g.Genomes)-1)
 	for i := 0; i < numGenomes; i++ {
 		// Pick a random genome
-		genomeNum := rand.Intn(len(g.Genomes))
+		genomeNum := rng.Intn(len(g.Genomes))
 		gen := g.Genomes[genomeNum]
 		// Determine the total number of mutations to perform within the genome
-		numMutations := 1 + rand.Intn(2)
+		numMutations := 1 + rng.Intn(2)
 		// fmt.Printf("\nMutating genome #%v %v times, before:\n%v\n", genomeNum, numMutations, genome)
-		gen.Mutate(numMutations)
+		gen.MutateWith(rng, numMutations)
 		// fmt.Printf("after:\n%v\n", genome)
 	}
 }
 
+// mutationRate mutates each symbol of each genome with probability rate.
+func (g *Generation) mutationRate(rng functions.RNG, rate float64) {
+	for _, gn := range g.Genomes {
+		if numMutations := mutations(rng, gn, rate); numMutations > 0 {
+			gn.MutateWith(rng, numMutations)
+		}
+	}
+}
+
+// linkMutation mutates the linking function of each genome with probability
+// rate, choosing from fm.
+func (g *Generation) linkMutation(rng functions.RNG, rate float64, fm functions.FuncMap) {
+	for _, gn := range g.Genomes {
+		if rng.Float64() < rate {
+			gn.MutateLinkWith(rng, fm)
+		}
+	}
+}
+
+// mutations returns the number of mutations to make to gn so that each of its
+// symbols is mutated with probability rate, drawing its randomness from rng.
+func mutations(rng functions.RNG, gn *genome.Genome, rate float64) int {
+	numMutations := 0
+	for _, v := range gn.Genes {
+		for range v.Symbols {
+			if rng.Float64() < rate {
+				numMutations++
+			}
+		}
+	}
+	return numMutations
+}
+
 // getBest evaluates all genomes and returns a pointer to the best one.
 func (g *Generation) getBest() *genome.Genome {
-	bestScore := 0.0
-	bestGenome := g.Genomes[0]
+	return g.getBestWith(g.ScoringFunc)
+}
+
+// getBestWith is like getBest, but evaluates the genomes with sf.
+// The best genome is chosen by genome.Better, so ties are broken reproducibly.
+func (g *Generation) getBestWith(sf genome.ScoringFunc) *genome.Genome {
 	c := make(chan *genome.Genome)
 	for i := 0; i < len(g.Genomes); i++ { // Evaluate genomes concurrently
-		go g.Genomes[i].Evaluate(g.ScoringFunc, c)
+		go g.Genomes[i].Evaluate(sf, c)
 	}
-	for i := 0; i < len(g.Genomes); i++ { // Collect and return the highest scoring Genome
-		gn := <-c
-		if gn.Score > bestScore {
-			bestGenome = gn
-			bestScore = gn.Score
-		}
+	for i := 0; i < len(g.Genomes); i++ { // Wait for every Genome to be scored
+		<-c
 	}
-	return bestGenome
+	return genome.Best(g.Genomes)
 }
 
 // maxArity determines the maximum number of input terminals for the given set of symbols.
@@ -143,7 +558,7 @@ func maxArity(fs []gene.FuncWeight, fm functions.FuncMap) int {
 				r = fn.Terminals()
 			}
 		} else {
-			log.Printf("unable to find symbol %v in function map", f.Symbol)
+			functions.Log.Printf("unable to find symbol %v in function map", f.Symbol)
 		}
 	}
 	return r
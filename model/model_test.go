@@ -5,10 +5,18 @@
 package model
 
 import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/gmlewis/gep/functions"
 	mn "github.com/gmlewis/gep/functions/math_nodes"
 	"github.com/gmlewis/gep/gene"
+	"github.com/gmlewis/gep/genome"
 )
 
 func TestMaxArity(t *testing.T) {
@@ -30,6 +38,641 @@ func TestMaxArity(t *testing.T) {
 	}
 }
 
+func TestTrainBestEver(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	var mu sync.Mutex
+	maxSeen := 0.0
+	validateFunc := func(g *genome.Genome) float64 {
+		result := 0.0
+		for x := 1.0; x <= 5; x++ {
+			e := math.Abs(g.EvalMath([]float64{x}) - (x*x*x + x))
+			result += 1000.0 / (1.0 + e)
+		}
+		result /= 5
+		mu.Lock()
+		if result > maxSeen {
+			maxSeen = result
+		}
+		mu.Unlock()
+		return result
+	}
+	e := New(funcs, mn.Math, 30, 8, 2, 1, 0, "+", validateFunc)
+	best := e.Train(TrainConfig{Generations: 20, DisableElitism: true, MutateRate: 0.5})
+	if best != e.BestEver {
+		t.Errorf("Train returned %p, want BestEver %p", best, e.BestEver)
+	}
+	if best.Score < maxSeen {
+		t.Errorf("Train best-ever score = %v, want >= %v (best seen)", best.Score, maxSeen)
+	}
+	if got := validateFunc(best); got != best.Score {
+		t.Errorf("BestEver was altered: rescored %v, stored %v", got, best.Score)
+	}
+}
+
+func TestTrainCacheEvaluations(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	var mu sync.Mutex
+	calls := 0
+	sf := func(g *genome.Genome) float64 {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return 1
+	}
+	e := New(funcs, mn.Math, 50, 8, 2, 1, 0, "+", sf)
+	for i := range e.Genomes {
+		e.Genomes[i] = e.Genomes[0].Dup()
+	}
+	// Each generation scores each of its distinct genomes exactly once.
+	prev := 0
+	var perGeneration []int
+	onGeneration := func(p Progress) {
+		distinct := map[uint64]bool{}
+		for _, v := range e.Genomes {
+			distinct[v.CanonicalHash()] = true
+		}
+		mu.Lock()
+		if got := calls - prev; got != len(distinct) {
+			t.Errorf("generation #%v: Train with CacheEvaluations scored %v genomes, want %v distinct", p.Generation, got, len(distinct))
+		}
+		perGeneration, prev = append(perGeneration, calls-prev), calls
+		mu.Unlock()
+	}
+	e.Train(TrainConfig{Generations: 3, CacheEvaluations: true, OnGeneration: onGeneration})
+	if len(perGeneration) != 3 || perGeneration[0] != 1 {
+		t.Fatalf("Train with CacheEvaluations scored %v per generation, want 1 for the %v identical genomes of generation #0", perGeneration, len(e.Genomes))
+	}
+	if perGeneration[1] == 1 && perGeneration[2] == 1 {
+		t.Errorf("Train with CacheEvaluations scored %v per generation, want mutation to produce distinct genomes", perGeneration)
+	}
+}
+
+func TestTrainMiniBatch(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	ds := &genome.Dataset{}
+	for i := 0; i < 100; i++ {
+		ds.Inputs = append(ds.Inputs, []float64{float64(i)})
+		ds.Targets = append(ds.Targets, math.Sin(float64(i*i))) // Not exactly solvable, so Train runs every generation.
+	}
+	var mu sync.Mutex
+	var batches []map[float64]bool // rows touched by the scoring function of each batch
+	score := func(batch *genome.Dataset) genome.ScoringFunc {
+		if batch == ds { // The final rescoring of the best genome.
+			return genome.RMSE(ds)
+		}
+		touched := map[float64]bool{}
+		batches = append(batches, touched)
+		return func(g *genome.Genome) float64 {
+			for _, in := range batch.Inputs {
+				mu.Lock()
+				touched[in[0]] = true
+				mu.Unlock()
+			}
+			return genome.RMSE(batch)(g)
+		}
+	}
+	e := New(funcs, mn.Math, 20, 8, 2, 1, 0, "+", nil)
+	e.Train(TrainConfig{Generations: 6, MiniBatch: &MiniBatch{Dataset: ds, Score: score, Size: 10, Every: 2}})
+	if len(batches) != 3 {
+		t.Fatalf("Train with 6 generations chose %v batches, want 3 (one every 2 generations)", len(batches))
+	}
+	all := map[float64]bool{}
+	for i, touched := range batches {
+		if len(touched) != 10 {
+			t.Errorf("batch #%v touched %v rows, want 10", i, len(touched))
+		}
+		for row := range touched {
+			all[row] = true
+		}
+	}
+	if len(all) <= 10 {
+		t.Errorf("batches touched only %v distinct rows in total, want the batches resampled", len(all))
+	}
+}
+
+func TestTrainMiniBatchRescore(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	ds := &genome.Dataset{}
+	for i := 0; i < 100; i++ {
+		ds.Inputs = append(ds.Inputs, []float64{float64(i)})
+		ds.Targets = append(ds.Targets, float64(i))
+	}
+	// The score is the number of rows scored, which tells batches from the full dataset.
+	score := func(batch *genome.Dataset) genome.ScoringFunc {
+		return func(g *genome.Genome) float64 { return float64(batch.Len()) }
+	}
+	e := New(funcs, mn.Math, 20, 8, 2, 1, 0, "+", nil)
+	best := e.Train(TrainConfig{Generations: 3, MiniBatch: &MiniBatch{Dataset: ds, Score: score, Size: 10}})
+	if best.Score != 100 {
+		t.Errorf("Train with mini-batches returned score %v, want full-dataset score 100", best.Score)
+	}
+	for i, v := range e.Genomes {
+		if v.Score != 10 {
+			t.Errorf("genome #%v score = %v, want mini-batch score 10", i, v.Score)
+		}
+	}
+
+	// A perfect batch score must neither stick nor stop training when the
+	// full dataset disagrees.
+	lucky := func(batch *genome.Dataset) genome.ScoringFunc {
+		if batch == ds {
+			return func(g *genome.Genome) float64 { return 5 }
+		}
+		return func(g *genome.Genome) float64 { return 1000 }
+	}
+	generations := 0
+	e = New(funcs, mn.Math, 20, 8, 2, 1, 0, "+", nil)
+	best = e.Train(TrainConfig{
+		Generations: 3,
+		MiniBatch:   &MiniBatch{Dataset: ds, Score: lucky, Size: 10},
+		OnGeneration: func(p Progress) {
+			generations++
+			if p.BestEver.Score != 5 {
+				t.Errorf("generation %v BestEver score = %v, want full-dataset score 5", p.Generation, p.BestEver.Score)
+			}
+		},
+	})
+	if generations != 3 || best.Score != 5 {
+		t.Errorf("Train with a lucky batch ran %v generations and returned score %v, want 3 and 5", generations, best.Score)
+	}
+}
+
+func TestTrainReplaceDegenerate(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	ds := &genome.Dataset{}
+	for i := 0; i < 10; i++ {
+		ds.Inputs = append(ds.Inputs, []float64{float64(i)})
+		ds.Targets = append(ds.Targets, math.Sin(float64(i*i)))
+	}
+	numConstant := func(pop []*genome.Genome) int {
+		n := 0
+		for _, v := range pop {
+			if v.IsConstant(ds) {
+				n++
+			}
+		}
+		return n
+	}
+	for _, cfg := range []*Degenerate{nil, {Dataset: ds}} {
+		e := New(funcs, mn.Math, 50, 8, 2, 1, 1, "+", genome.RMSE(ds))
+		for _, v := range e.Genomes[5:] {
+			for _, g := range v.Genes {
+				g.Symbols[0] = "c0" // The whole genome is then c0+c0.
+			}
+		}
+		if n := numConstant(e.Genomes); n < 45 {
+			t.Fatalf("seeded population has %v constant genomes, want at least 45", n)
+		}
+		e.Train(TrainConfig{Generations: 1, ReplaceDegenerate: cfg})
+		n := numConstant(e.Genomes)
+		switch {
+		case cfg == nil && n < 25:
+			t.Errorf("Train without ReplaceDegenerate left only %v constant genomes, want most", n)
+		case cfg != nil && n > 20:
+			t.Errorf("Train with ReplaceDegenerate left %v of 45 constant genomes, want at most 20", n)
+		}
+	}
+}
+
+func TestReplaceDegenerateDuplicates(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	e := New(funcs, mn.Math, 20, 8, 2, 1, 0, "+", nil)
+	for i := range e.Genomes {
+		e.Genomes[i] = e.Genomes[0].Dup()
+	}
+	first := e.Genomes[0]
+	if n := e.replaceDegenerate(functions.DefaultRNG, &Degenerate{Duplicates: true}); n != 19 {
+		t.Errorf("replaceDegenerate replaced %v of 20 identical genomes, want 19", n)
+	}
+	if e.Genomes[0] != first {
+		t.Errorf("replaceDegenerate replaced the first of the identical genomes")
+	}
+	for i, v := range e.Genomes[1:] {
+		if err := v.Validate(mn.Math); err != nil {
+			t.Errorf("replacement genome #%v: Validate = %v", i+1, err)
+		}
+	}
+}
+
+func TestReplaceDegenerateFrozen(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	ds := &genome.Dataset{Inputs: [][]float64{{0}, {1}, {2}}, Targets: []float64{0, 1, 4}}
+	e := New(funcs, mn.Math, 3, 8, 2, 1, 1, "+", nil)
+	for _, v := range e.Genomes {
+		for _, g := range v.Genes {
+			g.Symbols[0] = "c0" // The whole genome is then c0+c0.
+		}
+	}
+	frozen := e.Genomes[0]
+	frozen.Frozen = true
+	e.Genomes[1] = frozen.Dup()
+	e.Genomes[1].Frozen = false
+	if n := e.replaceDegenerate(functions.NewRNG(1), &Degenerate{Dataset: ds, Duplicates: true}); n != 2 {
+		t.Errorf("replaceDegenerate replaced %v of 3 constant genomes, want 2", n)
+	}
+	if e.Genomes[0] != frozen {
+		t.Errorf("replaceDegenerate replaced the frozen constant genome")
+	}
+}
+
+// panicLogger is a functions.Logger whose Fatalf panics (rather than exiting)
+// with the message, so that tests may recover from it.
+type panicLogger struct{}
+
+func (panicLogger) Printf(format string, v ...interface{}) {}
+func (panicLogger) Println(v ...interface{})               {}
+func (panicLogger) Fatalf(format string, v ...interface{}) { panic(fmt.Sprintf(format, v...)) }
+
+func TestTrainFixedLink(t *testing.T) {
+	defer func(l functions.Logger) { functions.Log = l }(functions.Log)
+	functions.Log = panicLogger{}
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	ds := &genome.Dataset{}
+	for i := 0; i < 10; i++ {
+		ds.Inputs = append(ds.Inputs, []float64{float64(i)})
+		ds.Targets = append(ds.Targets, math.Sin(float64(i*i)))
+	}
+	train := func(sf genome.ScoringFunc, cfg TrainConfig) (msg interface{}) {
+		defer func() { msg = recover() }()
+		e := New(funcs, mn.Math, 30, 8, 3, 1, 1, "*", sf)
+		e.Train(cfg)
+		for i, v := range e.Genomes {
+			if v.LinkFunc != "*" {
+				t.Errorf("genome #%v LinkFunc = %q, want *", i, v.LinkFunc)
+			}
+		}
+		return nil
+	}
+
+	// Every built-in operator leaves the linking function alone.
+	cfgs := []TrainConfig{
+		{Generations: 20, FixedLink: true},
+		{Generations: 20, FixedLink: true, MutateRate: 0.2, CacheEvaluations: true},
+		{Generations: 20, FixedLink: true, ReplaceDegenerate: &Degenerate{Dataset: ds, Duplicates: true}},
+		{Generations: 20, FixedLink: true, MiniBatch: &MiniBatch{Dataset: ds, Score: genome.RMSE, Size: 5}},
+	}
+	for i, cfg := range cfgs {
+		if msg := train(genome.RMSE(ds), cfg); msg != nil {
+			t.Errorf("%v: Train with FixedLink failed: %v", i, msg)
+		}
+	}
+
+	// A custom operator (here, within the scoring function) that alters the link is caught.
+	relink := func(g *genome.Genome) float64 {
+		g.LinkFunc = "+"
+		return 1
+	}
+	msg := train(relink, TrainConfig{Generations: 2, FixedLink: true})
+	if s, ok := msg.(string); !ok || !strings.Contains(s, "linking function") {
+		t.Errorf("Train with FixedLink and an altered link = %v, want fatal error", msg)
+	}
+}
+
+func TestTrainLinkMutation(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	sf := func(g *genome.Genome) float64 { return 1 }
+	e := New(funcs, mn.Math, 20, 8, 3, 2, 0, "+", sf)
+	e.Train(TrainConfig{Generations: 5, LinkMutateRate: 1, LinkFuncs: mn.Math, DisableElitism: true})
+	valid := map[string]bool{}
+	for _, sym := range genome.LinkFuncs(mn.Math) {
+		valid[sym] = true
+	}
+	changed := 0
+	for i, v := range e.Genomes {
+		if !valid[v.LinkFunc] {
+			t.Errorf("genome #%v has linking function %q, want one of %v", i, v.LinkFunc, genome.LinkFuncs(mn.Math))
+		}
+		if v.LinkFunc != "+" {
+			changed++
+		}
+	}
+	if changed == 0 {
+		t.Errorf("Train with LinkMutateRate left every linking function unchanged")
+	}
+
+	// Misconfigurations are rejected before any generation is run.
+	defer func(l functions.Logger) { functions.Log = l }(functions.Log)
+	functions.Log = panicLogger{}
+	scored := 0
+	count := func(g *genome.Genome) float64 {
+		scored++
+		return 1
+	}
+	for _, cfg := range []TrainConfig{
+		{Generations: 5, LinkMutateRate: 1},
+		{Generations: 5, LinkMutateRate: 1, LinkFuncs: functions.FuncMap{}},
+		{Generations: 5, LinkMutateRate: 1, LinkFuncs: mn.Math, FixedLink: true},
+	} {
+		msg := func() (msg interface{}) {
+			defer func() { msg = recover() }()
+			New(funcs, mn.Math, 20, 8, 3, 2, 0, "+", count).Train(cfg)
+			return nil
+		}()
+		if s, ok := msg.(string); !ok || !strings.Contains(s, "LinkMutateRate") {
+			t.Errorf("Train(%+v) = %v, want fatal error", cfg, msg)
+		}
+	}
+	if scored != 0 {
+		t.Errorf("misconfigured Train scored %v genomes, want 0", scored)
+	}
+}
+
+func TestTrainBestEvents(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	// Each genome scores the number of genomes scored before it, so every
+	// generation improves on the last.
+	const generations = 5
+	var mu sync.Mutex
+	calls := 0
+	sf := func(g *genome.Genome) float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return float64(calls - 1)
+	}
+	e := New(funcs, mn.Math, 10, 8, 2, 1, 0, "+", sf)
+	events := make(chan *genome.Genome, 100)
+	// Every genome scored is an improvement, so each is sent as soon as it is
+	// scored, before the generation is complete.
+	sent := 0
+	onGeneration := func(p Progress) {
+		if want := (p.Generation + 1) * len(e.Genomes); len(events) != want {
+			t.Errorf("generation #%v: %v best genomes sent, want %v", p.Generation, len(events), want)
+		}
+		sent = len(events)
+	}
+	best := e.Train(TrainConfig{Generations: generations, BestEvents: events, OnGeneration: onGeneration})
+	close(events)
+	var got []*genome.Genome
+	for v := range events {
+		got = append(got, v)
+	}
+	if len(got) != calls || sent == 0 {
+		t.Fatalf("Train sent %v best genomes, want %v", len(got), calls)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Score <= got[i-1].Score {
+			t.Errorf("best genome #%v score %v, want more than %v", i, got[i].Score, got[i-1].Score)
+		}
+	}
+	if last := got[len(got)-1]; last.Score != best.Score || last == best {
+		t.Errorf("last best genome = %v (score %v), want a copy of %v (score %v)", last, last.Score, best, best.Score)
+	}
+
+	// An unbuffered channel that is never read must not stall training.
+	done := make(chan bool)
+	go func() {
+		e.Train(TrainConfig{Generations: generations, BestEvents: make(chan *genome.Genome)})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Train with a full BestEvents channel did not finish")
+	}
+}
+
+func TestTrainKeepHistory(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	// Each genome scores the number of genomes scored before it, so the best
+	// genome of generation i scores (i+1)*numGenomes-1.
+	const numGenomes, generations = 10, 5
+	var mu sync.Mutex
+	calls := 0
+	sf := func(g *genome.Genome) float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return float64(calls - 1)
+	}
+	e := New(funcs, mn.Math, numGenomes, 8, 2, 1, 0, "+", sf)
+	e.Train(TrainConfig{Generations: generations})
+	if e.History != nil {
+		t.Errorf("Train without KeepHistory recorded %v genomes", len(e.History))
+	}
+	calls = 0
+	e.Train(TrainConfig{Generations: generations, KeepHistory: true})
+	if len(e.History) != generations {
+		t.Fatalf("Train recorded %v genomes in History, want %v", len(e.History), generations)
+	}
+	for i, v := range e.History {
+		if want := float64((i+1)*numGenomes - 1); v.Score != want {
+			t.Errorf("History[%v].Score = %v, want %v", i, v.Score, want)
+		}
+		for _, p := range e.Genomes {
+			if v == p {
+				t.Errorf("History[%v] is not a copy", i)
+			}
+		}
+	}
+}
+
+func TestTrainRNG(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	ds := &genome.Dataset{}
+	for i := 0; i < 10; i++ {
+		x := float64(i)
+		ds.Inputs = append(ds.Inputs, []float64{x})
+		ds.Targets = append(ds.Targets, x*x-x)
+	}
+	run := func() []string {
+		e := NewWith(functions.NewRNG(7), funcs, mn.Math, 20, 6, 2, 1, 0, "+", genome.RMSE(ds))
+		best := e.Train(TrainConfig{Generations: 20, RNG: functions.NewRNG(8), Restart: &Restart{Stagnation: 3, Elites: 2, MutationRate: 0.3}})
+		r := []string{best.String()}
+		for _, v := range e.Genomes {
+			r = append(r, v.String())
+		}
+		return r
+	}
+	if got, want := run(), run(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Train with identically seeded RNGs differs:\n%v\n%v", got, want)
+	}
+}
+
+func codingLengthStats(g *Generation) (mean, stddev float64) {
+	for _, v := range g.Genomes {
+		mean += float64(v.CodingLength())
+	}
+	mean /= float64(len(g.Genomes))
+	for _, v := range g.Genomes {
+		d := float64(v.CodingLength()) - mean
+		stddev += d * d
+	}
+	return mean, math.Sqrt(stddev / float64(len(g.Genomes)))
+}
+
+func TestNewRamped(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	headSize := 20
+	e := NewRamped(funcs, mn.Math, 100, headSize, 1, 2, 0, "+", nil)
+	for i, v := range e.Genomes {
+		if err := v.Validate(mn.Math); err != nil {
+			t.Fatalf("genome #%v %q: Validate = %v", i, v, err)
+		}
+	}
+	mean, stddev := codingLengthStats(e)
+	if stddev < float64(headSize)/2 {
+		t.Errorf("NewRamped coding length mean=%v stddev=%v, want stddev >= %v", mean, stddev, headSize/2)
+	}
+	min, max := 2*headSize+1, 0
+	for _, v := range e.Genomes {
+		if n := v.CodingLength(); n < min {
+			min = n
+		}
+		if n := v.CodingLength(); n > max {
+			max = n
+		}
+	}
+	if min > 3 || max < headSize {
+		t.Errorf("NewRamped coding lengths range from %v to %v, want from <= 3 to >= %v", min, max, headSize)
+	}
+
+	// The same seed gives the same population.
+	a := NewRampedWith(functions.NewRNG(1), funcs, mn.Math, 10, headSize, 2, 2, 0, "+", nil)
+	b := NewRampedWith(functions.NewRNG(1), funcs, mn.Math, 10, headSize, 2, 2, 0, "+", nil)
+	for i := range a.Genomes {
+		if a.Genomes[i].String() != b.Genomes[i].String() {
+			t.Errorf("NewRampedWith genome #%v = %v, then %v with the same seed", i, a.Genomes[i], b.Genomes[i])
+		}
+	}
+}
+
+func TestTrainFrozen(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	e := NewWith(functions.NewRNG(1), funcs, mn.Math, 30, 8, 2, 1, 0, "+", nil)
+	frozen := e.Genomes[0]
+	frozen.Frozen = true
+	want := frozen.String()
+	// The frozen genome scores far above the others, so it keeps surviving.
+	e.ScoringFunc = func(g *genome.Genome) float64 {
+		if g.String() == want {
+			return 500
+		}
+		return 1
+	}
+	var survivors int
+	e.Train(TrainConfig{
+		Generations: 10,
+		MutateRate:  0.5,
+		RNG:         functions.NewRNG(2),
+		OnGeneration: func(p Progress) {
+			survivors = 0
+			for _, v := range e.Genomes {
+				if v.Frozen {
+					survivors++
+					if got := v.String(); got != want {
+						t.Errorf("generation %v: frozen genome altered to %v, want %v", p.Generation, got, want)
+					}
+				}
+			}
+			if survivors == 0 {
+				t.Errorf("generation %v: no frozen genome survived", p.Generation)
+			}
+		},
+	})
+}
+
+func TestTrainParsimonyTieBreak(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	// Every genome ties on score, so only parsimony distinguishes them.
+	sf := func(g *genome.Genome) float64 { return 1 }
+	meanLength := func(pop []*genome.Genome) float64 {
+		total := 0
+		for _, v := range pop {
+			total += v.CodingLength()
+		}
+		return float64(total) / float64(len(pop))
+	}
+	noop := Operator{Name: "noop", Apply: func(rng functions.RNG, gn *genome.Genome) {}}
+	train := func(parsimony bool) (*genome.Genome, []*genome.Genome, int) {
+		e := NewWith(functions.NewRNG(1), funcs, mn.Math, 30, 8, 2, 1, 0, "+", sf)
+		smallest := e.Genomes[0].CodingLength()
+		for _, v := range e.Genomes {
+			if n := v.CodingLength(); n < smallest {
+				smallest = n
+			}
+		}
+		best := e.Train(TrainConfig{Generations: 3, ParsimonyTieBreak: parsimony, Operators: []Operator{noop}, RNG: functions.NewRNG(2)})
+		return best, e.Genomes, smallest
+	}
+	best, pop, smallest := train(true)
+	if got := best.CodingLength(); got != smallest {
+		t.Errorf("Train best coding length = %v, want %v", got, smallest)
+	}
+	_, plain, _ := train(false)
+	if got, want := meanLength(pop), meanLength(plain); got >= want {
+		t.Errorf("mean coding length with ParsimonyTieBreak = %v, want less than %v without", got, want)
+	}
+	// The tie-break must not collapse the tied population into clones.
+	distinct := map[string]bool{}
+	for _, v := range pop {
+		distinct[v.String()] = true
+	}
+	if len(distinct) < 2 {
+		t.Errorf("ParsimonyTieBreak left %v distinct genomes of %v, want several", len(distinct), len(pop))
+	}
+}
+
 func BenchmarkReplication(b *testing.B) {
 	funcs := []gene.FuncWeight{
 		{"+", 1},
@@ -38,7 +681,7 @@ func BenchmarkReplication(b *testing.B) {
 	}
 	e := New(funcs, mn.Math, 30, 8, 4, 1, 0, "+", nil)
 	for i := 0; i < b.N; i++ {
-		e.replication()
+		e.replication(functions.DefaultRNG)
 	}
 }
 
@@ -50,6 +693,6 @@ func BenchmarkMutation(b *testing.B) {
 	}
 	e := New(funcs, mn.Math, 30, 8, 4, 1, 0, "+", nil)
 	for i := 0; i < b.N; i++ {
-		e.mutation()
+		e.mutation(functions.DefaultRNG)
 	}
 }
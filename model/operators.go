@@ -0,0 +1,56 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"github.com/gmlewis/gep/functions"
+	"github.com/gmlewis/gep/genome"
+)
+
+// Operator is a genetic operator applied by Train to each genome of the
+// population (after replication), in place of the usual mutation.
+type Operator struct {
+	// Name identifies the operator in its OperatorStats.
+	Name string
+	// Apply alters gn in place, drawing its randomness from rng.
+	Apply func(rng functions.RNG, gn *genome.Genome)
+}
+
+// OperatorStats reports the effectiveness of an Operator during training.
+type OperatorStats struct {
+	// Name is the name of the operator.
+	Name string
+	// Attempts is the number of children produced by the operator.
+	Attempts int
+	// Improvements is the number of children that scored higher than their
+	// parent (the genome to which the operator was applied).
+	Improvements int
+}
+
+// ImprovementRate returns the fraction of the attempts of the operator that
+// improved upon the parent, or 0 if it has made no attempts.
+func (s OperatorStats) ImprovementRate() float64 {
+	if s.Attempts == 0 {
+		return 0
+	}
+	return float64(s.Improvements) / float64(s.Attempts)
+}
+
+// applyOperators applies each of ops in turn to each genome of g, scoring
+// every child with sf and recording in stats (which parallels ops) whether
+// it improved upon its parent. The genomes must already be scored.
+func (g *Generation) applyOperators(rng functions.RNG, ops []Operator, sf genome.ScoringFunc, stats []OperatorStats) {
+	for i, op := range ops {
+		for _, gn := range g.Genomes {
+			parent := gn.Score
+			op.Apply(rng, gn)
+			gn.Score = sf(gn)
+			stats[i].Attempts++
+			if gn.Score > parent {
+				stats[i].Improvements++
+			}
+		}
+	}
+}
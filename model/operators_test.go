@@ -0,0 +1,56 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/gmlewis/gep/functions"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+	"github.com/gmlewis/gep/genome"
+)
+
+func TestTrainOperators(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	// Each genome scores its number of genes, so adding a gene always
+	// improves upon the parent.
+	sf := func(g *genome.Genome) float64 { return float64(len(g.Genes)) }
+	ops := []Operator{
+		{Name: "grow", Apply: func(rng functions.RNG, gn *genome.Genome) { gn.AddGeneWith(rng, mn.Math) }},
+		{Name: "noop", Apply: func(rng functions.RNG, gn *genome.Genome) {}},
+	}
+	const numGenomes, generations = 10, 5
+	e := New(funcs, mn.Math, numGenomes, 4, 1, 1, 0, "+", sf)
+	var last Progress
+	e.Train(TrainConfig{
+		Generations:  generations,
+		Operators:    ops,
+		RNG:          functions.NewRNG(1),
+		OnGeneration: func(p Progress) { last = p },
+	})
+	if len(last.Operators) != len(ops) {
+		t.Fatalf("Progress.Operators = %v, want %v entries", last.Operators, len(ops))
+	}
+	const attempts = numGenomes * (generations - 1)
+	for i, want := range []OperatorStats{
+		{Name: "grow", Attempts: attempts, Improvements: attempts},
+		{Name: "noop", Attempts: attempts},
+	} {
+		if got := last.Operators[i]; got != want {
+			t.Errorf("Progress.Operators[%v] = %+v, want %+v", i, got, want)
+		}
+	}
+	if got := last.Operators[0].ImprovementRate(); got != 1 {
+		t.Errorf("grow ImprovementRate = %v, want 1", got)
+	}
+	if got := last.Operators[1].ImprovementRate(); got != 0 {
+		t.Errorf("noop ImprovementRate = %v, want 0", got)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"github.com/gmlewis/gep/functions"
+	"github.com/gmlewis/gep/genome"
+)
+
+// Restart configures the warm restarts of Train: whenever the best genome
+// ever seen has not improved for Stagnation generations, the population is
+// perturbed by WarmRestart in place of the usual reproduction.
+type Restart struct {
+	// Stagnation is the number of generations without improvement that
+	// triggers a warm restart. It must be positive.
+	Stagnation int
+	// Elites is the number of top-ranked genomes preserved unchanged.
+	Elites int
+	// MutationRate is the probability that each symbol of each other genome
+	// is mutated.
+	MutationRate float64
+}
+
+// WarmRestart perturbs the scored population pop to help it escape a local
+// optimum without losing all progress (as a random restart would): the top
+// eliteCount genomes (ranked by genome.Better) are left unchanged, and each of
+// the others is heavily mutated, with each of its symbols mutated with
+// probability mutationRate (but at least once). The genomes are not rescored.
+func WarmRestart(pop []*genome.Genome, eliteCount int, mutationRate float64) {
+	WarmRestartWith(functions.DefaultRNG, pop, eliteCount, mutationRate)
+}
+
+// WarmRestartWith is like WarmRestart, but draws its randomness from rng.
+func WarmRestartWith(rng functions.RNG, pop []*genome.Genome, eliteCount int, mutationRate float64) {
+	for n, i := range genome.Ranking(pop) {
+		if n < eliteCount {
+			continue
+		}
+		if numMutations := mutations(rng, pop[i], mutationRate); numMutations > 0 {
+			pop[i].MutateWith(rng, numMutations)
+		} else {
+			pop[i].MutateWith(rng, 1)
+		}
+	}
+}
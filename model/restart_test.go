@@ -0,0 +1,84 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+	"github.com/gmlewis/gep/genome"
+)
+
+func TestWarmRestart(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	const numGenomes, eliteCount = 20, 3
+	e := New(funcs, mn.Math, numGenomes, 10, 2, 4, 0, "+", nil)
+	before := make([][]string, numGenomes)
+	for i, v := range e.Genomes {
+		v.Score = float64((i * 7) % numGenomes) // The elites are #11, #14, and #17.
+		before[i] = v.LinearSymbols()
+	}
+	WarmRestart(e.Genomes, eliteCount, 0.8)
+	for i, v := range e.Genomes {
+		after := v.LinearSymbols()
+		changed := 0
+		for j := range after {
+			if after[j] != before[i][j] {
+				changed++
+			}
+		}
+		switch elite := v.Score >= numGenomes-eliteCount; {
+		case elite && changed > 0:
+			t.Errorf("WarmRestart altered elite genome #%v (score %v) at %v symbols", i, v.Score, changed)
+		case !elite && changed < len(after)/5:
+			t.Errorf("WarmRestart altered genome #%v (score %v) at %v of %v symbols, want at least %v", i, v.Score, changed, len(after), len(after)/5)
+		}
+	}
+}
+
+func TestTrainRestart(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	const numGenomes = 10
+	sf := func(g *genome.Genome) float64 { return 1 }
+	run := func(r *Restart) (before, after []string) {
+		e := New(funcs, mn.Math, numGenomes, 8, 2, 2, 0, "+", sf)
+		// An unbeatable BestEver makes training stagnate from the start.
+		e.BestEver = e.Genomes[0].Dup()
+		e.BestEver.Score = 2
+		for _, v := range e.Genomes {
+			before = append(before, v.String())
+		}
+		e.Train(TrainConfig{Generations: 3, Restart: r})
+		for _, v := range e.Genomes {
+			after = append(after, v.String())
+		}
+		return before, after
+	}
+
+	// Preserving every genome as an elite leaves the population unchanged.
+	before, after := run(&Restart{Stagnation: 1, Elites: numGenomes, MutationRate: 1})
+	for i := range before {
+		if after[i] != before[i] {
+			t.Errorf("Train with all elites altered genome #%v: %q, want %q", i, after[i], before[i])
+		}
+	}
+
+	// Without elites, every genome but the best (restored by elitism) is altered.
+	before, after = run(&Restart{Stagnation: 1, MutationRate: 1})
+	for i := 1; i < numGenomes; i++ {
+		if after[i] == before[i] {
+			t.Errorf("Train with warm restarts left genome #%v unchanged: %q", i, after[i])
+		}
+	}
+}
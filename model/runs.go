@@ -0,0 +1,127 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"sync"
+
+	"github.com/gmlewis/gep/functions"
+	"github.com/gmlewis/gep/genome"
+)
+
+// RunManyConfig configures the repeated runs of RunMany.
+type RunManyConfig struct {
+	// Template is the population whose shape is copied by each run.
+	// It is required.
+	Template *Generation
+	// Train configures the training of each run.
+	Train TrainConfig
+	// Parallelism is the maximum number of runs trained concurrently; if it
+	// is more than 1, Train.OnGeneration (if any) and the success criterion
+	// must be safe for concurrent use. If zero, the runs are sequential.
+	Parallelism int
+}
+
+// RunManyReport summarizes the results of the repeated runs of RunMany.
+type RunManyReport struct {
+	// Runs is the number of runs, one per seed.
+	Runs int
+	// Successes is the number of runs whose best genome satisfied the
+	// success criterion, and SuccessRate is their fraction of the runs.
+	Successes   int
+	SuccessRate float64
+	// MeanGenerations is the mean, over the successful runs, of the number
+	// of generations run before a solution was found, or NaN if no run
+	// succeeded.
+	MeanGenerations float64
+	// Generations holds the number of generations run before each run (in
+	// the order of the seeds) found a solution, or -1 if it did not.
+	Generations []int
+	// BestScores holds the score of the best genome of each run, in the
+	// order of the seeds.
+	BestScores []float64
+}
+
+// RunMany runs Train as configured by cfg.Train once per seed, each time from
+// a new random population shaped like cfg.Template (see gene.RandomLike) and
+// with a functions.NewRNG(seed) source of randomness in place of cfg.Train.RNG,
+// and reports how many runs found a solution, as judged by success of the best
+// genome ever seen. Up to cfg.Parallelism runs are trained concurrently.
+// cfg.Template is left unaltered; it must be non-nil. Given deterministic
+// scoring, the report is reproducible from the seeds, whatever the parallelism.
+func RunMany(cfg RunManyConfig, seeds []int64, success func(*genome.Genome) bool) RunManyReport {
+	if cfg.Template == nil {
+		functions.Log.Fatalf("model.RunMany: RunManyConfig.Template must be non-nil")
+	}
+	workers := cfg.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+	r := RunManyReport{
+		Runs:            len(seeds),
+		MeanGenerations: math.NaN(),
+		Generations:     make([]int, len(seeds)),
+		BestScores:      make([]float64, len(seeds)),
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for i, seed := range seeds {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, seed int64) {
+			defer func() { <-sem; wg.Done() }()
+			r.Generations[i], r.BestScores[i] = run(cfg.Template, cfg.Train, seed, success)
+		}(i, seed)
+	}
+	wg.Wait()
+	total := 0
+	for _, n := range r.Generations {
+		if n >= 0 {
+			r.Successes++
+			total += n
+		}
+	}
+	if r.Runs > 0 {
+		r.SuccessRate = float64(r.Successes) / float64(r.Runs)
+	}
+	if r.Successes > 0 {
+		r.MeanGenerations = float64(total) / float64(r.Successes)
+	}
+	return r
+}
+
+// run trains a new random population shaped like g with the given seed, and
+// returns the number of generations run before success was first satisfied
+// (or -1 if it never was) and the score of the best genome.
+func run(g *Generation, cfg TrainConfig, seed int64, success func(*genome.Genome) bool) (int, float64) {
+	rng := functions.NewRNG(seed)
+	e := &Generation{
+		Genomes:     make([]*genome.Genome, len(g.Genomes)),
+		Funcs:       g.Funcs,
+		ScoringFunc: g.ScoringFunc,
+	}
+	for i, v := range g.Genomes {
+		if e.Genomes[i] = randomLike(rng, v); e.Genomes[i] == nil {
+			e.Genomes[i] = v.Dup()
+		}
+	}
+	found := -1
+	onGeneration := cfg.OnGeneration
+	cfg.RNG = rng
+	cfg.OnGeneration = func(p Progress) {
+		if found < 0 && success(p.BestEver) {
+			found = p.Generation
+		}
+		if onGeneration != nil {
+			onGeneration(p)
+		}
+	}
+	best := e.Train(cfg)
+	if found < 0 && success(best) {
+		found = cfg.Generations
+	}
+	return found, best.Score
+}
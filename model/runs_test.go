@@ -0,0 +1,121 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/gmlewis/gep/functions"
+	mn "github.com/gmlewis/gep/functions/math_nodes"
+	"github.com/gmlewis/gep/gene"
+	"github.com/gmlewis/gep/genome"
+)
+
+func TestRunMany(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"+", 1},
+		{"-", 1},
+		{"*", 1},
+	}
+	// The trivial target is the input itself.
+	sf := func(g *genome.Genome) float64 {
+		err := 0.0
+		for x := 1.0; x <= 4; x++ {
+			err += math.Abs(g.EvalMath([]float64{x}) - x)
+		}
+		return 1000 / (1 + err)
+	}
+	solved := func(g *genome.Genome) bool { return g.Score >= 1000 }
+	e := New(funcs, mn.Math, 30, 4, 1, 1, 0, "+", sf)
+	before := e.Genomes[0].String()
+	cfg := RunManyConfig{Template: e, Train: TrainConfig{Generations: 50}}
+	seeds := []int64{1, 2, 3, 4, 5}
+
+	got := RunMany(cfg, seeds, solved)
+	if got.Runs != len(seeds) || got.Successes != len(seeds) || got.SuccessRate != 1 {
+		t.Errorf("RunMany = %+v, want all %v runs to succeed", got, len(seeds))
+	}
+	if math.IsNaN(got.MeanGenerations) || got.MeanGenerations > float64(cfg.Train.Generations) {
+		t.Errorf("RunMany MeanGenerations = %v, want at most %v", got.MeanGenerations, cfg.Train.Generations)
+	}
+	for i, score := range got.BestScores {
+		if score < 1000 {
+			t.Errorf("RunMany BestScores[%v] = %v, want 1000", i, score)
+		}
+	}
+	if e.Genomes[0].String() != before {
+		t.Errorf("RunMany altered the template population")
+	}
+	cfg.Parallelism = 3
+	if parallel := RunMany(cfg, seeds, solved); !reflect.DeepEqual(parallel, got) {
+		t.Errorf("RunMany with Parallelism = %+v, want %+v", parallel, got)
+	}
+
+	never := RunMany(RunManyConfig{Template: e, Train: TrainConfig{Generations: 2}}, seeds[:2], func(*genome.Genome) bool { return false })
+	if never.Successes != 0 || never.SuccessRate != 0 || !math.IsNaN(never.MeanGenerations) {
+		t.Errorf("RunMany with no successes = %+v, want rate 0 and NaN mean", never)
+	}
+	if want := []int{-1, -1}; !reflect.DeepEqual(never.Generations, want) {
+		t.Errorf("RunMany Generations = %v, want %v", never.Generations, want)
+	}
+
+	defer func(l functions.Logger) { functions.Log = l }(functions.Log)
+	functions.Log = panicLogger{}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("RunMany without a Template did not stop with a fatal error")
+			}
+		}()
+		RunMany(RunManyConfig{Train: TrainConfig{Generations: 2}}, seeds, solved)
+	}()
+}
+
+// nanPlus is a "+" that always returns NaN, to detect which function map a
+// genome is bound to.
+type nanPlus struct{}
+
+func (nanPlus) Symbol() string                             { return "+" }
+func (nanPlus) Terminals() int                             { return 2 }
+func (nanPlus) BoolFunction(a, b, c, d bool) bool          { return false }
+func (nanPlus) Float64Function(a, b, c, d float64) float64 { return math.NaN() }
+
+func TestRandomLike(t *testing.T) {
+	funcs := []gene.FuncWeight{
+		{"-", 1},
+		{"*", 1},
+	}
+	rng := functions.NewRNG(1)
+	genes := []*gene.Gene{gene.RandomNewWith(rng, 3, 4, 1, 0, funcs, nil), gene.RandomNewWith(rng, 3, 4, 1, 0, funcs, nil)}
+
+	// The template is bound to a function map whose "+" linking function is always NaN.
+	fm := functions.FuncMap{}
+	for k, v := range mn.Math {
+		fm[k] = v
+	}
+	fm["+"] = nanPlus{}
+	bound, err := genome.New(genes, "+").Rebind(fm)
+	if err != nil {
+		t.Fatalf("Rebind = %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if got := randomLike(rng, bound).EvalMath([]float64{2}); !math.IsNaN(got) {
+			t.Errorf("randomLike of a rebound genome EvalMath = %v, want NaN from its function map", got)
+		}
+	}
+
+	homeotic := genome.NewHomeotic(genes, gene.NewWithHead("*.d0.d1", 1, mn.Math))
+	for i := 0; i < 10; i++ {
+		r := randomLike(rng, homeotic)
+		if r.Homeotic == nil {
+			t.Fatalf("randomLike of a homeotic genome = %v, want a homeotic genome", r)
+		}
+		if err := r.Validate(mn.Math); err != nil {
+			t.Errorf("randomLike of a homeotic genome = %v, invalid: %v", r, err)
+		}
+	}
+}
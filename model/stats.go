@@ -0,0 +1,51 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+
+	"github.com/gmlewis/gep/genome"
+)
+
+// NodeHistogram tallies the number of times each symbol appears within the
+// expressed (coding) regions of all the genomes in pop. Symbols in the
+// non-coding regions and the linking functions are not counted.
+// Recording the histogram each generation shows how the mix of functions
+// favored by the population evolves over time.
+func NodeHistogram(pop []*genome.Genome) map[string]int {
+	r := map[string]int{}
+	for _, v := range pop {
+		for sym, n := range v.CodingSymbolMap() {
+			r[sym] += n
+		}
+	}
+	return r
+}
+
+// NormalizeScores returns the scores of the genomes in pop, min-max normalized
+// to the range [0,1], without altering the genomes. If all the scores are
+// equal, every normalized score is 0.5. NaN scores are ignored when finding
+// the range and normalize to 0.
+func NormalizeScores(pop []*genome.Genome) []float64 {
+	r := make([]float64, len(pop))
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range pop {
+		if math.IsNaN(v.Score) {
+			continue
+		}
+		lo, hi = math.Min(lo, v.Score), math.Max(hi, v.Score)
+	}
+	for i, v := range pop {
+		switch {
+		case math.IsNaN(v.Score):
+		case hi <= lo || math.IsInf(hi-lo, 0):
+			r[i] = 0.5
+		default:
+			r[i] = (v.Score - lo) / (hi - lo)
+		}
+	}
+	return r
+}
@@ -0,0 +1,75 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/gmlewis/gep/gene"
+	"github.com/gmlewis/gep/genome"
+)
+
+func TestNodeHistogram(t *testing.T) {
+	pop := []*genome.Genome{
+		genome.New([]*gene.Gene{gene.New("+.d0.d1.*.d2"), gene.New("*.d0.d0.d1")}, "+"),
+		genome.New([]*gene.Gene{gene.New("-.+.d1.d0.d0.Ln")}, "*"),
+	}
+	want := map[string]int{
+		"+":  2,
+		"*":  1,
+		"-":  1,
+		"d0": 5,
+		"d1": 2,
+	}
+	got := NodeHistogram(pop)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NodeHistogram = %v, want %v", got, want)
+	}
+	total := 0
+	for _, n := range got {
+		total += n
+	}
+	if want := pop[0].CodingLength() + pop[1].CodingLength(); total != want {
+		t.Errorf("NodeHistogram sums to %v, want total coding length %v", total, want)
+	}
+	if got := NodeHistogram(nil); len(got) != 0 {
+		t.Errorf("NodeHistogram(nil) = %v, want empty", got)
+	}
+}
+
+func TestNormalizeScores(t *testing.T) {
+	newPop := func(scores ...float64) []*genome.Genome {
+		var r []*genome.Genome
+		for _, s := range scores {
+			r = append(r, &genome.Genome{Score: s})
+		}
+		return r
+	}
+	tests := []struct {
+		name   string
+		scores []float64
+		want   []float64
+	}{
+		{name: "known range", scores: []float64{200, 450, 1000, 600}, want: []float64{0, 0.3125, 1, 0.5}},
+		{name: "all equal", scores: []float64{7, 7, 7}, want: []float64{0.5, 0.5, 0.5}},
+		{name: "single", scores: []float64{42}, want: []float64{0.5}},
+		{name: "NaN", scores: []float64{math.NaN(), 10, 20}, want: []float64{0, 0, 1}},
+		{name: "empty", scores: nil, want: []float64{}},
+	}
+	for _, test := range tests {
+		pop := newPop(test.scores...)
+		got := NormalizeScores(pop)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: NormalizeScores(%v) = %v, want %v", test.name, test.scores, got, test.want)
+		}
+		for i, v := range pop {
+			if s := test.scores[i]; v.Score != s && !math.IsNaN(s) {
+				t.Errorf("%v: NormalizeScores altered score #%v: %v, want %v", test.name, i, v.Score, s)
+			}
+		}
+	}
+}
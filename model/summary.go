@@ -0,0 +1,98 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gmlewis/gep/genome"
+)
+
+// summaryBins is the number of bins of the score histogram of Summarize.
+const summaryBins = 10
+
+// summaryBarWidth is the length of the bar of the fullest histogram bin.
+const summaryBarWidth = 40
+
+// Summarize returns a human-readable report of the results of a run: the
+// infix expression (see genome.Infix), score, size (coding length), and used
+// inputs of best, followed by a histogram of the scores of the genomes of the
+// final population pop. If best is nil, the best genome of pop (as ranked by
+// genome.Better) is reported. The genomes must already be scored.
+// Like SymbolCount, this currently only works for Math expressions.
+func Summarize(pop []*genome.Genome, best *genome.Genome) string {
+	if best == nil {
+		best = genome.Best(pop)
+	}
+	var b strings.Builder
+	if best != nil {
+		fmt.Fprintf(&b, "Best genome: %v\n", best.Infix())
+		fmt.Fprintf(&b, "Score: %v\n", best.Score)
+		fmt.Fprintf(&b, "Size: %v\n", best.CodingLength())
+		inputs := "none"
+		if used := best.UsedInputs(); len(used) > 0 {
+			names := make([]string, len(used))
+			for i, v := range used {
+				names[i] = fmt.Sprintf("d%v", v)
+			}
+			inputs = strings.Join(names, ", ")
+		}
+		fmt.Fprintf(&b, "Inputs used: %v\n", inputs)
+	}
+	fmt.Fprintf(&b, "Score distribution (%v genomes):\n", len(pop))
+	writeHistogram(&b, pop)
+	return b.String()
+}
+
+// writeHistogram writes to b a histogram of the finite scores of pop in
+// summaryBins equal bins spanning their range, followed by the number of
+// non-finite scores (if any).
+func writeHistogram(b *strings.Builder, pop []*genome.Genome) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	nonFinite := 0
+	for _, v := range pop {
+		if math.IsNaN(v.Score) || math.IsInf(v.Score, 0) {
+			nonFinite++
+			continue
+		}
+		lo, hi = math.Min(lo, v.Score), math.Max(hi, v.Score)
+	}
+	if nonFinite < len(pop) {
+		bins := summaryBins
+		if hi <= lo {
+			bins = 1
+		}
+		counts := make([]int, bins)
+		for _, v := range pop {
+			if math.IsNaN(v.Score) || math.IsInf(v.Score, 0) {
+				continue
+			}
+			i := 0
+			if bins > 1 {
+				i = int(float64(bins) * (v.Score - lo) / (hi - lo))
+			}
+			if i >= bins { // The maximum score falls in the last bin.
+				i = bins - 1
+			}
+			counts[i]++
+		}
+		most := 0
+		for _, n := range counts {
+			if n > most {
+				most = n
+			}
+		}
+		width := (hi - lo) / float64(bins)
+		for i, n := range counts {
+			bar := strings.Repeat("#", (n*summaryBarWidth+most-1)/most)
+			fmt.Fprintf(b, "  [%10.4g, %10.4g] %6v %v\n", lo+float64(i)*width, lo+float64(i+1)*width, n, bar)
+		}
+	}
+	if nonFinite > 0 {
+		fmt.Fprintf(b, "  non-finite: %v\n", nonFinite)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2014 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/gmlewis/gep/gene"
+	"github.com/gmlewis/gep/genome"
+)
+
+func TestSummarize(t *testing.T) {
+	best := genome.New([]*gene.Gene{gene.New("*.d0.d2.d0.d0")}, "+")
+	best.Score = 987.5
+	pop := []*genome.Genome{best}
+	for i := 0; i < 9; i++ {
+		gn := genome.New([]*gene.Gene{gene.New("d1")}, "+")
+		gn.Score = float64(100 * i)
+		pop = append(pop, gn)
+	}
+	nan := genome.New([]*gene.Gene{gene.New("d1")}, "+")
+	nan.Score = math.NaN()
+	pop = append(pop, nan)
+
+	for _, b := range []*genome.Genome{best, nil} {
+		got := Summarize(pop, b)
+		for _, want := range []string{
+			"Best genome: (d0 * d2)\n",
+			"Score: 987.5\n",
+			"Size: 3\n",
+			"Inputs used: d0, d2\n",
+			"Score distribution (11 genomes):\n",
+			"non-finite: 1\n",
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("Summarize(pop, %v) = %q, want it to contain %q", b, got, want)
+			}
+		}
+		// Each of the 10 bins holds one finite score, so each has a full bar.
+		if got, want := strings.Count(got, "#"), 10*summaryBarWidth; got != want {
+			t.Errorf("Summarize histogram has %v bar symbols, want %v", got, want)
+		}
+	}
+}